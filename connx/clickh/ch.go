@@ -3,27 +3,164 @@ package clickh
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// 驱动需要应用自己引入，比如 _ "github.com/ClickHouse/clickhouse-go/v2"
 type (
 	ConnConfig struct {
-		ConnStr string `cnf:",NA"`
+		ConnStr    string `cnf:",NA"`
+		DriverName string `cnf:",def=clickhouse"`
+		MaxExecS   int    `cnf:",NA"` // 查询的 max_execution_time，0代表不限制
+		ReadOnly   bool   `cnf:",NA"` // 查询时带上 readonly=1
+		MaxOpen    int    `cnf:",NA"`
+		MaxIdle    int    `cnf:",NA"`
 	}
 	ClickHouseX struct {
-		Cli *sql.DB
-		Ctx context.Context
+		Cli      *sql.DB
+		Ctx      context.Context
+		settings map[string]any // 查询时追加的 SETTINGS
+		setStr   string
 	}
 )
 
-func NewClickH(cf *ConnConfig) *ClickHouseX {
-	chX := ClickHouseX{Ctx: context.Background()}
+const defDriverName = "clickhouse"
 
-	conn, err := sql.Open("clickhouse", cf.ConnStr)
+func NewClickH(cf *ConnConfig) *ClickHouseX {
+	chX, err := NewClickHE(cf)
 	if err != nil {
 		log.Fatalf("Conn %s err: %s", cf.ConnStr, err)
 	}
+	return chX
+}
+
+// 连接或者 Ping 失败时返回错误，由调用方决定如何处理
+func NewClickHE(cf *ConnConfig) (*ClickHouseX, error) {
+	driverName := cf.DriverName
+	if driverName == "" {
+		driverName = defDriverName
+	}
+	// 没有引入驱动时 sql.Open 只会报 unknown driver，这里提示需要引入的包
+	if !driverRegistered(driverName) {
+		return nil, fmt.Errorf("clickh: sql driver %q not registered, add import _ \"github.com/ClickHouse/clickhouse-go/v2\"", driverName)
+	}
+	conn, err := sql.Open(driverName, cf.ConnStr)
+	if err != nil {
+		return nil, err
+	}
+	if cf.MaxOpen > 0 {
+		conn.SetMaxOpenConns(cf.MaxOpen)
+	}
+	if cf.MaxIdle > 0 {
+		conn.SetMaxIdleConns(cf.MaxIdle)
+	}
 
-	chX.Cli = conn
-	return &chX
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err = conn.PingContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	chX := &ClickHouseX{Cli: conn, Ctx: context.Background()}
+	kvs := make(map[string]any, 2)
+	if cf.MaxExecS > 0 {
+		kvs["max_execution_time"] = cf.MaxExecS
+	}
+	if cf.ReadOnly {
+		kvs["readonly"] = 1
+	}
+	chX.setSettings(kvs)
+	return chX, nil
+}
+
+func driverRegistered(name string) bool {
+	for _, d := range sql.Drivers() {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (chX *ClickHouseX) Close() error {
+	return chX.Cli.Close()
+}
+
+// 返回追加了查询设置的新对象，比如 chX.WithSettings(map[string]any{"max_threads": 4})
+func (chX *ClickHouseX) WithSettings(kvs map[string]any) *ClickHouseX {
+	newX := *chX
+	merged := make(map[string]any, len(chX.settings)+len(kvs))
+	for k, v := range chX.settings {
+		merged[k] = v
+	}
+	for k, v := range kvs {
+		merged[k] = v
+	}
+	newX.setSettings(merged)
+	return &newX
+}
+
+func (chX *ClickHouseX) CloneWithCtx(ctx context.Context) *ClickHouseX {
+	newX := *chX
+	newX.Ctx = ctx
+	return &newX
+}
+
+func (chX *ClickHouseX) setSettings(kvs map[string]any) {
+	chX.settings = kvs
+	if len(kvs) == 0 {
+		chX.setStr = ""
+		return
+	}
+
+	keys := make([]string, 0, len(kvs))
+	for k := range kvs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	items := make([]string, len(keys))
+	for i, k := range keys {
+		items[i] = k + "=" + settingValue(kvs[k])
+	}
+	chX.setStr = " SETTINGS " + strings.Join(items, ",")
+}
+
+func settingValue(v any) string {
+	switch val := v.(type) {
+	case string:
+		return "'" + strings.NewReplacer("\\", "\\\\", "'", "\\'").Replace(val) + "'"
+	case bool:
+		if val {
+			return "1"
+		}
+		return "0"
+	case int:
+		return strconv.Itoa(val)
+	case time.Duration:
+		return strconv.FormatInt(int64(val/time.Second), 10)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// 结尾的 FORMAT 子句，SETTINGS 必须放在它前面
+var sqlFormatTail = regexp.MustCompile(`(?i)\sFORMAT\s+\w+$`)
+
+// 查询语句后面追加 SETTINGS，语句中已经有 SETTINGS 的不处理
+func (chX *ClickHouseX) withSettings(sqlStr string) string {
+	if chX.setStr == "" || strings.Contains(strings.ToUpper(sqlStr), " SETTINGS ") {
+		return sqlStr
+	}
+	sqlStr = strings.TrimRight(sqlStr, "; \t\n")
+	if loc := sqlFormatTail.FindStringIndex(sqlStr); loc != nil {
+		return sqlStr[:loc[0]] + chX.setStr + sqlStr[loc[0]:]
+	}
+	return sqlStr + chX.setStr
 }
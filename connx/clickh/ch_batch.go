@@ -0,0 +1,164 @@
+package clickh
+
+import (
+	"fmt"
+	"github.com/qinchende/gofast/logx"
+	"github.com/qinchende/gofast/skill/exec"
+	"github.com/qinchende/gofast/store/orm"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// 异步批量写入器，行数、字节数、时间间隔任意一个达到就写一批
+// 写入在后台协程执行，出错时打日志并回调 OnError，不会 panic；程序退出时会把剩余的记录写完
+type (
+	BatchOption func(opts *batchOptions)
+
+	batchOptions struct {
+		maxRows  int
+		maxBytes int
+		interval time.Duration
+		onError  func(err error, rows [][]any)
+	}
+
+	BatchWriter struct {
+		chX       *ClickHouseX
+		sm        *orm.ModelSchema
+		insertSql string
+		executor  *exec.Chunk
+		onError   func(err error, rows [][]any)
+	}
+)
+
+const (
+	defBatchRows     = 10000
+	defBatchBytes    = 4 * 1024 * 1024 // 4M
+	defBatchInterval = time.Second
+)
+
+// model 是结构体指针，表名和列名来自 ModelSchema
+func (chX *ClickHouseX) NewBatchWriter(model any, opts ...BatchOption) *BatchWriter {
+	options := batchOptions{maxRows: defBatchRows, maxBytes: defBatchBytes, interval: defBatchInterval}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	sm := orm.Schema(model)
+	cls := sm.Columns()
+	bw := &BatchWriter{
+		chX: chX,
+		sm:  sm,
+		insertSql: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", sm.TableName(), strings.Join(cls, ","),
+			strings.TrimSuffix(strings.Repeat("?,", len(cls)), ",")),
+		onError: options.onError,
+	}
+	bw.executor = exec.NewChunk(bw.insertRows, exec.WithChunkBytes(options.maxBytes),
+		exec.WithChunkTasks(options.maxRows), exec.WithFlushInterval(options.interval))
+	return bw
+}
+
+func WithBatchRows(rows int) BatchOption {
+	return func(opts *batchOptions) {
+		opts.maxRows = rows
+	}
+}
+
+func WithBatchBytes(size int) BatchOption {
+	return func(opts *batchOptions) {
+		opts.maxBytes = size
+	}
+}
+
+func WithBatchInterval(dur time.Duration) BatchOption {
+	return func(opts *batchOptions) {
+		opts.interval = dur
+	}
+}
+
+// 写入失败的回调，rows 是这一批记录的列值
+func WithBatchError(fn func(err error, rows [][]any)) BatchOption {
+	return func(opts *batchOptions) {
+		opts.onError = fn
+	}
+}
+
+// 加入一条记录，obj 必须和创建时的 model 是同一类型，加入时就取值，之后修改 obj 不影响写入
+func (bw *BatchWriter) Write(obj any) {
+	sm, values := orm.SchemaValues(obj)
+	if sm != bw.sm {
+		panic(fmt.Errorf("clickh: batch writer of %s can't write %T", bw.sm.TableName(), obj))
+	}
+	_ = bw.executor.Add(values, rowSize(values))
+}
+
+// 立即写入当前缓存的记录
+func (bw *BatchWriter) Flush() {
+	bw.executor.Flush()
+}
+
+// 写入剩余的记录并等待所有写入完成
+func (bw *BatchWriter) Close() {
+	bw.executor.Wait()
+}
+
+// 一批记录放在一个事务中用同一个预编译语句写入，ClickHouse 驱动会合并成一次批量插入
+func (bw *BatchWriter) insertRows(tasks []any) {
+	rows := make([][]any, len(tasks))
+	for i := range tasks {
+		rows[i] = tasks[i].([]any)
+	}
+	if err := bw.execRows(rows); err != nil {
+		logx.ErrorF("[ClickHouse] batch insert %s %d rows err: %s", bw.sm.TableName(), len(rows), err)
+		if bw.onError != nil {
+			bw.onError(err, rows)
+		}
+	}
+}
+
+func (bw *BatchWriter) execRows(rows [][]any) (err error) {
+	ctx := bw.chX.Ctx
+	tx, err := bw.chX.Cli.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, bw.insertSql)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, values := range rows {
+		if _, err = stmt.ExecContext(ctx, values...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 估算一行数据的字节数，字符串和字节数组按实际长度，其它按8字节
+func rowSize(values []any) int {
+	size := 0
+	for _, v := range values {
+		switch val := v.(type) {
+		case string:
+			size += len(val)
+		case []byte:
+			size += len(val)
+		default:
+			if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+				size += rv.Len() * 8
+			} else {
+				size += 8
+			}
+		}
+	}
+	return size
+}
@@ -0,0 +1,79 @@
+package clickh
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRowSize(t *testing.T) {
+	cases := []struct {
+		values []any
+		size   int
+	}{
+		{nil, 0},
+		{[]any{"abc", []byte("de")}, 5},
+		{[]any{int64(1), 1.5, true, time.Now()}, 32},
+		{[]any{[]int32{1, 2, 3}, []string{"a"}}, 32},
+		{[]any{"", nil}, 8},
+	}
+	for _, c := range cases {
+		if got := rowSize(c.values); got != c.size {
+			t.Errorf("rowSize(%v) = %d, want %d", c.values, got, c.size)
+		}
+	}
+}
+
+func TestSettingValue(t *testing.T) {
+	cases := []struct {
+		val  any
+		want string
+	}{
+		{"abc", "'abc'"},
+		{`it's a\b`, `'it\'s a\\b'`},
+		{true, "1"},
+		{false, "0"},
+		{30, "30"},
+		{90 * time.Second, "90"},
+		{int64(5), "5"},
+		{1.5, "1.5"},
+	}
+	for _, c := range cases {
+		if got := settingValue(c.val); got != c.want {
+			t.Errorf("settingValue(%v) = %s, want %s", c.val, got, c.want)
+		}
+	}
+}
+
+func TestWithSettings(t *testing.T) {
+	chX := &ClickHouseX{}
+	chX.setSettings(map[string]any{"readonly": 1, "max_execution_time": 30})
+	cases := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT 1", "SELECT 1 SETTINGS max_execution_time=30,readonly=1"},
+		{"SELECT 1;\n", "SELECT 1 SETTINGS max_execution_time=30,readonly=1"},
+		{"SELECT * FROM t FORMAT JSONEachRow;", "SELECT * FROM t SETTINGS max_execution_time=30,readonly=1 FORMAT JSONEachRow"},
+		{"select * from t\nformat TabSeparated", "select * from t SETTINGS max_execution_time=30,readonly=1\nformat TabSeparated"},
+		{"SELECT format FROM t", "SELECT format FROM t SETTINGS max_execution_time=30,readonly=1"},
+		{"SELECT 1 SETTINGS readonly=2", "SELECT 1 SETTINGS readonly=2"},
+	}
+	for _, c := range cases {
+		if got := chX.withSettings(c.sql); got != c.want {
+			t.Errorf("withSettings(%q) = %q, want %q", c.sql, got, c.want)
+		}
+	}
+
+	if got := (&ClickHouseX{}).withSettings("SELECT 1;"); got != "SELECT 1;" {
+		t.Errorf("no settings got %q", got)
+	}
+}
+
+// 没有引入驱动时，错误信息要告诉调用方引入哪个包
+func TestNewClickHENoDriver(t *testing.T) {
+	_, err := NewClickHE(&ConnConfig{ConnStr: "clickhouse://127.0.0.1:9000/default"})
+	if err == nil || !strings.Contains(err.Error(), `_ "github.com/ClickHouse/clickhouse-go/v2"`) {
+		t.Fatalf("got %v", err)
+	}
+}
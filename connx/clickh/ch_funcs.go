@@ -2,6 +2,7 @@ package clickh
 
 import (
 	"database/sql"
+	"github.com/qinchende/gofast/store/sqlx"
 )
 
 func errPanic(err error) {
@@ -11,13 +12,13 @@ func errPanic(err error) {
 }
 
 func (mx *ClickHouseX) Query(sql string, args ...any) *sql.Rows {
-	rows, err := mx.Cli.Query(sql, args...)
+	rows, err := mx.Cli.Query(mx.withSettings(sql), args...)
 	errPanic(err)
 	return rows
 }
 
 func (mx *ClickHouseX) QueryContext(sql string, args ...any) *sql.Rows {
-	rows, err := mx.Cli.QueryContext(mx.Ctx, sql, args...)
+	rows, err := mx.Cli.QueryContext(mx.Ctx, mx.withSettings(sql), args...)
 	errPanic(err)
 	return rows
 }
@@ -33,3 +34,18 @@ func (mx *ClickHouseX) ExecContext(sql string, args ...any) sql.Result {
 	errPanic(err)
 	return result
 }
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 查询一条记录解析到 dest 中，列和字段按 ModelSchema 的列名对应，没有记录返回0
+func (mx *ClickHouseX) QueryRow(dest any, sql string, args ...any) int64 {
+	rows := mx.QueryContext(sql, args...)
+	defer sqlx.CloseSqlRows(rows)
+	return sqlx.ScanRow(dest, rows)
+}
+
+// 查询多条记录解析到 dest 中，dest 是 *[]T 或者 *[]*T
+func (mx *ClickHouseX) QueryRows(dest any, sql string, args ...any) int64 {
+	rows := mx.QueryContext(sql, args...)
+	defer sqlx.CloseSqlRows(rows)
+	return sqlx.ScanRows(dest, rows)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/qinchende/gofast/connx/gfrds"
 	"github.com/qinchende/gofast/store/sqlx"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -15,21 +16,42 @@ type (
 	ConnCnf struct {
 		ConnStr    string   `v:"required"`
 		ConnStrR   string   `v:"required=false"`
+		ConnStrRs  []string `v:"required=false"`       // 多个从库，配置之后 ConnStrR 被忽略
+		WeightsR   []int    `v:"required=false"`       // 从库的权重，和 ConnStrRs 一一对应，默认都是1
+		MaxLagS    int      `v:"def=0,range=[0:3600]"` // 从库复制延迟超过这么多秒就暂时不读，0代表不检查
 		MaxOpen    int      `v:"def=100,range=[1:1000]"`
 		MaxIdle    int      `v:"def=100"`
+		SlowMS     int      `v:"def=500,range=[1:60000]"` // 执行超过这么多毫秒的SQL打印慢日志
 		RedisNodes []string `v:"required=false,len=[10:300]"`
+		DbName     string   `v:"required=false"` // 非MySQL时需要指定，用于生成缓存Key
 	}
 )
 
 func OpenMysql(cf *ConnCnf) *sqlx.OrmDB {
-	ormDB := sqlx.OrmDB{Attrs: &sqlx.DBAttrs{DriverName: "mysql"}, Ctx: context.Background()}
+	return OpenDB(sqlx.DriverMysql, cf)
+}
+
+// PostgreSQL 和 SQLite 需要应用自己引入驱动，比如 github.com/lib/pq 和 github.com/mattn/go-sqlite3
+func OpenPostgres(cf *ConnCnf) *sqlx.OrmDB {
+	return OpenDB(sqlx.DriverPostgres, cf)
+}
+
+func OpenSqlite(cf *ConnCnf) *sqlx.OrmDB {
+	return OpenDB(sqlx.DriverSqlite, cf)
+}
+
+// driverName 决定了 sqlx 生成SQL时使用的方言
+func OpenDB(driverName string, cf *ConnCnf) *sqlx.OrmDB {
+	ormDB := sqlx.OrmDB{Attrs: &sqlx.DBAttrs{DriverName: driverName}, Ctx: context.Background()}
 
 	// DBName ->
-	dbConfig, _ := mysql.ParseDSN(cf.ConnStr)
-	if dbConfig != nil {
-		// 必须统一数据库名称，全部转换成小写
-		// 将来表缓存的时候需要用到这里的DBName
-		ormDB.Attrs.DbName = strings.ToLower(dbConfig.DBName)
+	// 必须统一数据库名称，全部转换成小写
+	// 将来表缓存的时候需要用到这里的DBName
+	ormDB.Attrs.DbName = strings.ToLower(cf.DbName)
+	if ormDB.Attrs.DbName == "" && driverName == sqlx.DriverMysql {
+		if dbConfig, _ := mysql.ParseDSN(cf.ConnStr); dbConfig != nil {
+			ormDB.Attrs.DbName = strings.ToLower(dbConfig.DBName)
+		}
 	}
 
 	// 主库连接
@@ -45,20 +67,30 @@ func OpenMysql(cf *ConnCnf) *sqlx.OrmDB {
 
 	// 从库连接
 	// 如果配置文件配置了只读数据库，应用于读写分离
-	if cf.ConnStrR != "" {
-		reader, err := sql.Open(ormDB.Attrs.DriverName, cf.ConnStrR)
-		if err != nil {
-			log.Fatalf("Conn %s err: %s", cf.ConnStrR, err)
+	if len(cf.ConnStrRs) > 0 {
+		replicas := make([]sqlx.Replica, len(cf.ConnStrRs))
+		for i, connStr := range cf.ConnStrRs {
+			replicas[i] = sqlx.Replica{Name: "replica#" + strconv.Itoa(i), DB: openReader(driverName, connStr, cf)}
+			if i < len(cf.WeightsR) {
+				replicas[i].Weight = cf.WeightsR[i]
+			}
 		}
-		// See "Important settings" section.
-		reader.SetConnMaxLifetime(time.Minute * 3)
-		reader.SetMaxOpenConns(cf.MaxOpen)
-		reader.SetMaxIdleConns(cf.MaxIdle)
-		ormDB.Reader = reader
+		ormDB.Reader = replicas[0].DB
+		ormDB.SetReplicas(sqlx.NewReplicaPool(driverName, replicas, time.Duration(cf.MaxLagS)*time.Second))
+	} else if cf.ConnStrR != "" {
+		ormDB.Reader = openReader(driverName, cf.ConnStrR, cf)
 	} else {
 		ormDB.Reader = ormDB.Writer
 	}
 
+	// 慢日志阈值，连接池状态导出到 Prometheus
+	ormDB.Attrs.SlowThreshold = time.Duration(cf.SlowMS) * time.Millisecond
+	if ormDB.Attrs.DbName != "" {
+		ormDB.StatPool(ormDB.Attrs.DbName)
+	} else {
+		ormDB.StatPool(driverName)
+	}
+
 	// redis cache
 	rds := cf.RedisNodes
 	rdsNodes := make([]gfrds.GfRedis, len(rds))
@@ -70,3 +102,15 @@ func OpenMysql(cf *ConnCnf) *sqlx.OrmDB {
 
 	return &ormDB
 }
+
+func openReader(driverName, connStr string, cf *ConnCnf) *sql.DB {
+	reader, err := sql.Open(driverName, connStr)
+	if err != nil {
+		log.Fatalf("Conn %s err: %s", connStr, err)
+	}
+	// See "Important settings" section.
+	reader.SetConnMaxLifetime(time.Minute * 3)
+	reader.SetMaxOpenConns(cf.MaxOpen)
+	reader.SetMaxIdleConns(cf.MaxIdle)
+	return reader
+}
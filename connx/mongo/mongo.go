@@ -2,6 +2,7 @@ package mongo
 
 import (
 	"context"
+	"fmt"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
@@ -33,6 +34,15 @@ func (mgX *MgoX) Close() {
 }
 
 func NewMongo(cf *ConnConfig) *MgoX {
+	mgX, err := NewMongoE(cf)
+	if err != nil {
+		log.Fatalf("MongoDB %v", err)
+	}
+	return mgX
+}
+
+// 连接或者 Ping 失败时返回错误，由调用方决定如何处理
+func NewMongoE(cf *ConnConfig) (*MgoX, error) {
 	url := cf.Url
 	pass := cf.Pass
 	user := cf.User
@@ -46,14 +56,15 @@ func NewMongo(cf *ConnConfig) *MgoX {
 	client, err := mongo.Connect(ctx, cOpt)
 
 	if err != nil {
-		log.Fatalf("MongoDB connect err: %v", err)
+		return nil, fmt.Errorf("connect err: %w", err)
 	}
 
 	err = client.Ping(ctx, readpref.Primary())
 	if err != nil {
-		log.Fatalf("MongoDB ping err: %v", err)
+		_ = client.Disconnect(context.Background())
+		return nil, fmt.Errorf("ping err: %w", err)
 	}
 
 	log.Printf("MongoDB: %s connect sucess!", url)
-	return &MgoX{DB: client.Database(db), Cli: client, Ctx: context.Background()}
+	return &MgoX{DB: client.Database(db), Cli: client, Ctx: context.Background()}, nil
 }
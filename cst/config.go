@@ -12,6 +12,7 @@ type WebConfig struct {
 	ForwardedByClientIP   bool   `v:"def=true"`      // 是否从"X-Forwarded-For"的header中提取请求IP地址
 	ApplyUrlParamsToPms   bool   `v:"def=true"`      // 将UrlParams解析的参数自动加入Pms
 	PrintRouteTrees       bool   `v:"def=false"`     // 是否打印出当前路由数
+	HotReloadRoutes       bool   `v:"def=false"`     // 是否支持运行时热更新路由（保留原始路由信息，多占一些内存）
 
 	//LogType     string `v:"def=json,enum=json|sdx"`              // 日志类型
 	//EnableRouteMonitor bool `cnf:",def=true"` // 是否统计路由的访问处理情况，为单个路由的熔断降载做储备
@@ -33,4 +34,7 @@ type SdxConfig struct {
 	EnableShedding        bool  `v:"def=true"`  // 启动降载限制访问
 	EnableTimeout         bool  `v:"def=true"`  // 启动超时拦截
 	DefTimeoutMS          int64 `v:"def=3000"`  // 默认请求超时时间（单位：毫秒）
+
+	EnableMetrics bool   `v:"def=false"`    // 启动 Prometheus 统计
+	MetricsPath   string `v:"def=/metrics"` // Prometheus 抓取数据的路由
 }
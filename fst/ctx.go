@@ -14,7 +14,8 @@ import (
 // Context is the most important part of GoFast. It allows us to pass variables between middleware,
 // manage the flow, validate the JSON of a request and render a JSON response for example.
 type Context struct {
-	myApp *GoFast // 用于上下文
+	myApp *GoFast      // 用于上下文
+	mem   *fstMemSpace // 本次请求使用的路由树版本
 
 	EnterTime time.Duration // 请求起始时间
 	ResWrap   *ResponseWrap
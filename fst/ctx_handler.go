@@ -3,15 +3,15 @@
 package fst
 
 func (c *Context) SetRouteToAny() {
-	c.route.ptrNode = c.myApp.miniNodeAny
+	c.route.ptrNode = c.mem.miniNodeAny
 }
 
 func (c *Context) SetRouteTo404() {
-	c.route.ptrNode = c.myApp.miniNode404
+	c.route.ptrNode = c.mem.miniNode404
 }
 
 func (c *Context) SetRouteTo405() {
-	c.route.ptrNode = c.myApp.miniNode405
+	c.route.ptrNode = c.mem.miniNode405
 }
 
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
@@ -21,7 +21,7 @@ func (c *Context) execHandlers() {
 	}
 
 	c.RouteIdx = c.route.ptrNode.routeIdx
-	c.handlers = c.myApp.fstMem.hdsNodes[c.route.ptrNode.hdsItemIdx]
+	c.handlers = c.mem.hdsNodes[c.route.ptrNode.hdsItemIdx]
 	c.execIdx = -1
 	c.Next()
 }
@@ -30,7 +30,7 @@ func (c *Context) execHandlers() {
 func (c *Context) Next() {
 	c.execIdx++
 	for c.execIdx < int8(len(c.handlers.hdsIdxChain)) {
-		c.myApp.fstMem.tidyHandlers[c.handlers.hdsIdxChain[c.execIdx]](c)
+		c.mem.tidyHandlers[c.handlers.hdsIdxChain[c.execIdx]](c)
 		// 可能被设置成了 abort ，这样后面的 handlers 不用再调用了
 		if c.execIdx == maxRouteHandlers {
 			break
@@ -44,16 +44,16 @@ func (c *Context) execAfterMatchHandlers() {
 	//if c.route.ptrNode == nil {
 	//	return
 	//}
-	it := c.myApp.fstMem.hdsNodes[c.route.ptrNode.hdsItemIdx]
-	gp := c.myApp.fstMem.hdsNodes[c.route.ptrNode.hdsGroupIdx]
+	it := c.mem.hdsNodes[c.route.ptrNode.hdsItemIdx]
+	gp := c.mem.hdsNodes[c.route.ptrNode.hdsGroupIdx]
 
 	for gp.afterMatchLen > 0 {
-		c.myApp.fstMem.tidyHandlers[gp.afterMatchIdx](c)
+		c.mem.tidyHandlers[gp.afterMatchIdx](c)
 		gp.afterMatchLen--
 		gp.afterMatchIdx++
 	}
 	for it.afterMatchLen > 0 {
-		c.myApp.fstMem.tidyHandlers[it.afterMatchIdx](c)
+		c.mem.tidyHandlers[it.afterMatchIdx](c)
 		it.afterMatchLen--
 		it.afterMatchIdx++
 	}
@@ -64,15 +64,15 @@ func (c *Context) execBeforeSendHandlers() {
 	//if c.route.ptrNode == nil {
 	//	return
 	//}
-	it := c.handlers // c.myApp.fstMem.hdsNodes[c.route.ptrNode.hdsItemIdx]
-	gp := c.myApp.fstMem.hdsNodes[c.route.ptrNode.hdsGroupIdx]
+	it := c.handlers // c.mem.hdsNodes[c.route.ptrNode.hdsItemIdx]
+	gp := c.mem.hdsNodes[c.route.ptrNode.hdsGroupIdx]
 
 	// 5.beforeSend
 	for gp.beforeSendLen > 0 {
 		//if c.aborted {
 		//	goto over
 		//}
-		c.myApp.fstMem.tidyHandlers[gp.beforeSendIdx](c)
+		c.mem.tidyHandlers[gp.beforeSendIdx](c)
 		gp.beforeSendLen--
 		gp.beforeSendIdx++
 	}
@@ -80,7 +80,7 @@ func (c *Context) execBeforeSendHandlers() {
 		//if c.aborted {
 		//	goto over
 		//}
-		c.myApp.fstMem.tidyHandlers[it.beforeSendIdx](c)
+		c.mem.tidyHandlers[it.beforeSendIdx](c)
 		it.beforeSendLen--
 		it.beforeSendIdx++
 	}
@@ -92,15 +92,15 @@ func (c *Context) execAfterSendHandlers() {
 	//if c.route.ptrNode == nil {
 	//	return
 	//}
-	it := c.handlers // c.myApp.fstMem.hdsNodes[c.route.ptrNode.hdsItemIdx]
-	gp := c.myApp.fstMem.hdsNodes[c.route.ptrNode.hdsGroupIdx]
+	it := c.handlers // c.mem.hdsNodes[c.route.ptrNode.hdsItemIdx]
+	gp := c.mem.hdsNodes[c.route.ptrNode.hdsGroupIdx]
 
 	// 6.afterSend
 	for it.afterSendLen > 0 {
 		//if c.aborted {
 		//	goto over
 		//}
-		c.myApp.fstMem.tidyHandlers[it.afterSendIdx](c)
+		c.mem.tidyHandlers[it.afterSendIdx](c)
 		it.afterSendLen--
 		it.afterSendIdx++
 	}
@@ -108,7 +108,7 @@ func (c *Context) execAfterSendHandlers() {
 		//if c.aborted {
 		//	goto over
 		//}
-		c.myApp.fstMem.tidyHandlers[gp.afterSendIdx](c)
+		c.mem.tidyHandlers[gp.afterSendIdx](c)
 		gp.afterSendLen--
 		gp.afterSendIdx++
 	}
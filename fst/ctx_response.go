@@ -170,7 +170,8 @@ func (w *ResponseWrap) SendHijackRedirect(req *http.Request, resStatus int, redi
 		return
 	}
 	w.resetResponse(resStatus, lang.ToBytes(redirectUrl))
-	http.Redirect(w, req, redirectUrl, resStatus)
+	// 这里已经持有锁，要直接写底层的 ResponseWriter；传 w 的话 http.Redirect 调用 w.WriteHeader 会再次加锁导致死锁
+	http.Redirect(w.ResponseWriter, req, redirectUrl, resStatus)
 	w.respLock.Unlock()
 }
 
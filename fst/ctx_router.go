@@ -22,6 +22,32 @@ func (gft *GoFast) RoutePathsWithMethod() []string {
 	return allPaths
 }
 
+// 路由的基本信息，方便生成接口文档等
+type RouteInfo struct {
+	Index  uint16 // 路由索引，和 RouteAttrs.SetRouteIndex 对应
+	Method string
+	Path   string       // 注册时的完整路径，包含参数约束
+	Host   string       // 分组限定的 Host，空代表默认路由树
+	Attrs  []RouteAttrs // 路由上挂载的属性
+}
+
+// 所有正常的路由（不含特殊路由和已删除的路由）
+func (gft *GoFast) RouteInfos() []RouteInfo {
+	infos := make([]RouteInfo, 0, len(gft.allRoutes))
+	for i := int(gft.speRoutesLen); i < len(gft.allRoutes); i++ {
+		ri := gft.allRoutes[i]
+		if ri.removed {
+			continue
+		}
+		info := RouteInfo{Index: ri.routeIdx, Method: ri.method, Path: ri.fullPath, Attrs: ri.attrs}
+		if ri.group.host != nil {
+			info.Host = ri.group.host.pattern
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 // special
 func (gft *GoFast) SpecialRoutesLen() uint16 {
@@ -39,15 +65,16 @@ func (gft *GoFast) SpecialRoutePaths() []string {
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 // 获取相应路由节点完整URL
 func (gft *GoFast) FullPath(idx uint16) string {
-	if idx < 0 || int(idx) >= len(gft.allPaths) {
+	allPaths := gft.loadMem().allPaths
+	if int(idx) >= len(allPaths) {
 		return ""
 	}
-	return gft.allPaths[idx]
+	return allPaths[idx]
 }
 
 func (c *Context) FullPath() string {
 	if c.route.ptrNode != nil {
-		return c.myApp.allPaths[c.route.ptrNode.routeIdx]
+		return c.mem.allPaths[c.route.ptrNode.routeIdx]
 	} else {
 		return ""
 	}
@@ -11,6 +11,7 @@ import (
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -35,23 +36,22 @@ type GoFast struct {
 type HomeRouter struct {
 	RouteGroup              // HomeRouter 本身就是一个路由分组
 	allRoutes  []*RouteItem // 记录当前Server所有的路由信息，方便后期重构路由树（）
-	allPaths   []string     // 相应的路由URL
 
 	// 有三个特殊 RouteItem： 1. any 2. noRoute  3. noMethod
 	// 这三个节点不参与构建路由树
 	specialGroup *RouteGroup // 特殊路由分组
 	speRoutesLen uint16
-	miniNodeAny  *radixMiniNode
-	miniNode404  *radixMiniNode
-	miniNode405  *radixMiniNode
 
 	// 虽然支持 RESTFUL 路由规范，但本框架 GET 和 POST 是一等公民
 	// 绝大部分应用Get和Post请求居多，我们能尽快匹配就不需要无用的Method比较选择的过程
 	//（我主张不要过分强调restful风格，这本身就是个鸡肋概念，没有完全解决问题，反而带来思想负担，引发无用争辩）。
 	routerTrees methodTrees
+	hostRouters []*hostRouter // 按 Host 区分的路由树，见 RouteGroup.Host
 
-	ctxPool sync.Pool    // 第二级：Handler context pools (第一级是标准形式，不需要缓冲池)
-	fstMem  *fstMemSpace // 主要以数组结构的形式，存储了 Routes & Handlers
+	ctxPool   sync.Pool    // 第二级：Handler context pools (第一级是标准形式，不需要缓冲池)
+	fstMem    *fstMemSpace // 主要以数组结构的形式，存储了 Routes & Handlers，注册路由和构建路由树时使用
+	curMem    atomic.Value // 当前生效的 *fstMemSpace，请求都从这里取
+	routeLock sync.Mutex   // 热更新路由时加锁
 }
 
 // 一个快速创建Server的函数，使用默认配置参数，方便调用。
@@ -141,6 +141,7 @@ func (gft *GoFast) serveHTTPWithCtx(w http.ResponseWriter, r *http.Request) {
 	c.ResWrap.Reset(w)
 	c.ReqRaw = r
 	c.reset()
+	c.mem = gft.loadMem() // 整个请求都使用同一个版本的路由树
 	gft.handleHTTPRequest(c)
 	// 超时引发的对象不能放回缓存池
 	if !c.ResWrap.isTimeout {
@@ -164,36 +165,16 @@ func (gft *GoFast) handleHTTPRequest(c *Context) {
 
 	// 以下分A、B、C三步走
 	// A. 看能不能找到 http method 对应的路由树
-	miniRoot := gft.getMethodMiniRoot(c.ReqRaw.Method)
-	if miniRoot != nil {
-		// 开始在路由树中匹配 url path
-		miniRoot.matchRoute(gft.fstMem, reqPath, &c.route, unescape)
-		c.UrlParams = c.route.params
-
-		// 如果能匹配到路径
-		if c.route.ptrNode != nil {
-			// 进一步的check，比如可以在这里跳转成404；或者直接AbortDirect
-			if c.route.ptrNode.hasAfterMatch {
-				c.execAfterMatchHandlers()
-			}
-
-			// 如果已经render，说明上面路由判断出了问题，执行特殊处理函数
-			if c.rendered {
-				c.execIdx = -1                    // 解除Render限制
-				c.route.ptrNode = gft.miniNodeAny // after match error handlers
-			}
-			c.execHandlers() // match handlers
-			return
-		}
-
-		// 支持重定向 && c.ReqRaw.Method != CONNECT && reqPath != [home index]
-		if c.route.rts && c.ReqRaw.Method[0] != 'C' && reqPath != "/" {
-			// TODO：需要重定向的跳转，先执行特殊中间件
-			c.route.ptrNode = gft.miniNodeAny // after match error handlers
-			c.execHandlers()                  // match handlers
-			redirectTrailingSlash(c)          // redirect handlers
-			return
-		}
+	// 请求的 Host 匹配了某个 Host 路由时先在它的路由树中找，找不到再用默认路由树；Host 中的参数一直保留
+	hostTrees := c.mem.matchHost(c.ReqRaw, c.route.params)
+	hostPmsLen := len(*c.route.params)
+	if hostTrees != nil && gft.matchInTrees(c, hostTrees, reqPath, unescape) {
+		return
+	}
+	*c.route.params = (*c.route.params)[:hostPmsLen]
+	c.route.rts = false
+	if gft.matchInTrees(c, c.mem.routerTrees, reqPath, unescape) {
+		return
 	}
 
 	// B. 可以尝试是否不同的Method中能匹配路由
@@ -201,26 +182,74 @@ func (gft *GoFast) handleHTTPRequest(c *Context) {
 	// 找到了：就给出Method错误提示
 	// 找不到：就走后面路由没匹配的逻辑
 	if gft.WebConfig.CheckOtherMethodRoute {
-		for _, tree := range gft.routerTrees {
-			if tree.method == c.ReqRaw.Method || tree.miniRoot == nil {
-				continue
-			}
-			// 在别的 Method 路由树中匹配到了当前路径，返回提示 当前请求的 Method 错了。
-			if tree.miniRoot.matchRoute(gft.fstMem, reqPath, &c.route, unescape); c.route.ptrNode != nil {
-				c.route.ptrNode = gft.miniNode405
-				c.UrlParams = c.route.params
-				c.execHandlers() // 405 handlers
-				return
-			}
+		if hostTrees != nil && gft.matchOtherMethod(c, hostTrees, reqPath, unescape) {
+			return
+		}
+		if gft.matchOtherMethod(c, c.mem.routerTrees, reqPath, unescape) {
+			return
 		}
 	}
 
 	// C. 以上都无法匹配，就走404逻辑
-	c.route.ptrNode = gft.miniNode404
+	c.route.ptrNode = c.mem.miniNode404
 	c.execHandlers() // 404 handlers
 	return
 }
 
+// 在一组 method 路由树中匹配并执行，返回请求是否已经处理
+func (gft *GoFast) matchInTrees(c *Context, trees methodTrees, reqPath string, unescape bool) bool {
+	miniRoot := trees.getMethodMiniRoot(c.ReqRaw.Method)
+	if miniRoot == nil {
+		return false
+	}
+
+	// 开始在路由树中匹配 url path
+	miniRoot.matchRoute(c.mem, reqPath, &c.route, unescape)
+	c.UrlParams = c.route.params
+
+	// 如果能匹配到路径
+	if c.route.ptrNode != nil {
+		// 进一步的check，比如可以在这里跳转成404；或者直接AbortDirect
+		if c.route.ptrNode.hasAfterMatch {
+			c.execAfterMatchHandlers()
+		}
+
+		// 如果已经render，说明上面路由判断出了问题，执行特殊处理函数
+		if c.rendered {
+			c.execIdx = -1                      // 解除Render限制
+			c.route.ptrNode = c.mem.miniNodeAny // after match error handlers
+		}
+		c.execHandlers() // match handlers
+		return true
+	}
+
+	// 支持重定向 && c.ReqRaw.Method != CONNECT && reqPath != [home index]
+	if c.route.rts && c.ReqRaw.Method[0] != 'C' && reqPath != "/" {
+		// TODO：需要重定向的跳转，先执行特殊中间件
+		c.route.ptrNode = c.mem.miniNodeAny // after match error handlers
+		c.execHandlers()                    // match handlers
+		redirectTrailingSlash(c)            // redirect handlers
+		return true
+	}
+	return false
+}
+
+func (gft *GoFast) matchOtherMethod(c *Context, trees methodTrees, reqPath string, unescape bool) bool {
+	for _, tree := range trees {
+		if tree.method == c.ReqRaw.Method || tree.miniRoot == nil {
+			continue
+		}
+		// 在别的 Method 路由树中匹配到了当前路径，返回提示 当前请求的 Method 错了。
+		if tree.miniRoot.matchRoute(c.mem, reqPath, &c.route, unescape); c.route.ptrNode != nil {
+			c.route.ptrNode = c.mem.miniNode405
+			c.UrlParams = c.route.params
+			c.execHandlers() // 405 handlers
+			return true
+		}
+	}
+	return false
+}
+
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 // NOTE：重构路由树。（重要！重要！重要！必须调用这个方法初始化路由树和中间件）
 // 在不执行真正Listen的场景中，调用此函数能初始化服务器（必须要调用此函数来构造路由）
@@ -234,6 +263,7 @@ func (gft *GoFast) BuildRoutes() {
 	gft.execAppHandlers(gft.eBeforeBuildRoutesHds) // before build routes
 	gft.buildAllRoutes()
 	gft.execAppHandlers(gft.eAfterBuildRoutesHds) // after build routes
+	gft.curMem.Store(gft.fstMem)                  // 路由树生效
 }
 
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
@@ -5,6 +5,7 @@ package fst
 const (
 	EBeforeBuildRoutes = "onBeforeBuildRoutes" // 开始重构路由树前
 	EAfterBuildRoutes  = "onAfterBuildRoutes"  // 重构路由树后
+	EReloadRoutes      = "onReloadRoutes"      // 热更新路由，新路由树生效之前调用
 	EReady             = "onReady"             // server 接收正式请求之前调用
 	EClose             = "onClose"             // server 关闭退出之前调用
 )
@@ -12,6 +13,7 @@ const (
 type appEvents struct {
 	eBeforeBuildRoutesHds []AppHandler
 	eAfterBuildRoutesHds  []AppHandler
+	eReloadRoutesHds      []AppHandler
 	eReadyHds             []AppHandler
 	eCloseHds             []AppHandler
 }
@@ -28,6 +30,8 @@ func (gft *GoFast) On(eType string, handles ...AppHandler) {
 		gft.eBeforeBuildRoutesHds = append(gft.eBeforeBuildRoutesHds, handles...)
 	case EAfterBuildRoutes:
 		gft.eAfterBuildRoutesHds = append(gft.eAfterBuildRoutesHds, handles...)
+	case EReloadRoutes:
+		gft.eReloadRoutesHds = append(gft.eReloadRoutesHds, handles...)
 	case EReady:
 		gft.eReadyHds = append(gft.eReadyHds, handles...)
 	case EClose:
@@ -45,6 +49,10 @@ func (gft *GoFast) OnAfterBuildRoutes(hds ...AppHandler) {
 	gft.On(EAfterBuildRoutes, hds...)
 }
 
+func (gft *GoFast) OnReloadRoutes(hds ...AppHandler) {
+	gft.On(EReloadRoutes, hds...)
+}
+
 func (gft *GoFast) OnReady(hds ...AppHandler) {
 	gft.On(EReady, hds...)
 }
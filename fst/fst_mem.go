@@ -29,7 +29,19 @@ type fstMemSpace struct {
 	allRadixMiniNodes []radixMiniNode
 	allRadixMiniLen   uint16
 
+	// 参数节点的约束，radixMiniNode.consIdx 是这里的索引
+	paramCons []*paramCons
+
 	// handlers节点切片（专门用户记录 不同类型处理函数 的索引值）
 	hdsNodes    []handlersNode // 专门记录事件处理函数的节点切片
 	hdsNodesLen uint16         // 节点数量
+
+	// 以下是本次构建的路由树入口，请求进来时取当前版本的 fstMemSpace，热更新路由时整体替换，处理中的请求不受影响
+	routerTrees methodTrees   // 默认的路由树
+	hosts       []*hostRouter // 按 Host 区分的路由树，和 hostTrees 一一对应
+	hostTrees   []methodTrees
+	miniNodeAny *radixMiniNode
+	miniNode404 *radixMiniNode
+	miniNode405 *radixMiniNode
+	allPaths    []string // 所有路由的URL，下标是 routeIdx
 }
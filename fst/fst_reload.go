@@ -0,0 +1,50 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package fst
+
+// 运行时热更新路由，不用重启服务：
+// app.Get("/new", handler)          // 服务启动之后继续注册路由
+// app.RemoveRoute("GET", "/old")    // 标记删除路由
+// app.ReloadRoutes()                // 在新的 fstMemSpace 中重建路由树，然后整体替换
+// 处理中的请求继续使用老的路由树，新请求使用新的路由树
+// 注意：注册、删除和重建路由需要在同一个协程中顺序调用；非调试模式需要开启 WebConfig.HotReloadRoutes
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+func (gft *GoFast) ReloadRoutes() {
+	gft.routeLock.Lock()
+	defer gft.routeLock.Unlock()
+
+	GFPanicIf(!gft.IsDebugging() && !gft.WebConfig.HotReloadRoutes, "Hot reload routes need WebConfig.HotReloadRoutes.")
+	GFPanicIf(gft.allRoutes == nil, "Routes resource has been released, enable WebConfig.HotReloadRoutes first.")
+
+	// 老的 fstMemSpace 可能还有请求在用，只能在新的空间中重建
+	old := gft.fstMem
+	gft.fstMem = &fstMemSpace{
+		myApp:          gft,
+		allCtxHandlers: old.allCtxHandlers,
+		allCtxHdsLen:   old.allCtxHdsLen,
+	}
+	for _, ri := range gft.allRoutes {
+		ri.hdsIdx = 0
+	}
+	gft.buildAllRoutes()
+
+	gft.execAppHandlers(gft.eReloadRoutesHds) // 新路由生效之前，准备好路由相关的资源
+	gft.curMem.Store(gft.fstMem)
+}
+
+// 标记删除路由，调用 ReloadRoutes 之后生效，返回是否找到该路由
+// 路由的索引位置保持不变，RouteIdx 对应的统计等资源不受影响
+func (gft *GoFast) RemoveRoute(method, path string) bool {
+	gft.routeLock.Lock()
+	defer gft.routeLock.Unlock()
+
+	found := false
+	for i := int(gft.speRoutesLen); i < len(gft.allRoutes); i++ {
+		ri := gft.allRoutes[i]
+		if !ri.removed && ri.method == method && ri.fullPath == path {
+			ri.removed = true
+			found = true
+		}
+	}
+	return found
+}
@@ -6,13 +6,17 @@ import "net/http"
 
 // 一次性构建Mini内存版的所有路由项
 func (gft *GoFast) buildAllRoutes() {
-	gft.routerTrees = make(methodTrees, 0, 9)
-	gft.routerTrees = append(gft.routerTrees, &methodTree{method: http.MethodGet})
-	gft.routerTrees = append(gft.routerTrees, &methodTree{method: http.MethodPost})
+	gft.routerTrees = newMethodTrees()
+	for _, hr := range gft.hostRouters {
+		hr.trees = newMethodTrees()
+	}
 
 	// TODO：启动server之前，注册的路由只是做了记录在allRouters变量中，这里开始一次性构造路由前缀树
 	// Note: 前面三个路由项是系统默认的特殊路由，不参与具体的路由树构造
 	for i := int(gft.speRoutesLen); i < len(gft.allRoutes); i++ {
+		if gft.allRoutes[i].removed {
+			continue
+		}
 		gft.regRouteItem(gft.allRoutes[i])
 	}
 
@@ -24,6 +28,22 @@ func (gft *GoFast) buildAllRoutes() {
 	gft.buildMiniRoutes()
 }
 
+// 当前生效的路由树，还没有构建时返回正在注册的
+func (gft *GoFast) loadMem() *fstMemSpace {
+	if mem, ok := gft.curMem.Load().(*fstMemSpace); ok {
+		return mem
+	}
+	return gft.fstMem
+}
+
+// GET 和 POST 两棵树固定在最前面，方便快速查找
+func newMethodTrees() methodTrees {
+	trees := make(methodTrees, 0, 9)
+	trees = append(trees, &methodTree{method: http.MethodGet})
+	trees = append(trees, &methodTree{method: http.MethodPost})
+	return trees
+}
+
 // 注册每一条的路由，生成 原始的 Radix 树
 func (gft *GoFast) regRouteItem(ri *RouteItem) {
 	// Debug模式下打印新添加的路由
@@ -31,43 +51,51 @@ func (gft *GoFast) regRouteItem(ri *RouteItem) {
 		debugPrintRoute(gft, ri)
 	}
 
-	mTree := gft.getMethodTree(ri.method)
+	// 指定了 Host 的分组，路由注册到对应 Host 的路由树
+	trees := &gft.routerTrees
+	if ri.group.host != nil {
+		trees = &ri.group.host.trees
+	}
+	mTree := trees.getMethodTree(ri.method)
 	if mTree == nil {
 		mTree = &methodTree{method: ri.method, root: nil}
-		gft.routerTrees = append(gft.routerTrees, mTree)
+		*trees = append(*trees, mTree)
+	}
+	// 有可选参数时，一个路由要注册多条路径
+	for _, path := range ri.treePaths {
+		mTree.regRoute(path, ri)
 	}
-	mTree.regRoute(ri.fullPath, ri)
 }
 
 // 获取method树的根节点
-func (gft *GoFast) getMethodMiniRoot(method string) (tRoot *radixMiniNode) {
+func (trees methodTrees) getMethodMiniRoot(method string) (tRoot *radixMiniNode) {
 	switch method[0] {
 	case 'G':
-		tRoot = gft.routerTrees[0].miniRoot
+		tRoot = trees[0].miniRoot
 	case 'P':
 		if method[1] == 'O' {
-			tRoot = gft.routerTrees[1].miniRoot
+			tRoot = trees[1].miniRoot
 		} else {
-			tRoot = gft.routerTrees.getTreeMiniRoot(method)
+			tRoot = trees.getTreeMiniRoot(method)
 		}
 	default:
-		tRoot = gft.routerTrees.getTreeMiniRoot(method)
+		tRoot = trees.getTreeMiniRoot(method)
 	}
 	return
 }
 
-func (gft *GoFast) getMethodTree(method string) (tree *methodTree) {
+func (trees methodTrees) getMethodTree(method string) (tree *methodTree) {
 	switch method[0] {
 	case 'G':
-		tree = gft.routerTrees[0]
+		tree = trees[0]
 	case 'P':
 		if method[1] == 'O' {
-			tree = gft.routerTrees[1]
+			tree = trees[1]
 		} else {
-			tree = gft.routerTrees.getTree(method)
+			tree = trees.getTree(method)
 		}
 	default:
-		tree = gft.routerTrees.getTree(method)
+		tree = trees.getTree(method)
 	}
 	return
 }
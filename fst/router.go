@@ -23,17 +23,23 @@ type RouteGroup struct {
 	myApp        *GoFast
 	prefix       string
 	children     []*RouteGroup
-	hdsIdx       int16  // 记录当前分组 对应新事件数组中的起始位置索引
-	selfHdsLen   uint16 // 记录当前分组中一共加入的处理函数个数（仅限于本分组加入的事件，不包含合并上级分组的）
-	parentHdsLen uint16 // 记录所属上级分组的所有处理函数个数（仅包含上级分组，不含本分组的事件个数）
+	hdsIdx       int16       // 记录当前分组 对应新事件数组中的起始位置索引
+	selfHdsLen   uint16      // 记录当前分组中一共加入的处理函数个数（仅限于本分组加入的事件，不包含合并上级分组的）
+	parentHdsLen uint16      // 记录所属上级分组的所有处理函数个数（仅包含上级分组，不含本分组的事件个数）
+	host         *hostRouter // 分组限定的 Host，nil 代表默认路由树
 }
 
 type RouteItem struct {
-	group       *RouteGroup // router group
-	method      string      // httpMethod
-	fullPath    string      // 路由的完整路径
-	routeEvents             // all handlers
-	routeIdx    uint16      // 此路由在路由数组中的索引值
+	group       *RouteGroup           // router group
+	method      string                // httpMethod
+	fullPath    string                // 路由的完整路径
+	routeEvents                       // all handlers
+	routeIdx    uint16                // 此路由在路由数组中的索引值
+	hdsIdx      int16                 // 对应新事件数组中的位置，可选参数的路由会对应多个树节点，只需要生成一次
+	paramCons   map[string]*paramCons // 参数名对应的约束
+	treePaths   []string              // 去掉参数约束之后注册到路由树的路径
+	removed     bool                  // 已经删除，下次重建路由树时不再注册
+	attrs       []RouteAttrs          // 路由上挂载的属性，比如接口文档
 }
 
 // 每一种事件类型需要占用3个字节(开始索引2字节 + 长度1字节(长度最大255))
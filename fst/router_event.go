@@ -132,5 +132,6 @@ func (ri *RouteItem) AfterMatch(hds ...CtxHandler) *RouteItem {
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 func (ri *RouteItem) Attrs(ra RouteAttrs) *RouteItem {
 	ra.SetRouteIndex(ri.routeIdx)
+	ri.attrs = append(ri.attrs, ra)
 	return ri
 }
@@ -16,6 +16,7 @@ func (gp *RouteGroup) Group(relPath string) *RouteGroup {
 		prefix: gp.fixAbsolutePath(relPath),
 		myApp:  gp.myApp,
 		hdsIdx: -1,
+		host:   gp.host,
 	}
 	gp.children = append(gp.children, gpNew)
 	return gpNew
@@ -85,7 +86,7 @@ func (gp *RouteGroup) createStaticHandler(relPath string, fs http.FileSystem) Ct
 		if err != nil {
 			// 没有匹配到静态文件，用系统中 404 （NoRoute handler）做响应处理
 			c.ResWrap.WriteHeader(http.StatusNotFound)
-			c.route.ptrNode = gp.myApp.miniNode404
+			c.route.ptrNode = c.mem.miniNode404
 			c.execHandlers()
 			return
 		}
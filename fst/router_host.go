@@ -0,0 +1,142 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package fst
+
+import (
+	"net/http"
+	"strings"
+)
+
+// 按请求的 Host 选择路由树，一个进程可以给多个域名提供不同的路由，比如：
+// api := app.Host("api.example.com")
+// tenant := app.Host("{tenant}.example.com")   // 通过 c.Param("tenant") 拿到子域名
+// any := app.Host("*.example.com")             // * 匹配任意一段，但不记录参数
+// 每个 Host 有自己的一组 method 路由树，没有匹配到 Host 或者 Host 路由树中找不到路由时，使用默认（没有指定 Host）的路由树
+// 完全匹配的 Host 优先，其次是参数少的
+type hostRouter struct {
+	pattern string   // 原始写法，统一转成小写
+	labels  []string // 按 '.' 拆开的每一段
+	paramCt int      // 参数和通配段的个数
+	trees   methodTrees
+}
+
+// 返回一个新的分组，前缀和当前分组一样，所有在这个分组（以及子分组）下注册的路由只响应匹配 pattern 的请求
+func (gp *RouteGroup) Host(pattern string) *RouteGroup {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	GFPanicIf(pattern == "", "Host pattern can not be empty")
+	gpNew := gp.Group("")
+	gpNew.host = gp.myApp.addHostRouter(pattern)
+	return gpNew
+}
+
+func (gft *GoFast) addHostRouter(pattern string) *hostRouter {
+	for _, hr := range gft.hostRouters {
+		if hr.pattern == pattern {
+			return hr
+		}
+	}
+
+	hr := &hostRouter{pattern: pattern, labels: strings.Split(pattern, ".")}
+	for _, lb := range hr.labels {
+		GFPanicIf(lb == "", spf("Host %s is not valid", pattern))
+		if lb == "*" || isHostParam(lb) {
+			hr.paramCt++
+		} else {
+			GFPanicIf(strings.ContainsAny(lb, "{}*"), spf("Host %s is not valid", pattern))
+		}
+	}
+
+	// 参数少的排在前面，优先匹配
+	pos := len(gft.hostRouters)
+	for i, item := range gft.hostRouters {
+		if hr.paramCt < item.paramCt {
+			pos = i
+			break
+		}
+	}
+	gft.hostRouters = append(gft.hostRouters, nil)
+	copy(gft.hostRouters[pos+1:], gft.hostRouters[pos:])
+	gft.hostRouters[pos] = hr
+	return hr
+}
+
+func isHostParam(label string) bool {
+	return len(label) > 2 && label[0] == '{' && label[len(label)-1] == '}'
+}
+
+// 找到请求对应的 Host 路由树，Host 中的参数追加到 params 中
+func (mem *fstMemSpace) matchHost(r *http.Request, params *routeParams) methodTrees {
+	if len(mem.hosts) == 0 {
+		return nil
+	}
+	host := stripHostPort(r.Host)
+	for i, hr := range mem.hosts {
+		if hr.match(host, params) {
+			return mem.hostTrees[i]
+		}
+	}
+	return nil
+}
+
+func (hr *hostRouter) match(host string, params *routeParams) bool {
+	if hr.paramCt == 0 {
+		return len(host) == len(hr.pattern) && strings.EqualFold(host, hr.pattern)
+	}
+
+	pmsLen := len(*params)
+	for _, lb := range hr.labels {
+		pos := strings.IndexByte(host, '.')
+		if pos < 0 {
+			pos = len(host)
+		}
+		part := host[:pos]
+		switch {
+		case part == "":
+			*params = (*params)[:pmsLen]
+			return false
+		case isHostParam(lb):
+			*params = append(*params, UrlParam{Key: lb[1 : len(lb)-1], Value: strings.ToLower(part)})
+		case lb != "*" && !strings.EqualFold(lb, part):
+			*params = (*params)[:pmsLen]
+			return false
+		}
+		if pos == len(host) {
+			host = ""
+		} else {
+			host = host[pos+1:]
+		}
+	}
+	// 段数必须一样
+	if host != "" {
+		*params = (*params)[:pmsLen]
+		return false
+	}
+	return true
+}
+
+// 去掉端口，支持 [::1]:8080 这种 IPv6 的写法
+func stripHostPort(host string) string {
+	if len(host) > 0 && host[0] == '[' {
+		if end := strings.IndexByte(host, ']'); end > 0 {
+			return host[1:end]
+		}
+		return host
+	}
+	if pos := strings.LastIndexByte(host, ':'); pos >= 0 {
+		return host[:pos]
+	}
+	return host
+}
+
+// 默认的路由树和所有 Host 的路由树
+func (gft *GoFast) allMethodTrees() methodTrees {
+	if len(gft.hostRouters) == 0 {
+		return gft.routerTrees
+	}
+	all := make(methodTrees, 0, len(gft.routerTrees)+2*len(gft.hostRouters))
+	all = append(all, gft.routerTrees...)
+	for _, hr := range gft.hostRouters {
+		all = append(all, hr.trees...)
+	}
+	return all
+}
@@ -31,6 +31,7 @@ func (gp *RouteGroup) register(httpMethod, relPath string, hds []CtxHandler) *Ro
 		group:    gp,
 		routeIdx: 0,
 	}
+	ri.treePaths = ri.parsePath()
 	myApp := gp.myApp
 	ri.eHds = addCtxHandlers(myApp.fstMem, hds)
 	// 保存了所有的合法路由规则，暂不生成路由树，待所有环境初始化完成之后再构造路由前缀树
@@ -1 +1,13 @@
 package router
+
+import (
+	"github.com/qinchende/gofast/logx"
+	"os"
+	"testing"
+)
+
+// 整个包的测试共用一份日志配置，不要在单个测试里初始化全局状态
+func TestMain(m *testing.M) {
+	logx.MustSetup(&logx.LogConfig{LogMedium: "console", LogLevel: "error", LogStyle: "sdx"})
+	os.Exit(m.Run())
+}
@@ -0,0 +1,58 @@
+package router
+
+import (
+	"github.com/qinchende/gofast/fst"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func performHostRequest(app *fst.GoFast, method, host, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Host = host
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	return w
+}
+
+func TestRouteHost(t *testing.T) {
+	hit := ""
+	router := fst.Default()
+	router.Get("/home", func(c *fst.Context) {
+		hit = "default:" + c.Param("tenant")
+	})
+	router.Host("api.example.com").Get("/home", func(c *fst.Context) {
+		hit = "api"
+	})
+	tenant := router.Host("{tenant}.example.com")
+	tenant.Get("/home", func(c *fst.Context) {
+		hit = "tenant:" + c.Param("tenant")
+	})
+	tenant.Group("/v1").Get("/user/:id", func(c *fst.Context) {
+		hit = "user:" + c.Param("tenant") + ":" + c.Param("id")
+	})
+	router.Host("admin.example.com").Post("/login", func(c *fst.Context) {
+		hit = "admin"
+	})
+	router.BuildRoutes()
+
+	performHostRequest(router, http.MethodGet, "api.example.com:8080", "/home")
+	assert.Equal(t, "api", hit)
+	performHostRequest(router, http.MethodGet, "acme.example.com", "/home")
+	assert.Equal(t, "tenant:acme", hit)
+	performHostRequest(router, http.MethodGet, "acme.example.com", "/v1/user/12")
+	assert.Equal(t, "user:acme:12", hit)
+	performHostRequest(router, http.MethodGet, "localhost", "/home")
+	assert.Equal(t, "default:", hit)
+
+	// Host 路由树中找不到时用默认路由树
+	performHostRequest(router, http.MethodGet, "admin.example.com", "/home")
+	assert.Equal(t, "default:", hit)
+
+	w := performHostRequest(router, http.MethodGet, "localhost", "/v1/user/12")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performHostRequest(router, http.MethodGet, "a.b.example.com", "/v1/user/12")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
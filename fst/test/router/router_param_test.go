@@ -0,0 +1,91 @@
+package router
+
+import (
+	"github.com/qinchende/gofast/fst"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteParamConstraints(t *testing.T) {
+	hit := ""
+	router := fst.Default()
+	router.Get("/user/:id<int>", func(c *fst.Context) {
+		hit = "id:" + c.Param("id")
+	})
+	router.Get("/user/:name", func(c *fst.Context) {
+		hit = "name:" + c.Param("name")
+	})
+	router.Get("/file/:name<[a-z]+\\.png>", func(c *fst.Context) {
+		hit = "file:" + c.Param("name")
+	})
+	router.Get("/order/:sn<uuid>/items", func(c *fst.Context) {
+		hit = "order:" + c.Param("sn")
+	})
+	router.BuildRoutes()
+
+	performRequest(router, http.MethodGet, "/user/123")
+	assert.Equal(t, "id:123", hit)
+	performRequest(router, http.MethodGet, "/user/john")
+	assert.Equal(t, "name:john", hit)
+
+	performRequest(router, http.MethodGet, "/file/logo.png")
+	assert.Equal(t, "file:logo.png", hit)
+	w := performRequest(router, http.MethodGet, "/file/logo.jpg")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	performRequest(router, http.MethodGet, "/order/0f8fad5b-d9cb-469f-a165-70867728950e/items")
+	assert.Equal(t, "order:0f8fad5b-d9cb-469f-a165-70867728950e", hit)
+	w = performRequest(router, http.MethodGet, "/order/123/items")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRouteParamConstraintBacktrack(t *testing.T) {
+	hit := ""
+	router := fst.Default()
+	router.Get("/u/:id<int>/a", func(c *fst.Context) {
+		hit = "a:" + c.Param("id")
+	})
+	router.Get("/u/:name/b", func(c *fst.Context) {
+		hit = "b:" + c.Param("name")
+	})
+	router.BuildRoutes()
+
+	performRequest(router, http.MethodGet, "/u/5/a")
+	assert.Equal(t, "a:5", hit)
+	w := performRequest(router, http.MethodGet, "/u/5/b")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "b:5", hit)
+	performRequest(router, http.MethodGet, "/u/tom/b")
+	assert.Equal(t, "b:tom", hit)
+	w = performRequest(router, http.MethodGet, "/u/tom/a")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRouteOptionalParam(t *testing.T) {
+	hit := ""
+	router := fst.Default()
+	router.Get("/list/:page<uint>?", func(c *fst.Context) {
+		hit = "list:" + c.Param("page")
+	})
+	router.BuildRoutes()
+
+	performRequest(router, http.MethodGet, "/list/3")
+	assert.Equal(t, "list:3", hit)
+	performRequest(router, http.MethodGet, "/list")
+	assert.Equal(t, "list:", hit)
+	w := performRequest(router, http.MethodGet, "/list/x")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.Panics(t, func() {
+		fst.Default().Get("/a/:b?/c", func(c *fst.Context) {})
+	})
+}
+
+func TestRouteParamConstraintConflict(t *testing.T) {
+	router := fst.Default()
+	router.Get("/user/:id<int>", func(c *fst.Context) {})
+	router.Get("/user/:uid<int>", func(c *fst.Context) {})
+	assert.Panics(t, func() { router.BuildRoutes() })
+}
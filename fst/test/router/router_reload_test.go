@@ -0,0 +1,107 @@
+package router
+
+import (
+	"github.com/qinchende/gofast/fst"
+	"github.com/qinchende/gofast/sdx"
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteReload(t *testing.T) {
+	hit := ""
+	router := fst.Default()
+	router.WebConfig.HotReloadRoutes = true
+	router.Get("/old", func(c *fst.Context) {
+		hit = "old"
+	})
+	router.BuildRoutes()
+
+	reloaded := false
+	router.OnReloadRoutes(func(app *fst.GoFast) {
+		reloaded = true
+	})
+
+	// 新注册的路由在 ReloadRoutes 之后才生效
+	router.Get("/new/:id", func(c *fst.Context) {
+		hit = "new:" + c.Param("id")
+	})
+	w := performRequest(router, http.MethodGet, "/new/1")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	router.ReloadRoutes()
+	assert.True(t, reloaded)
+	performRequest(router, http.MethodGet, "/new/1")
+	assert.Equal(t, "new:1", hit)
+	performRequest(router, http.MethodGet, "/old")
+	assert.Equal(t, "old", hit)
+
+	assert.True(t, router.RemoveRoute(http.MethodGet, "/old"))
+	assert.False(t, router.RemoveRoute(http.MethodGet, "/none"))
+	router.ReloadRoutes()
+	w = performRequest(router, http.MethodGet, "/old")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	performRequest(router, http.MethodGet, "/new/2")
+	assert.Equal(t, "new:2", hit)
+}
+
+// 带上 sdx 的全套中间件，请求处理中热更新路由，需要 go test -race 检查
+func TestRouteReloadConcurrent(t *testing.T) {
+	router := fst.Default()
+	router.WebConfig.HotReloadRoutes = true
+	router.SdxConfig.EnableTimeout = true
+	router.SdxConfig.DefTimeoutMS = 3000
+	sdx.SuperHandlers(router)
+	router.Get("/base", func(c *fst.Context) {
+		c.String(http.StatusOK, "base")
+	})
+	router.BuildRoutes()
+
+	var wg, ready sync.WaitGroup
+	var served int64
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		ready.Add(1)
+		go func() {
+			defer wg.Done()
+			ready.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				w := performRequest(router, http.MethodGet, "/base")
+				assert.Equal(t, http.StatusOK, w.Code)
+				performRequest(router, http.MethodGet, "/new/1")
+				atomic.AddInt64(&served, 1)
+				// 单核机器上请求协程和超时子协程会一直互相唤醒，不让出的话其它协程可能长时间得不到调度
+				runtime.Gosched()
+			}
+		}()
+	}
+	ready.Wait()
+
+	// 每次热更新之间都要有请求在处理
+	for i := 0; i < 20; i++ {
+		router.Get("/new/"+strconv.Itoa(i), func(c *fst.Context) {
+			c.String(http.StatusOK, "new")
+		})
+		router.ReloadRoutes()
+		for last := atomic.LoadInt64(&served); atomic.LoadInt64(&served) < last+2; {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	w := performRequest(router, http.MethodGet, "/new/19")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -104,6 +104,22 @@ matchChildNode:
 		pNode = &fstMem.allRadixMiniNodes[n.childStart+id]
 		// 子节点是 模糊匹配节点（:*） | 首字符匹配的普通节点，就走这个逻辑
 		if pNode.nType >= param || (pLen > 0 && fstMem.treeChars[pNode.matchStart] == path[0]) {
+			// 参数约束不满足，尝试后面的兄弟参数节点
+			if pNode.consIdx > 0 {
+				if !fstMem.paramCons[pNode.consIdx].match(path, unescape) {
+					continue
+				}
+				// 后面还有兄弟参数节点时，这个分支后面的路径匹配失败，要回退尝试兄弟节点
+				if id+1 < uint16(n.childLen) {
+					pmLen := len(*mr.params)
+					pNode.matchRoute(fstMem, path, mr, unescape)
+					if mr.ptrNode != nil {
+						return
+					}
+					*mr.params = (*mr.params)[:pmLen]
+					continue
+				}
+			}
 			n = pNode
 			goto nextLoop
 		}
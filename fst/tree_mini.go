@@ -2,10 +2,12 @@
 // Use of this source code is governed by a MIT license
 package fst
 
+import "math"
+
 // 用新的数据结构重建整棵路由树，用数组实现的树结构
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 // 自定义数据结构存放 所有的 路由树相关信息，全部通过数组索引的方式来访问
-// 目前是64位系统的两个字长，16字节
+// 目前是18字节
 type radixMiniNode struct {
 	// router index (2字节)
 	routeIdx uint16
@@ -26,6 +28,8 @@ type radixMiniNode struct {
 
 	// 节点类型 （1字节）
 	nType uint8
+	// 参数约束在 fstMem.paramCons 中的索引，0代表没有约束 （1字节）
+	consIdx uint8
 	// wildChild bool // 下一个节点是否为通配符
 
 	// 因为下面这几种情况相对来说都少，预先判断，利于每次请求的执行效率
@@ -67,11 +71,17 @@ func (n *radixNode) rebuildNode(fstMem *fstMemSpace, idx uint16) {
 	}
 
 	// 第一种：如果是一个路由叶子节点 (能匹配一个路由)
-	if n.leafItem != nil {
-		newMini.hdsGroupIdx = n.leafItem.group.hdsIdx     // 记录“分组”事件在 全局 事件队列中的 起始位置
-		newMini.hdsItemIdx = n.leafItem.rebuildHandlers() // 记录“节点”事件在 全局 事件队列中的 起始位置
-		newMini.routeIdx = n.leafItem.routeIdx
-		combNodeHandlers(fstMem, newMini, true) // 构造执行链
+	if ri := n.leafItem; ri != nil {
+		newMini.hdsGroupIdx = ri.group.hdsIdx // 记录“分组”事件在 全局 事件队列中的 起始位置
+		newMini.routeIdx = ri.routeIdx
+		// 可选参数的路由对应多个叶子节点，共用同一组事件
+		if ri.hdsIdx > 0 {
+			newMini.hdsItemIdx = ri.hdsIdx
+		} else {
+			newMini.hdsItemIdx = ri.rebuildHandlers() // 记录“节点”事件在 全局 事件队列中的 起始位置
+			ri.hdsIdx = newMini.hdsItemIdx
+			combNodeHandlers(fstMem, newMini, true) // 构造执行链
+		}
 	}
 	// 释放掉资源
 	n.leafItem = nil
@@ -79,6 +89,11 @@ func (n *radixNode) rebuildNode(fstMem *fstMemSpace, idx uint16) {
 	// 节点类型 和 是否通配符
 	newMini.nType = n.nType
 	//newMini.wildChild = n.wildChild
+	if n.cons != nil {
+		fstMem.paramCons = append(fstMem.paramCons, n.cons)
+		GFPanicIf(len(fstMem.paramCons) > math.MaxUint8, "Too many route param constraints more than MaxUint8.")
+		newMini.consIdx = uint8(len(fstMem.paramCons) - 1)
+	}
 
 	if newMini.hdsGroupIdx > 0 && newMini.hdsItemIdx > 0 {
 		hdsGroup := fstMem.hdsNodes[newMini.hdsGroupIdx]
@@ -149,20 +164,21 @@ func combNodeHandlers(fstMem *fstMemSpace, miniNode *radixMiniNode, needGroup bo
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 // 重建特殊节点
 func rebuildSpecialHandlers(home *GoFast) {
-	home.miniNodeAny = &radixMiniNode{routeIdx: home.allRoutes[0].routeIdx}
-	home.miniNodeAny.hdsGroupIdx = home.allRoutes[0].group.hdsIdx
-	home.miniNodeAny.hdsItemIdx = home.allRoutes[0].rebuildHandlers()
-	combNodeHandlers(home.fstMem, home.miniNodeAny, true)
-
-	home.miniNode404 = &radixMiniNode{routeIdx: home.allRoutes[1].routeIdx}
-	home.miniNode404.hdsGroupIdx = home.allRoutes[1].group.hdsIdx
-	home.miniNode404.hdsItemIdx = home.allRoutes[1].rebuildHandlers()
-	combNodeHandlers(home.fstMem, home.miniNode404, true)
-
-	home.miniNode405 = &radixMiniNode{routeIdx: home.allRoutes[2].routeIdx}
-	home.miniNode405.hdsGroupIdx = home.allRoutes[2].group.hdsIdx
-	home.miniNode405.hdsItemIdx = home.allRoutes[2].rebuildHandlers()
-	combNodeHandlers(home.fstMem, home.miniNode405, true)
+	fstMem := home.fstMem
+	fstMem.miniNodeAny = &radixMiniNode{routeIdx: home.allRoutes[0].routeIdx}
+	fstMem.miniNodeAny.hdsGroupIdx = home.allRoutes[0].group.hdsIdx
+	fstMem.miniNodeAny.hdsItemIdx = home.allRoutes[0].rebuildHandlers()
+	combNodeHandlers(fstMem, fstMem.miniNodeAny, true)
+
+	fstMem.miniNode404 = &radixMiniNode{routeIdx: home.allRoutes[1].routeIdx}
+	fstMem.miniNode404.hdsGroupIdx = home.allRoutes[1].group.hdsIdx
+	fstMem.miniNode404.hdsItemIdx = home.allRoutes[1].rebuildHandlers()
+	combNodeHandlers(fstMem, fstMem.miniNode404, true)
+
+	fstMem.miniNode405 = &radixMiniNode{routeIdx: home.allRoutes[2].routeIdx}
+	fstMem.miniNode405.hdsGroupIdx = home.allRoutes[2].group.hdsIdx
+	fstMem.miniNode405.hdsItemIdx = home.allRoutes[2].rebuildHandlers()
+	combNodeHandlers(fstMem, fstMem.miniNode405, true)
 }
 
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
@@ -194,6 +210,7 @@ func (gft *GoFast) buildMiniRoutes() {
 	fstMem.allRadixMiniLen = 0
 	fstMem.routeGroupLen = 0
 	fstMem.routeItemLen = uint16(len(gft.allRoutes))
+	fstMem.paramCons = []*paramCons{nil} // 0 代表没有约束
 	// end
 	// ++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 
@@ -211,7 +228,7 @@ func (gft *GoFast) buildMiniRoutes() {
 	gpRebuildHandlers(&gft.RouteGroup)
 	gpRebuildHandlers(gft.specialGroup)
 	// 2. 重建路由树 （这里面将节点事件 转换到 新版全局数组中）
-	for _, mTree := range gft.routerTrees {
+	for _, mTree := range gft.allMethodTrees() {
 		rebuildMethodTree(fstMem, mTree)
 	}
 	// 3. 重建特殊节点，比如 NoRoute | NoMethod
@@ -221,15 +238,24 @@ func (gft *GoFast) buildMiniRoutes() {
 	fstMem.treeChars = string(fstMem.treeCharT)
 
 	// 所有路由节点URL
-	gft.allPaths = gft.RoutePaths()
+	fstMem.allPaths = gft.RoutePaths()
+	// 记录本次构建的路由树入口
+	fstMem.routerTrees = gft.routerTrees
+	fstMem.hosts = make([]*hostRouter, len(gft.hostRouters))
+	fstMem.hostTrees = make([]methodTrees, len(gft.hostRouters))
+	for i, hr := range gft.hostRouters {
+		fstMem.hosts[i] = hr
+		fstMem.hostTrees[i] = hr.trees
+	}
 
 	// TODO: 释放掉原始树的资源，后面不可以根据这些树结构构造路由了。
-	if !gft.IsDebugging() {
+	// 需要热更新路由时，原始路由和处理函数都要保留
+	if !gft.IsDebugging() && !gft.WebConfig.HotReloadRoutes {
 		fstMem.allCtxHandlers = nil
 		fstMem.allCtxHdsLen = 0
 		fstMem.treeCharT = nil
 
-		for _, mTree := range gft.routerTrees {
+		for _, mTree := range gft.allMethodTrees() {
 			mTree.root = nil
 		}
 
@@ -244,7 +270,7 @@ func allocateMemSpace(gft *GoFast) {
 	fstMem := gft.fstMem
 
 	var totalNodes, nodeStrLen uint16
-	for _, mTree := range gft.routerTrees {
+	for _, mTree := range gft.allMethodTrees() {
 		totalNodes += mTree.nodeCt
 		nodeStrLen += mTree.nodeStrLen
 	}
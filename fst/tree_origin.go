@@ -2,7 +2,7 @@
 // Use of this source code is governed by a MIT license
 package fst
 
-// 目前一共 73 字节
+// 目前一共 81 字节
 type radixNode struct {
 	match     string       // 16字节
 	indices   string       // 16字节
@@ -10,6 +10,7 @@ type radixNode struct {
 	wildChild bool         // 1字节
 	nType     uint8        // 8字节
 	leafItem  *RouteItem   // 8字节
+	cons      *paramCons   // 8字节，参数节点的约束
 }
 
 // n.nType必须是通配型，seg必须以通配符(:,*)打头
@@ -33,6 +34,9 @@ func (n *radixNode) matchSameWildcards(mTree *methodTree, seg string, ri *RouteI
 	if n.match != part {
 		panic(spf("通配路由 %s 和 %s 参数名称不一致\n", n.match, part))
 	}
+	if !sameCons(n.cons, ri.consOf(part)) {
+		panic(spf("通配路由 %s 的参数约束不一致\n", n.match))
+	}
 	// 证明刚好完整匹配参数
 	if !hasSlash {
 		if n.leafItem != nil {
@@ -48,9 +52,26 @@ func (n *radixNode) matchSameWildcards(mTree *methodTree, seg string, ri *RouteI
 
 // 只能添加 wildcard path
 func (n *radixNode) addWildChild(mTree *methodTree, seg string, ri *RouteItem) {
-	// 如果子节点是通配符节点，直接循环下一次
+	// 如果子节点是通配符节点，约束相同的共用节点，约束不同的参数作为兄弟节点，匹配时依次尝试
 	if n.wildChild {
-		n.children[0].regSegment(mTree, n, seg, ri)
+		cons := ri.consOf(seg)
+		for _, ch := range n.children {
+			if seg[0] == '*' || ch.nType == catchAll || sameCons(ch.cons, cons) {
+				ch.regSegment(mTree, n, seg, ri)
+				return
+			}
+		}
+
+		emptySub := &radixNode{}
+		mTree.nodeCt++
+		// 没有约束的参数节点放在最后兜底
+		last := len(n.children) - 1
+		if cons != nil && n.children[last].cons == nil {
+			n.children = append(n.children[:last], emptySub, n.children[last])
+		} else {
+			n.children = append(n.children, emptySub)
+		}
+		emptySub.bindSegment(mTree, seg, ri)
 		return
 	}
 
@@ -135,6 +156,7 @@ func (n *radixNode) regSegment(mTree *methodTree, nParent *radixNode, seg string
 			leafItem:  n.leafItem,
 			nType:     n.nType,
 			wildChild: n.wildChild,
+			cons:      n.cons,
 		}
 		mTree.nodeCt++
 		n.children = []*radixNode{splitSub}
@@ -220,6 +242,7 @@ func (n *radixNode) bindSegment(mTree *methodTree, path string, ri *RouteItem) {
 				if tSeg[0] == '*' {
 					pNode.nType = catchAll
 				}
+				pNode.cons = ri.consOf(tSeg)
 			}
 			pNode.match = tSeg
 			mTree.nodeStrLen += uint16(len(tSeg))
@@ -240,6 +263,7 @@ func (n *radixNode) bindSegment(mTree *methodTree, path string, ri *RouteItem) {
 			if tSeg[0] == '*' {
 				newNode.nType = catchAll
 			}
+			newNode.cons = ri.consOf(tSeg)
 		} else {
 			pNode.indices += string([]byte{tSeg[0]})
 		}
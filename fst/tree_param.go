@@ -0,0 +1,185 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package fst
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// 路由参数的约束，写法：
+// /user/:id<int>                  内置类型：int, uint, alpha, alnum, hex, uuid
+// /file/:name<[a-z]+\.png>        其它的都当作正则表达式，必须完整匹配当前段
+// /list/:page<uint>?              ? 表示可选段，相当于同时注册了 /list/:page 和 /list，只能是最后一段
+// 同一位置可以有多个约束不同的参数节点，匹配时约束不满足就尝试下一个，没有约束的参数节点最后兜底
+type paramCons struct {
+	expr  string // 约束的原始写法，打印路由树时显示
+	check func(val string) bool
+}
+
+var paramTypes = map[string]func(val string) bool{
+	"int":   isIntParam,
+	"uint":  isUintParam,
+	"alpha": func(val string) bool { return allBytesIn(val, isAlpha) },
+	"alnum": func(val string) bool { return allBytesIn(val, func(c byte) bool { return isAlpha(c) || isDigit(c) }) },
+	"hex":   func(val string) bool { return allBytesIn(val, isHex) },
+	"uuid":  isUUIDParam,
+}
+
+func newParamCons(expr string) *paramCons {
+	if fn, ok := paramTypes[expr]; ok {
+		return &paramCons{expr: expr, check: fn}
+	}
+	reg, err := regexp.Compile("^(?:" + expr + ")$")
+	GFPanicIf(err != nil, spf("路由参数约束 <%s> 不是合法的正则表达式：%v", expr, err))
+	return &paramCons{expr: expr, check: reg.MatchString}
+}
+
+// path 是待匹配的剩余路径，只校验第一段
+func (pc *paramCons) match(path string, unescape bool) bool {
+	if pos := strings.IndexByte(path, '/'); pos >= 0 {
+		path = path[:pos]
+	}
+	if unescape {
+		if v, err := url.QueryUnescape(path); err == nil {
+			path = v
+		}
+	}
+	return pc.check(path)
+}
+
+func sameCons(a, b *paramCons) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.expr == b.expr
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 解析路由中参数的约束和可选段，返回去掉约束之后真正要注册到路由树的路径
+// 比如 /a/:b<int>? 返回 [/a/:b /a]，约束记录在 ri.paramCons 中
+// 路由树中同一位置不能同时有普通段和参数段，所以可选段只能是最后一段
+func (ri *RouteItem) parsePath() []string {
+	path := ri.fullPath
+	if strings.IndexByte(path, '<') < 0 && strings.IndexByte(path, '?') < 0 {
+		return []string{path}
+	}
+
+	paths := []string{""}
+	for i := 1; i <= len(path); {
+		// 找到当前段的结尾，约束中可能有 '/'，需要跳过
+		end, seg, optional := i, "", false
+		if i < len(path) && path[i] == ':' {
+			end = i + 1
+			for end < len(path) && path[end] != '/' && path[end] != '<' && path[end] != '?' {
+				end++
+			}
+			name := path[i+1 : end]
+			seg = path[i:end]
+			if end < len(path) && path[end] == '<' {
+				cEnd := consEnd(path, end)
+				GFPanicIf(cEnd < 0, spf("路由 %s 参数约束没有闭合", path))
+				if ri.paramCons == nil {
+					ri.paramCons = make(map[string]*paramCons)
+				}
+				ri.paramCons[name] = newParamCons(path[end+1 : cEnd])
+				end = cEnd + 1
+			}
+			if end < len(path) && path[end] == '?' {
+				optional = true
+				end++
+				GFPanicIf(end < len(path), spf("路由 %s 只有最后一段参数可以是可选的", path))
+			}
+			GFPanicIf(end < len(path) && path[end] != '/', spf("路由 %s 参数约束之后只能是 '/'", path))
+		} else {
+			for end < len(path) && path[end] != '/' {
+				end++
+			}
+			seg = path[i:end]
+			GFPanicIf(strings.ContainsAny(seg, "<?"), spf("路由 %s 中只有 : 参数才能设置约束和可选", path))
+		}
+
+		ct := len(paths)
+		for j := 0; j < ct; j++ {
+			if optional {
+				paths = append(paths, paths[j])
+			}
+			paths[j] += "/" + seg
+		}
+		i = end + 1
+	}
+
+	for j := range paths {
+		if paths[j] == "" {
+			paths[j] = "/"
+		}
+	}
+	return paths
+}
+
+// 约束的结束位置：后面紧跟 '/'、'?' 或者路径结束的 '>'
+func consEnd(path string, start int) int {
+	for i := start + 1; i < len(path); i++ {
+		if path[i] == '>' && (i == len(path)-1 || path[i+1] == '/' || path[i+1] == '?') {
+			return i
+		}
+	}
+	return -1
+}
+
+// 通配段对应的参数约束，seg 是 :name 或者 :name/xxx
+func (ri *RouteItem) consOf(seg string) *paramCons {
+	if ri.paramCons == nil || seg[0] != ':' {
+		return nil
+	}
+	if pos := strings.IndexByte(seg, '/'); pos > 0 {
+		seg = seg[:pos]
+	}
+	return ri.paramCons[seg[1:]]
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+func isAlpha(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isHex(c byte) bool   { return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') }
+
+func allBytesIn(val string, fn func(c byte) bool) bool {
+	if len(val) == 0 {
+		return false
+	}
+	for i := 0; i < len(val); i++ {
+		if !fn(val[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isUintParam(val string) bool {
+	return len(val) <= 20 && allBytesIn(val, isDigit)
+}
+
+func isIntParam(val string) bool {
+	if len(val) > 0 && val[0] == '-' {
+		val = val[1:]
+	}
+	return len(val) <= 19 && allBytesIn(val, isDigit)
+}
+
+// 8-4-4-4-12
+func isUUIDParam(val string) bool {
+	if len(val) != 36 {
+		return false
+	}
+	for i := 0; i < len(val); i++ {
+		if i == 8 || i == 13 || i == 18 || i == 23 {
+			if val[i] != '-' {
+				return false
+			}
+		} else if !isHex(val[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -17,6 +17,12 @@ func (gft *GoFast) printRouteTrees() {
 	for _, tree := range gft.routerTrees {
 		printTree(tree, strTree)
 	}
+	for _, hr := range gft.hostRouters {
+		strTree.WriteString("[Host: " + hr.pattern + "]\n")
+		for _, tree := range hr.trees {
+			printTree(tree, strTree)
+		}
+	}
 	strTree.WriteString("++++++++++++++++++++++++++++++\n")
 	// 打印到控制台
 	debugPrintRouteTree(gft, strTree)
@@ -44,9 +50,13 @@ func (n *radixNode) prettyPrint(str *strings.Builder, prefix string, isTail bool
 		nextPrefix += "│   "
 	}
 
-	// 要显示的节点内容
-	str.WriteString(n.match)
-	curLen := len([]rune(prefix)) + len([]rune(n.match))
+	// 要显示的节点内容，参数约束显示在参数名后面
+	match := n.match
+	if n.cons != nil {
+		match += "<" + n.cons.expr + ">"
+	}
+	str.WriteString(match)
+	curLen := len([]rune(prefix)) + len([]rune(match))
 	// 缩进最大是160字符占位符
 	retract := 60 - curLen
 	for i := 1; i <= 5; i++ {
@@ -1 +1,153 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
 package rpcx
+
+import (
+	"context"
+	"github.com/qinchende/gofast/fst"
+	"github.com/qinchende/gofast/logx"
+	"github.com/qinchende/gofast/sdx/gate"
+	"google.golang.org/grpc"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// gRPC 服务端的配置参数，服务治理相关的开关和 SdxConfig 保持一致
+type GrpcSrvCnf struct {
+	Name             string `v:"required"`                         // 服务名称
+	ListenAddr       string `v:"def=0.0.0.0:9099,route=ipv4:port"` // 监听ip:port
+	BeforeShutdownMS int64  `v:"def=1000"`                         // 退出server之前等待的毫秒，等待正在处理的请求完成
+	DefTimeoutMS     int32  `v:"def=3000,range=[0:]"`              // 默认请求超时时间（单位：毫秒）
+	SlowTimeMS       int32  `v:"def=500,range=[0:]"`               // 超过这个耗时的请求记入慢日志
+	EnableTrack      bool   `v:"def=false"`                        // 启动链路追踪
+	EnableShedding   bool   `v:"def=true"`                         // 启动降载限制访问
+	EnableTimeout    bool   `v:"def=true"`                         // 启动超时拦截
+}
+
+// 承载 gRPC 服务，所有方法都经过 sdx 的治理链：计数|追踪|日志|熔断|降载|超时|异常|耗时
+type GrpcServer struct {
+	GrpcSrvCnf
+	srv     *grpc.Server
+	keeper  *gate.RequestKeeper
+	methods map[string]uint16 // FullMethod -> 统计和熔断用的索引
+	quit    chan os.Signal
+}
+
+// register 中完成所有服务的注册，比如：pb.RegisterGreeterServer(srv, &greeter{})
+func NewGrpcServer(cnf *GrpcSrvCnf, register func(srv *grpc.Server), opts ...grpc.ServerOption) *GrpcServer {
+	gs := &GrpcServer{
+		GrpcSrvCnf: *cnf,
+		keeper:     gate.NewReqKeeper(cnf.Name),
+		quit:       make(chan os.Signal, 1),
+	}
+
+	// 拦截器按照先后顺序依次执行，顺序不可随意改变。用户自定义的拦截器通过 opts 追加在后面
+	unary := grpc.ChainUnaryInterceptor(
+		gs.unaryCount,    // 访问计数
+		gs.unaryTracing,  // 链路追踪
+		gs.unaryLogger,   // 请求日志
+		gs.unaryBreaker,  // 自适应熔断
+		gs.unaryShedding, // 过载保护
+		gs.unaryTimeout,  // 超时自动返回
+		gs.unaryRecovery, // @@@ 截获所有异常，避免服务进程崩溃 @@@
+		gs.unaryMetric,   // 耗时统计
+	)
+	stream := grpc.ChainStreamInterceptor(
+		gs.streamCount,
+		gs.streamTracing,
+		gs.streamLogger,
+		gs.streamBreaker,
+		gs.streamRecovery,
+	)
+	gs.srv = grpc.NewServer(append([]grpc.ServerOption{unary, stream}, opts...)...)
+	register(gs.srv)
+	gs.initKeeper()
+	return gs
+}
+
+// 所有服务注册完成之后才知道方法的数量，这里按方法名排序构造统计和熔断器
+func (gs *GrpcServer) initKeeper() {
+	var paths []string
+	for svc, info := range gs.srv.GetServiceInfo() {
+		for _, md := range info.Methods {
+			paths = append(paths, "/"+svc+"/"+md.Name)
+		}
+	}
+	sort.Strings(paths)
+
+	gs.methods = make(map[string]uint16, len(paths))
+	for i, p := range paths {
+		gs.methods[p] = uint16(i)
+	}
+	gs.keeper.InitAndRun(paths, []string{"AllRequest"})
+}
+
+func (gs *GrpcServer) Server() *grpc.Server {
+	return gs.srv
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 启动监听，不阻塞当前协程
+func (gs *GrpcServer) Start() error {
+	lis, err := net.Listen("tcp", gs.ListenAddr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := gs.srv.Serve(lis); err != nil {
+			logx.Error(err.Error())
+			gs.quit <- syscall.SIGABRT // 应用异常退出
+		}
+	}()
+	logx.InfoF("Grpc: %s listening on %s", gs.Name, gs.ListenAddr)
+	return nil
+}
+
+// 单独运行 gRPC 服务，阻塞直到收到退出信号
+func (gs *GrpcServer) Listen() {
+	if err := gs.Start(); err != nil {
+		logx.ErrorFatal(err)
+	}
+
+	signal.Notify(gs.quit, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM) // 和 GoFast 监听相同的信号
+	sign := <-gs.quit
+	if sign != syscall.SIGABRT {
+		logx.InfoF("Signal: %s(pid: %d), starting shutdown...", sign, os.Getpid())
+	}
+	gs.Stop()
+	logx.Info("Grpc listen exit, bye...")
+}
+
+// HTTP 和 gRPC 混合部署：跟随 GoFast 一起启动，GoFast 收到退出信号时一起优雅关闭
+func (gs *GrpcServer) BindApp(app *fst.GoFast) {
+	app.OnReady(func(app *fst.GoFast) {
+		if err := gs.Start(); err != nil {
+			logx.ErrorFatal(err)
+		}
+	})
+	app.OnClose(func(app *fst.GoFast) {
+		gs.Stop()
+	})
+}
+
+// 优雅关闭：不再接收新请求，等待处理中的请求完成，超过 BeforeShutdownMS 就强制关闭
+func (gs *GrpcServer) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(gs.BeforeShutdownMS)*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		gs.srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logx.Error("Grpc: graceful stop timeout, force stop.")
+		gs.srv.Stop()
+	}
+}
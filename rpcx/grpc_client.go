@@ -0,0 +1,104 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package rpcx
+
+import (
+	"context"
+	"fmt"
+	"github.com/qinchende/gofast/skill/discov"
+	"github.com/qinchende/gofast/skill/fuse"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"sync"
+	"time"
+)
+
+// gRPC 客户端的配置参数
+type GrpcCliCnf struct {
+	Target      string `v:"required"`                                   // 目标地址，比如 127.0.0.1:9099 | dns:///svc.local:9099 | discov:///user-svc
+	Balancer    string `v:"def=p2c,enum=round_robin|weighted|p2c|hash"` // 负载均衡算法
+	TimeoutMS   int32  `v:"def=2000,range=[0:]"`                        // 单次调用超时时间（单位：毫秒），0不限制
+	SlowTimeMS  int32  `v:"def=500,range=[0:]"`                         // 超过这个耗时的调用记入慢日志
+	DialMS      int32  `v:"def=3000,range=[0:]"`                        // 建立连接的超时时间（单位：毫秒），0表示不等待连接就绪
+	Retries     int32  `v:"def=0,range=[0:5]"`                          // Unavailable 时最多重试的次数（只适合幂等调用）
+	RetryMS     int32  `v:"def=50,range=[0:]"`                          // 重试间隔（单位：毫秒），每次翻倍
+	EnableTrack bool   `v:"def=true"`                                   // 向下游传递链路追踪信息
+	Insecure    bool   `v:"def=true"`                                   // 不使用 TLS
+}
+
+// 客户端拦截器按方法区分熔断器，下游某个方法异常不影响其它方法的调用
+type GrpcClient struct {
+	GrpcCliCnf
+	conn     *grpc.ClientConn
+	breakers map[string]fuse.Breaker
+	brkMu    sync.RWMutex
+}
+
+func NewGrpcClient(cnf *GrpcCliCnf, opts ...grpc.DialOption) (*GrpcClient, error) {
+	gc := &GrpcClient{GrpcCliCnf: *cnf, breakers: make(map[string]fuse.Breaker)}
+	if gc.Balancer == "" {
+		gc.Balancer = discov.LbP2C
+	}
+
+	// 拦截器按照先后顺序依次执行：追踪|日志|重试|熔断|超时
+	dialOpts := []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(
+			gc.unaryTracing,
+			gc.unaryLogger,
+			gc.unaryRetry,
+			gc.unaryBreaker,
+			gc.unaryTimeout,
+		),
+		grpc.WithChainStreamInterceptor(
+			gc.streamTracing,
+			gc.streamBreaker,
+		),
+		grpc.WithDefaultServiceConfig(fmt.Sprintf(serviceCnfLbFmt, gc.Balancer)),
+	}
+	if gc.Insecure {
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	dialOpts = append(dialOpts, opts...)
+
+	ctx := context.Background()
+	if gc.DialMS > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(gc.DialMS)*time.Millisecond)
+		defer cancel()
+		dialOpts = append(dialOpts, grpc.WithBlock())
+	}
+
+	conn, err := grpc.DialContext(ctx, gc.Target, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	gc.conn = conn
+	return gc, nil
+}
+
+// 生成的 pb 客户端都用这个连接，比如：pb.NewGreeterClient(gc.Conn())
+func (gc *GrpcClient) Conn() *grpc.ClientConn {
+	return gc.conn
+}
+
+func (gc *GrpcClient) Close() error {
+	return gc.conn.Close()
+}
+
+// 每个方法第一次调用时才创建熔断器
+func (gc *GrpcClient) breaker(method string) fuse.Breaker {
+	gc.brkMu.RLock()
+	brk, ok := gc.breakers[method]
+	gc.brkMu.RUnlock()
+	if ok {
+		return brk
+	}
+
+	gc.brkMu.Lock()
+	defer gc.brkMu.Unlock()
+	if brk, ok = gc.breakers[method]; !ok {
+		brk = fuse.NewGBreaker(gc.Target+method, true)
+		gc.breakers[method] = brk
+	}
+	return brk
+}
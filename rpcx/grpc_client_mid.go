@@ -0,0 +1,214 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package rpcx
+
+import (
+	"context"
+	"github.com/qinchende/gofast/logx"
+	"github.com/qinchende/gofast/skill/fuse"
+	"github.com/qinchende/gofast/skill/timex"
+	"github.com/qinchende/gofast/skill/trace"
+	"go.opentelemetry.io/otel"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 注入链路追踪信息到 metadata，下游服务据此还原 span
+func (gc *GrpcClient) unaryTracing(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if gc.EnableTrack == false {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	ctx, span := startClientSpan(ctx, method, cc.Target())
+	defer span.End()
+
+	trace.MessageSent.Event(ctx, 1, req)
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	endRpcSpan(span, err)
+	if err == nil {
+		trace.MessageReceived.Event(ctx, 1, reply)
+	}
+	return err
+}
+
+// 慢调用和失败调用日志
+func (gc *GrpcClient) unaryLogger(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := timex.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+
+	latency := timex.NowDiff(start)
+	if err != nil {
+		logx.ErrorF("[grpc-cli] %s - %s - %s - %s - %s", cc.Target(), method, status.Code(err), timex.ToStringMS(latency), err)
+	} else if gc.SlowTimeMS > 0 && latency > time.Duration(gc.SlowTimeMS)*time.Millisecond {
+		logx.SlowF("[grpc-cli] %s - %s - slowcall - %s", cc.Target(), method, timex.ToStringMS(latency))
+	}
+	return err
+}
+
+// 下游返回 Unavailable 时重试，熔断器打开的情况直接返回不重试
+func (gc *GrpcClient) unaryRetry(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	wait := time.Duration(gc.RetryMS) * time.Millisecond
+	for i := int32(0); i < gc.Retries && canRetry(err); i++ {
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		wait *= 2
+		err = invoker(ctx, method, req, reply, cc, opts...)
+	}
+	return err
+}
+
+// 熔断，调用失败的 gRPC 状态码作为 Reject 的原因
+func (gc *GrpcClient) unaryBreaker(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	brk := gc.breaker(method)
+	if err := brk.Allow(); err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	if isServerFault(err) {
+		brk.Reject(status.Code(err).String())
+	} else {
+		brk.Accept()
+	}
+	return err
+}
+
+// 调用方没有设置更早的截止时间，就用配置的超时时间
+func (gc *GrpcClient) unaryTimeout(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if gc.TimeoutMS <= 0 {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	timeout := time.Duration(gc.TimeoutMS) * time.Millisecond
+	if dl, ok := ctx.Deadline(); ok && time.Until(dl) < timeout {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 流式调用建立流的时候做熔断，追踪覆盖整个流，直到读到结束或者出错
+func (gc *GrpcClient) streamTracing(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if gc.EnableTrack == false {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+
+	ctx, span := startClientSpan(ctx, method, cc.Target())
+	cs, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		endRpcSpan(span, err)
+		span.End()
+		return nil, err
+	}
+
+	ts := &tracedClientStream{ClientStream: cs, ctx: ctx, span: span}
+	// 调用方取消的流可能不会再调用 RecvMsg，这里兜底结束 span
+	// 流自己结束的时候也会取消 cs.Context()，这时错误由 RecvMsg 或 SendMsg 记录
+	go func() {
+		<-cs.Context().Done()
+		if err := ctx.Err(); err != nil {
+			ts.finish(status.FromContextError(err).Err())
+		}
+	}()
+	return ts, nil
+}
+
+type tracedClientStream struct {
+	grpc.ClientStream
+	ctx       context.Context
+	span      oteltrace.Span
+	once      sync.Once
+	sentId    int32
+	receiveId int32
+}
+
+func (ts *tracedClientStream) SendMsg(m any) error {
+	err := ts.ClientStream.SendMsg(m)
+	if err == nil {
+		trace.MessageSent.Event(ts.ctx, int(atomic.AddInt32(&ts.sentId, 1)), m)
+	} else if err != io.EOF {
+		// 返回 io.EOF 代表流已经结束，真正的错误要从 RecvMsg 拿
+		ts.finish(err)
+	}
+	return err
+}
+
+func (ts *tracedClientStream) RecvMsg(m any) error {
+	err := ts.ClientStream.RecvMsg(m)
+	if err == nil {
+		trace.MessageReceived.Event(ts.ctx, int(atomic.AddInt32(&ts.receiveId, 1)), m)
+	} else if err == io.EOF {
+		ts.finish(nil)
+	} else {
+		ts.finish(err)
+	}
+	return err
+}
+
+func (ts *tracedClientStream) CloseSend() error {
+	err := ts.ClientStream.CloseSend()
+	if err != nil && err != io.EOF {
+		ts.finish(err)
+	}
+	return err
+}
+
+// 只记录第一次结束的结果
+func (ts *tracedClientStream) finish(err error) {
+	ts.once.Do(func() {
+		endRpcSpan(ts.span, err)
+		ts.span.End()
+	})
+}
+
+func (gc *GrpcClient) streamBreaker(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	brk := gc.breaker(method)
+	if err := brk.Allow(); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	cs, err := streamer(ctx, desc, cc, method, opts...)
+	if isServerFault(err) {
+		brk.Reject(status.Code(err).String())
+	} else {
+		brk.Accept()
+	}
+	return cs, err
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+func startClientSpan(ctx context.Context, method, target string) (context.Context, oteltrace.Span) {
+	name, attrs := trace.SpanInfo(method, target)
+	tracer := otel.GetTracerProvider().Tracer(trace.TraceName)
+	ctx, span := tracer.Start(ctx, name, oteltrace.WithSpanKind(oteltrace.SpanKindClient), oteltrace.WithAttributes(attrs...))
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		md = metadata.MD{}
+	} else {
+		md = md.Copy()
+	}
+	trace.Inject(ctx, otel.GetTextMapPropagator(), &md)
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+func canRetry(err error) bool {
+	if status.Code(err) != codes.Unavailable {
+		return false
+	}
+	// 本地熔断器打开的时候，重试没有意义
+	return status.Convert(err).Message() != fuse.ErrServiceUnavailable.Error()
+}
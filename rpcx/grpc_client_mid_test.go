@@ -0,0 +1,144 @@
+package rpcx
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/qinchende/gofast/skill/trace"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	otcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// RecvMsg 按顺序返回 errs 中的错误，ctx 模拟流自己的上下文
+type fakeClientStream struct {
+	grpc.ClientStream
+	ctx  context.Context
+	errs []error
+}
+
+func (fs *fakeClientStream) Context() context.Context {
+	return fs.ctx
+}
+
+func (fs *fakeClientStream) CloseSend() error {
+	return nil
+}
+
+func (fs *fakeClientStream) RecvMsg(any) error {
+	err := fs.errs[0]
+	fs.errs = fs.errs[1:]
+	return err
+}
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	old := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(old) })
+	return sr
+}
+
+func dialLazy(t *testing.T) *grpc.ClientConn {
+	cc, err := grpc.Dial("passthrough:///stream.test", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cc.Close() })
+	return cc
+}
+
+// 打开一个被追踪的流，返回流和控制流上下文的 cancel
+func openTraced(t *testing.T, ctx context.Context, errs ...error) (grpc.ClientStream, context.CancelFunc) {
+	gc := &GrpcClient{GrpcCliCnf: GrpcCliCnf{EnableTrack: true}}
+	sCtx, sCancel := context.WithCancel(ctx)
+	streamer := func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &fakeClientStream{ctx: sCtx, errs: errs}, nil
+	}
+	cs, err := gc.streamTracing(ctx, &grpc.StreamDesc{ServerStreams: true}, dialLazy(t), "/svc/Watch", streamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sCancel)
+	return cs, sCancel
+}
+
+func spanCode(t *testing.T, span sdktrace.ReadOnlySpan) codes.Code {
+	for _, kv := range span.Attributes() {
+		if kv.Key == trace.GRPCStatusCodeKey {
+			return codes.Code(kv.Value.AsInt64())
+		}
+	}
+	t.Fatal("span without grpc status code")
+	return codes.OK
+}
+
+func TestStreamTracingRecvError(t *testing.T) {
+	sr := recordSpans(t)
+	cs, _ := openTraced(t, context.Background(), nil, status.Error(codes.Unavailable, "gone"), io.EOF)
+
+	assert.Nil(t, cs.RecvMsg(nil))
+	assert.Empty(t, sr.Ended())
+	assert.NotNil(t, cs.RecvMsg(nil))
+	assert.Equal(t, io.EOF, cs.RecvMsg(nil))
+
+	// 只记录第一个错误
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, otcodes.Error, spans[0].Status().Code)
+	assert.Equal(t, codes.Unavailable, spanCode(t, spans[0]))
+}
+
+func TestStreamTracingEOF(t *testing.T) {
+	sr := recordSpans(t)
+	cs, sCancel := openTraced(t, context.Background(), nil, io.EOF)
+
+	assert.Nil(t, cs.CloseSend())
+	assert.Nil(t, cs.RecvMsg(nil))
+	assert.Equal(t, io.EOF, cs.RecvMsg(nil))
+	sCancel()
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, otcodes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.OK, spanCode(t, spans[0]))
+}
+
+// 调用方取消之后不再读流，span 也要结束
+func TestStreamTracingCanceled(t *testing.T) {
+	sr := recordSpans(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	openTraced(t, ctx, nil)
+	cancel()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(sr.Ended()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("span not ended after cancel")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, codes.Canceled, spanCode(t, sr.Ended()[0]))
+}
+
+// 流自己结束时由 RecvMsg 记录结果，不能被当作取消
+func TestStreamTracingStreamDone(t *testing.T) {
+	sr := recordSpans(t)
+	cs, sCancel := openTraced(t, context.Background(), status.Error(codes.Internal, "boom"))
+	sCancel()
+	time.Sleep(10 * time.Millisecond)
+	assert.Empty(t, sr.Ended())
+
+	assert.NotNil(t, cs.RecvMsg(nil))
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Internal, spanCode(t, spans[0]))
+}
@@ -0,0 +1,162 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package rpcx
+
+import (
+	"context"
+	"github.com/qinchende/gofast/skill/discov"
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 通过 discov 发现服务的 gRPC 地址格式：discov:///user-svc
+const (
+	DiscovScheme    = "discov"
+	lbNamePrefix    = "gofast_"
+	attrWeightKey   = "weight"
+	serviceCnfLbFmt = `{"loadBalancingConfig":[{"` + lbNamePrefix + `%s":{}}]}`
+)
+
+var discovResolvers sync.Map // name -> discov.Resolver
+
+// 注册服务名对应的 Resolver，GrpcCliCnf.Target 写成 discov:///name 即可使用
+func RegisterDiscov(name string, r discov.Resolver) {
+	discovResolvers.Store(name, r)
+}
+
+func init() {
+	resolver.Register(discovBuilder{})
+	for _, name := range []string{discov.LbRoundRobin, discov.LbWeighted, discov.LbP2C, discov.LbHash} {
+		balancer.Register(lbBuilder{lbName: name})
+	}
+}
+
+// 一致性 hash 负载均衡时，用这个 key 选择实例
+type hashKeyCtx struct{}
+
+func WithHashKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, hashKeyCtx{}, key)
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+type discovBuilder struct{}
+
+func (discovBuilder) Scheme() string {
+	return DiscovScheme
+}
+
+func (discovBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	name := strings.TrimPrefix(target.URL.Path, "/")
+	if name == "" {
+		name = target.URL.Host
+	}
+	val, ok := discovResolvers.Load(name)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "discov: resolver %s not registered", name)
+	}
+
+	dr := &discovResolver{cc: cc}
+	r := val.(discov.Resolver)
+	dr.update(r.Endpoints())
+	dr.unwatch = r.Watch(dr.update)
+	return dr, nil
+}
+
+type discovResolver struct {
+	cc      resolver.ClientConn
+	unwatch func()
+	closed  int32
+}
+
+func (dr *discovResolver) update(eps []discov.Endpoint) {
+	if atomic.LoadInt32(&dr.closed) == 1 {
+		return
+	}
+	addrs := make([]resolver.Address, len(eps))
+	for i, ep := range eps {
+		addrs[i] = resolver.Address{Addr: ep.Addr, Attributes: attributes.New(attrWeightKey, ep.Weight)}
+	}
+	_ = dr.cc.UpdateState(resolver.State{Addresses: addrs})
+}
+
+func (dr *discovResolver) ResolveNow(resolver.ResolveNowOptions) {
+}
+
+// 连接关闭时取消订阅，否则 discov.Resolver 一直引用这个连接，无法回收
+func (dr *discovResolver) Close() {
+	atomic.StoreInt32(&dr.closed, 1)
+	dr.unwatch()
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 每个 ClientConn 单独一个 pickerBuilder，这样每个连接有自己的 discov.Balancer
+type lbBuilder struct {
+	lbName string
+}
+
+func (lb lbBuilder) Name() string {
+	return lbNamePrefix + lb.lbName
+}
+
+func (lb lbBuilder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	pb := &pickerBuilder{bl: discov.NewBalancer(lb.lbName)}
+	return base.NewBalancerBuilder(lb.Name(), pb, base.Config{HealthCheck: true}).Build(cc, opts)
+}
+
+// 每次可用连接变化时，更新 discov.Balancer 的实例列表并构造新的 Picker
+// Balancer 一直复用，P2C 的耗时统计等数据不会因为连接变化而丢失
+type pickerBuilder struct {
+	bl discov.Balancer
+}
+
+func (pb *pickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+
+	conns := make(map[string]balancer.SubConn, len(info.ReadySCs))
+	eps := make([]discov.Endpoint, 0, len(info.ReadySCs))
+	for sc, sci := range info.ReadySCs {
+		ep := discov.Endpoint{Addr: sci.Address.Addr}
+		if w, ok := sci.Address.Attributes.Value(attrWeightKey).(uint16); ok {
+			ep.Weight = w
+		}
+		conns[ep.Addr] = sc
+		eps = append(eps, ep)
+	}
+
+	pb.bl.Update(eps)
+	return &picker{bl: pb.bl, conns: conns}
+}
+
+type picker struct {
+	bl    discov.Balancer
+	conns map[string]balancer.SubConn
+}
+
+func (p *picker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	key, _ := info.Ctx.Value(hashKeyCtx{}).(string)
+	ep, done, err := p.bl.Pick(key)
+	if err != nil {
+		return balancer.PickResult{}, balancer.ErrNoSubConnAvailable
+	}
+	// Balancer 已经更新成新的实例列表，这个旧的 Picker 马上会被替换
+	sc, ok := p.conns[ep.Addr]
+	if !ok {
+		done(nil)
+		return balancer.PickResult{}, balancer.ErrNoSubConnAvailable
+	}
+	return balancer.PickResult{
+		SubConn: sc,
+		Done: func(di balancer.DoneInfo) {
+			done(di.Err)
+		},
+	}, nil
+}
@@ -0,0 +1,55 @@
+package rpcx
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/qinchende/gofast/skill/discov"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/resolver"
+)
+
+// 只记录 UpdateState 的次数，其它方法用不到
+type countCC struct {
+	resolver.ClientConn
+	updates int
+}
+
+func (cc *countCC) UpdateState(resolver.State) error {
+	cc.updates++
+	return nil
+}
+
+// 记录取消订阅的次数
+type unwatchResolver struct {
+	*discov.StaticResolver
+	unwatched int
+}
+
+func (r *unwatchResolver) Watch(fn func([]discov.Endpoint)) func() {
+	unwatch := r.StaticResolver.Watch(fn)
+	return func() {
+		r.unwatched++
+		unwatch()
+	}
+}
+
+func TestDiscovResolverClose(t *testing.T) {
+	sr := &unwatchResolver{StaticResolver: discov.NewStaticResolver(discov.Endpoint{Addr: "10.0.0.1:80"})}
+	RegisterDiscov("close-svc", sr)
+	defer discovResolvers.Delete("close-svc")
+
+	cc := &countCC{}
+	dr, err := discovBuilder{}.Build(resolver.Target{URL: url.URL{Scheme: DiscovScheme, Path: "/close-svc"}}, cc, resolver.BuildOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cc.updates)
+
+	sr.Update([]discov.Endpoint{{Addr: "10.0.0.2:80"}})
+	assert.Equal(t, 2, cc.updates)
+
+	// 关闭之后取消订阅，后面的变化不再通知
+	dr.Close()
+	assert.Equal(t, 1, sr.unwatched)
+	sr.Update([]discov.Endpoint{{Addr: "10.0.0.3:80"}})
+	assert.Equal(t, 2, cc.updates)
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package rpcx
+
+import (
+	"context"
+	"github.com/qinchende/gofast/skill/timex"
+	"github.com/qinchende/gofast/skill/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 流式调用一般是长连接，不做降载和超时拦截，只做计数|追踪|日志|熔断|异常
+type serverStream struct {
+	grpc.ServerStream
+	ctx         context.Context
+	recvCounter int
+	sendCounter int
+}
+
+func (ss *serverStream) Context() context.Context {
+	return ss.ctx
+}
+
+func (ss *serverStream) RecvMsg(m any) error {
+	err := ss.ServerStream.RecvMsg(m)
+	if err == nil {
+		ss.recvCounter++
+		trace.MessageReceived.Event(ss.ctx, ss.recvCounter, m)
+	}
+	return err
+}
+
+func (ss *serverStream) SendMsg(m any) error {
+	err := ss.ServerStream.SendMsg(m)
+	ss.sendCounter++
+	trace.MessageSent.Event(ss.ctx, ss.sendCounter, m)
+	return err
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+func (gs *GrpcServer) streamCount(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	gs.keeper.CountExtras(0)
+	return handler(srv, ss)
+}
+
+func (gs *GrpcServer) streamTracing(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if gs.EnableTrack == false {
+		return handler(srv, ss)
+	}
+
+	ctx, span := startServerSpan(ss.Context(), info.FullMethod)
+	defer span.End()
+
+	err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	endRpcSpan(span, err)
+	return err
+}
+
+func (gs *GrpcServer) streamLogger(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := timex.Now()
+	err := handler(srv, ss)
+	gs.logRequest(ss.Context(), info.FullMethod, start, err)
+	return err
+}
+
+func (gs *GrpcServer) streamBreaker(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	idx, ok := gs.methods[info.FullMethod]
+	if !ok {
+		return handler(srv, ss)
+	}
+
+	brk := gs.keeper.Breaker(idx)
+	if err := brk.Allow(); err != nil {
+		brk.LogError(err)
+		gs.keeper.CountRouteDrop(idx)
+		return status.Error(codes.Unavailable, err.Error())
+	}
+
+	err := handler(srv, ss)
+	if isServerFault(err) {
+		brk.Reject(err.Error())
+	} else {
+		brk.Accept()
+	}
+	gs.keeper.CountRoutePass(idx)
+	return err
+}
+
+func (gs *GrpcServer) streamRecovery(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if pic := recover(); pic != nil {
+			err = recoverError(info.FullMethod, pic)
+		}
+	}()
+	return handler(srv, ss)
+}
@@ -0,0 +1,224 @@
+package rpcx
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/qinchende/gofast/logx"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func init() {
+	logx.MustSetup(&logx.LogConfig{LogMedium: "console", LogLevel: "error", LogStyle: "sdx"})
+}
+
+const (
+	echoSay   = "/test.Echo/Say"
+	echoWatch = "/test.Echo/Watch"
+)
+
+// 手写的服务描述，不依赖 protoc 生成代码。请求内容决定服务端的行为
+type echoServer interface{}
+
+type echoImpl struct{}
+
+func echoHandle(ctx context.Context, msg string) (*wrapperspb.StringValue, error) {
+	switch msg {
+	case "panic":
+		panic("echo boom")
+	case "slow":
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+		}
+	case "fault":
+		return nil, status.Error(codes.Internal, "fault")
+	}
+	return wrapperspb.String("echo:" + msg), nil
+}
+
+var echoDesc = grpc.ServiceDesc{
+	ServiceName: "test.Echo",
+	HandlerType: (*echoServer)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Say",
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := new(wrapperspb.StringValue)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: echoSay}
+			return interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return echoHandle(ctx, req.(*wrapperspb.StringValue).Value)
+			})
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Watch",
+		ServerStreams: true,
+		Handler: func(srv any, ss grpc.ServerStream) error {
+			req := new(wrapperspb.StringValue)
+			if err := ss.RecvMsg(req); err != nil {
+				return err
+			}
+			resp, err := echoHandle(ss.Context(), req.Value)
+			if err != nil {
+				return err
+			}
+			return ss.SendMsg(resp)
+		},
+	}},
+}
+
+// 用内存连接启动服务，返回服务和客户端连接
+func newEchoServer(t *testing.T, cnf *GrpcSrvCnf) (*GrpcServer, *grpc.ClientConn) {
+	if cnf.Name == "" {
+		cnf.Name = "echo"
+	}
+	gs := NewGrpcServer(cnf, func(srv *grpc.Server) {
+		srv.RegisterService(&echoDesc, echoImpl{})
+	})
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = gs.Server().Serve(lis) }()
+	t.Cleanup(gs.Server().Stop)
+
+	cc, err := grpc.Dial("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cc.Close() })
+	return gs, cc
+}
+
+func say(cc *grpc.ClientConn, msg string) (string, error) {
+	reply := new(wrapperspb.StringValue)
+	err := cc.Invoke(context.Background(), echoSay, wrapperspb.String(msg), reply)
+	return reply.Value, err
+}
+
+func watch(cc *grpc.ClientConn, msg string) (string, error) {
+	cs, err := cc.NewStream(context.Background(), &echoDesc.Streams[0], echoWatch)
+	if err != nil {
+		return "", err
+	}
+	if err = cs.SendMsg(wrapperspb.String(msg)); err != nil && err != io.EOF {
+		return "", err
+	}
+	_ = cs.CloseSend()
+	reply := new(wrapperspb.StringValue)
+	if err = cs.RecvMsg(reply); err != nil {
+		return "", err
+	}
+	return reply.Value, nil
+}
+
+// 熔断器几乎全是失败记录，后面的请求大部分会被直接拒绝
+func openBreaker(gs *GrpcServer, method string) {
+	brk := gs.keeper.Breaker(gs.methods[method])
+	for i := 0; i < 1000; i++ {
+		brk.Reject("test")
+	}
+}
+
+func TestGrpcMethodsIndex(t *testing.T) {
+	gs, _ := newEchoServer(t, &GrpcSrvCnf{})
+	assert.Equal(t, map[string]uint16{echoSay: 0, echoWatch: 1}, gs.methods)
+}
+
+func TestGrpcUnaryRecovery(t *testing.T) {
+	_, cc := newEchoServer(t, &GrpcSrvCnf{})
+	_, err := say(cc, "panic")
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "echo boom")
+
+	// 服务没有崩溃，后面的请求正常处理
+	got, err := say(cc, "hi")
+	assert.Nil(t, err)
+	assert.Equal(t, "echo:hi", got)
+}
+
+func TestGrpcUnaryTimeout(t *testing.T) {
+	_, cc := newEchoServer(t, &GrpcSrvCnf{EnableTimeout: true, DefTimeoutMS: 50})
+	start := time.Now()
+	_, err := say(cc, "slow")
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// 关掉超时拦截就一直等到处理完
+	_, cc = newEchoServer(t, &GrpcSrvCnf{EnableTimeout: false, DefTimeoutMS: 50})
+	got, err := say(cc, "slow")
+	assert.Nil(t, err)
+	assert.Equal(t, "echo:slow", got)
+}
+
+func TestGrpcUnaryShedding(t *testing.T) {
+	gs, cc := newEchoServer(t, &GrpcSrvCnf{EnableShedding: true, DefTimeoutMS: 100})
+	_, err := say(cc, "hi")
+	assert.Nil(t, err)
+
+	// 最近的请求几乎都超时，开始降载（单次耗时最多按超时的1.1倍计算）
+	idx := gs.methods[echoSay]
+	for i := 0; i < 20; i++ {
+		gs.keeper.LimiterFinished(idx, 1000, gs.DefTimeoutMS)
+	}
+	_, err = say(cc, "hi")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// 流式调用不做降载
+	got, err := watch(cc, "hi")
+	assert.Nil(t, err)
+	assert.Equal(t, "echo:hi", got)
+}
+
+func TestGrpcUnaryBreaker(t *testing.T) {
+	gs, cc := newEchoServer(t, &GrpcSrvCnf{})
+	// 服务端的错误记入熔断器
+	_, err := say(cc, "fault")
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	openBreaker(gs, echoSay)
+	dropped := 0
+	for i := 0; i < 20; i++ {
+		if _, err = say(cc, "hi"); status.Code(err) == codes.Unavailable {
+			dropped++
+		}
+	}
+	assert.Greater(t, dropped, 0)
+
+	// 每个方法单独熔断
+	got, err := watch(cc, "hi")
+	assert.Nil(t, err)
+	assert.Equal(t, "echo:hi", got)
+}
+
+func TestGrpcStreamChain(t *testing.T) {
+	gs, cc := newEchoServer(t, &GrpcSrvCnf{})
+	_, err := watch(cc, "panic")
+	assert.Equal(t, codes.Internal, status.Code(err))
+	_, err = watch(cc, "fault")
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	openBreaker(gs, echoWatch)
+	dropped := 0
+	for i := 0; i < 20; i++ {
+		if _, err = watch(cc, "hi"); status.Code(err) == codes.Unavailable {
+			dropped++
+		}
+	}
+	assert.Greater(t, dropped, 0)
+	got, err := say(cc, "hi")
+	assert.Nil(t, err)
+	assert.Equal(t, "echo:hi", got)
+}
@@ -0,0 +1,206 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package rpcx
+
+import (
+	"context"
+	"fmt"
+	"github.com/qinchende/gofast/logx"
+	"github.com/qinchende/gofast/skill/timex"
+	"github.com/qinchende/gofast/skill/trace"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/baggage"
+	otcodes "go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"runtime/debug"
+	"time"
+)
+
+// 访问计数
+func (gs *GrpcServer) unaryCount(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	gs.keeper.CountExtras(0)
+	return handler(ctx, req)
+}
+
+// 链路追踪，从 metadata 中还原上游的 span
+func (gs *GrpcServer) unaryTracing(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if gs.EnableTrack == false {
+		return handler(ctx, req)
+	}
+
+	ctx, span := startServerSpan(ctx, info.FullMethod)
+	defer span.End()
+
+	trace.MessageReceived.Event(ctx, 1, req)
+	resp, err := handler(ctx, req)
+	endRpcSpan(span, err)
+	if err == nil {
+		trace.MessageSent.Event(ctx, 1, resp)
+	}
+	return resp, err
+}
+
+// 请求日志
+func (gs *GrpcServer) unaryLogger(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := timex.Now()
+	resp, err := handler(ctx, req)
+	gs.logRequest(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+// 自适应熔断，每个方法有自己单独的熔断器
+func (gs *GrpcServer) unaryBreaker(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	idx, ok := gs.methods[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	brk := gs.keeper.Breaker(idx)
+	if err := brk.Allow(); err != nil {
+		brk.LogError(err)
+		gs.keeper.CountRouteDrop(idx)
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	resp, err := handler(ctx, req)
+	if isServerFault(err) {
+		brk.Reject(err.Error()) // 一次异常返回
+	} else {
+		brk.Accept() // 一次正常请求
+	}
+	return resp, err
+}
+
+// 过载保护
+func (gs *GrpcServer) unaryShedding(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	idx, ok := gs.methods[info.FullMethod]
+	if gs.EnableShedding == false || !ok {
+		return handler(ctx, req)
+	}
+
+	if gs.keeper.LimiterAllow(idx, gs.DefTimeoutMS) {
+		gs.keeper.CountRouteDrop(idx)
+		return nil, status.Error(codes.ResourceExhausted, "LoadShedding!")
+	}
+	return handler(ctx, req)
+}
+
+// 超时自动返回（请求在后台任然继续执行，直到 handler 自己检查 ctx 退出）
+func (gs *GrpcServer) unaryTimeout(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	idx, ok := gs.methods[info.FullMethod]
+	if gs.EnableTimeout == false || gs.DefTimeoutMS <= 0 || !ok {
+		return handler(ctx, req)
+	}
+
+	ctxTimeout, cancelCtx := context.WithTimeout(ctx, time.Duration(gs.DefTimeoutMS)*time.Millisecond)
+	defer cancelCtx()
+
+	var resp any
+	var err error
+	panicChan := make(chan any, 1)
+	finishChan := make(chan struct{})
+	go func() {
+		defer func() {
+			if pic := recover(); pic != nil {
+				// NOTE：这里必须使用带缓冲的通道，否则本G可能因为父G的提前退出，而卡死在这里，导致G泄露
+				panicChan <- pic
+			}
+		}()
+		resp, err = handler(ctxTimeout, req)
+		close(finishChan)
+	}()
+
+	select {
+	case pic := <-panicChan:
+		panic(pic)
+	case <-finishChan:
+		return resp, err
+	case <-ctxTimeout.Done():
+		gs.keeper.CountRouteTimeout(idx)
+		return nil, status.Error(codes.DeadlineExceeded, ctxTimeout.Err().Error())
+	}
+}
+
+// 截获异常，防止程序崩溃
+func (gs *GrpcServer) unaryRecovery(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if pic := recover(); pic != nil {
+			err = recoverError(info.FullMethod, pic)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// 耗时统计
+func (gs *GrpcServer) unaryMetric(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	idx, ok := gs.methods[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	start := timex.Now()
+	defer func() {
+		tm := int32(timex.NowDiffMS(start))
+		gs.keeper.LimiterFinished(idx, tm, gs.DefTimeoutMS)
+		gs.keeper.CountRoutePass2(idx, tm)
+	}()
+	return handler(ctx, req)
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+func startServerSpan(ctx context.Context, fullMethod string) (context.Context, oteltrace.Span) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		md = metadata.MD{}
+	}
+	bags, spanCtx := trace.Extract(ctx, otel.GetTextMapPropagator(), &md)
+	ctx = oteltrace.ContextWithRemoteSpanContext(ctx, spanCtx)
+	ctx = baggage.ContextWithBaggage(ctx, bags)
+
+	name, attrs := trace.SpanInfo(fullMethod, trace.PeerFromCtx(ctx))
+	tracer := otel.GetTracerProvider().Tracer(trace.TraceName)
+	return tracer.Start(ctx, name, oteltrace.WithSpanKind(oteltrace.SpanKindServer), oteltrace.WithAttributes(attrs...))
+}
+
+func endRpcSpan(span oteltrace.Span, err error) {
+	if err == nil {
+		span.SetAttributes(trace.StatusCodeAttr(codes.OK))
+		return
+	}
+	s, _ := status.FromError(err)
+	span.SetStatus(otcodes.Error, s.Message())
+	span.SetAttributes(trace.StatusCodeAttr(s.Code()))
+}
+
+func (gs *GrpcServer) logRequest(ctx context.Context, method string, start time.Duration, err error) {
+	latency := timex.NowDiff(start)
+	code := status.Code(err)
+	if err != nil {
+		logx.ErrorF("[grpc] %s - %s - %s - %s - %s", method, trace.PeerFromCtx(ctx), code, timex.ToStringMS(latency), err)
+		return
+	}
+	if gs.SlowTimeMS > 0 && latency > time.Duration(gs.SlowTimeMS)*time.Millisecond {
+		logx.SlowF("[grpc] %s - %s - slowcall - %s", method, trace.PeerFromCtx(ctx), timex.ToStringMS(latency))
+		return
+	}
+	logx.InfoF("[grpc] %s - %s - %s - %s", method, trace.PeerFromCtx(ctx), code, timex.ToStringMS(latency))
+}
+
+// 只有服务端自身的问题才被认定是拒绝服务，计入熔断
+func isServerFault(err error) bool {
+	switch status.Code(err) {
+	case codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unavailable, codes.DataLoss, codes.Unimplemented, codes.Unknown:
+		return true
+	default:
+		return false
+	}
+}
+
+func recoverError(method string, pic any) error {
+	logx.StackF("[grpc] %s panic: %v\n%s", method, pic, debug.Stack())
+	return status.Error(codes.Internal, fmt.Sprint("panic: ", pic))
+}
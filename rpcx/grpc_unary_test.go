@@ -0,0 +1,30 @@
+package rpcx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsServerFault(t *testing.T) {
+	faults := []codes.Code{codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal,
+		codes.Unavailable, codes.DataLoss, codes.Unimplemented, codes.Unknown}
+	for _, c := range faults {
+		assert.True(t, isServerFault(status.Error(c, "x")), c.String())
+	}
+
+	clients := []codes.Code{codes.OK, codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.FailedPrecondition, codes.Aborted, codes.OutOfRange, codes.Unauthenticated}
+	for _, c := range clients {
+		assert.False(t, isServerFault(status.Error(c, "x")), c.String())
+	}
+
+	assert.False(t, isServerFault(nil))
+	// 不是 grpc status 的错误按 Unknown 处理
+	assert.True(t, isServerFault(errors.New("x")))
+	assert.False(t, isServerFault(status.FromContextError(context.Canceled).Err()))
+}
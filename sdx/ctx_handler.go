@@ -10,9 +10,27 @@ func PmsParser(c *fst.Context) {
 	}
 }
 
-//func JwtAuthHandler(secret string) fst.CtxHandler {
-//	return mid.JwtAuthHandler(secret)
-//}
+// 用 secret 作为默认 HS256 秘钥，所有请求都必须携带合法的 JWT
+// 这里用自己的秘钥验证，不依赖全局的 MyJwt；其它类型的 Session 不算通过验证
+func JwtAuthHandler(secret string) fst.CtxHandler {
+	fst.GFPanicIf(secret == "", "JwtAuthHandler secret can not be empty.")
+	jk := NewJwtKeeper(&JwtCnf{Secret: secret})
+	return func(c *fst.Context) {
+		ss, ok := c.Sess.(*JwtSession)
+		if ok && ss.keeper == jk {
+			return
+		}
+		if c.Sess != nil && !ok {
+			c.AbortFai(110, "Jwt auth error: "+ErrJwtMissing.Error())
+			return
+		}
+		// 没有 Session，或者是别的秘钥构造的 JwtSession，都用自己的秘钥重新验证
+		if ss = jk.buildSession(c); ss.token == "" {
+			c.AbortFai(110, "Jwt auth error: "+ErrJwtMissing.Error())
+		}
+	}
+}
+
 //func BuildPmsOfJson(ctx *fst.Context) {
 //	ctx.GenPmsByJSONBody()
 //}
@@ -41,7 +41,7 @@ func (rb *reqCounter) logPrintReqCounter(data *printData) {
 
 		logx.StatKV(cst.KV{
 			"typ": logx.LogStatRouteReq.Type,
-			"pth": rb.paths[idx],
+			"pth": data.paths[idx],
 			//"fls": []string{"accept", "timeout", "drop", "qps", "ave", "max"}
 			"val": [6]any{rt.accepts, rt.timeouts, rt.drops, lang.Round32(qps, 2), lang.Round32(aveTimeMS, 2), rt.maxTimeMS},
 		})
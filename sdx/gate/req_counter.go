@@ -4,6 +4,7 @@ package gate
 
 import (
 	"sync"
+	"sync/atomic"
 )
 
 type (
@@ -23,15 +24,21 @@ type (
 		total   uint64 // 只记调用次数
 	}
 
+	// 每个路由对应的统计数据，路由只会增加不会减少，热更新时整体替换
+	routeTable struct {
+		paths    []string
+		routes   []*routeCounter // API访问统计
+		breakers []*Breaker      // 不同路径的熔断统计器
+		limiters []*Limiter      // 限制器
+	}
+
 	reqCounter struct {
 		pid  int
 		name string
 
 		rmLock sync.Mutex
+		table  atomic.Value // *routeTable
 
-		// API访问统计
-		paths  []string
-		routes []routeCounter
 		// 其它计数器
 		extraPaths []string
 		extras     []extraCounter
@@ -40,9 +47,15 @@ type (
 	printData struct {
 		extras []extraCounter
 		routes []routeCounter
+		paths  []string
 	}
 )
 
+func (rb *reqCounter) routeTable() *routeTable {
+	tb, _ := rb.table.Load().(*routeTable)
+	return tb
+}
+
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 // 实现 exec.Interval 接口方法，方便对所有请求进行定时统计
 func (rb *reqCounter) AddItem(v any) bool {
@@ -51,13 +64,17 @@ func (rb *reqCounter) AddItem(v any) bool {
 
 // 返回当前容器中的所有数据，同时重置容器
 func (rb *reqCounter) RemoveAll() any {
-	times := uint64(0)
-	tpExtras := make([]extraCounter, len(rb.extras))
-	tpRoutes := make([]routeCounter, len(rb.routes))
-
 	rb.rmLock.Lock()
 	defer rb.rmLock.Unlock()
 
+	tb := rb.routeTable()
+	if tb == nil {
+		return nil
+	}
+	times := uint64(0)
+	tpExtras := make([]extraCounter, len(rb.extras))
+	tpRoutes := make([]routeCounter, len(tb.routes))
+
 	for i := 0; i < len(rb.extras); i++ {
 		ct := &rb.extras[i]
 
@@ -69,8 +86,8 @@ func (rb *reqCounter) RemoveAll() any {
 		times += tpExtras[i].total
 	}
 
-	for i := 0; i < len(rb.routes); i++ {
-		ct := &rb.routes[i]
+	for i := 0; i < len(tb.routes); i++ {
+		ct := tb.routes[i]
 
 		ct.rtLock.Lock()
 		tpRoutes[i].maxTimeMS = ct.maxTimeMS
@@ -97,6 +114,7 @@ func (rb *reqCounter) RemoveAll() any {
 	pack := &printData{
 		extras: tpExtras,
 		routes: tpRoutes,
+		paths:  tb.paths,
 	}
 	return pack
 }
@@ -110,8 +128,9 @@ func (rb *reqCounter) Execute(items any) {
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 // 统计一个通过的请求
 func (rk *RequestKeeper) CountRoutePass2(idx uint16, ms int32) {
+	rk.incMetric(idx, metricTypeAccept)
 	rk.execute.AddByFunc(func(any) (any, bool) {
-		ct := &rk.counter.routes[idx]
+		ct := rk.counter.routeTable().routes[idx]
 
 		ct.rtLock.Lock()
 		ct.accepts++
@@ -126,8 +145,9 @@ func (rk *RequestKeeper) CountRoutePass2(idx uint16, ms int32) {
 }
 
 func (rk *RequestKeeper) CountRoutePass(idx uint16) {
+	rk.incMetric(idx, metricTypeAccept)
 	rk.execute.AddByFunc(func(any) (any, bool) {
-		ct := &rk.counter.routes[idx]
+		ct := rk.counter.routeTable().routes[idx]
 
 		ct.rtLock.Lock()
 		ct.accepts++
@@ -139,8 +159,9 @@ func (rk *RequestKeeper) CountRoutePass(idx uint16) {
 
 // 统计一个处理超时的请求
 func (rk *RequestKeeper) CountRouteTimeout(idx uint16) {
+	rk.incMetric(idx, metricTypeTimeout)
 	rk.execute.AddByFunc(func(any) (any, bool) {
-		ct := &rk.counter.routes[idx]
+		ct := rk.counter.routeTable().routes[idx]
 
 		ct.rtLock.Lock()
 		ct.timeouts++
@@ -152,8 +173,9 @@ func (rk *RequestKeeper) CountRouteTimeout(idx uint16) {
 
 // 统计一个被丢弃的请求
 func (rk *RequestKeeper) CountRouteDrop(idx uint16) {
+	rk.incMetric(idx, metricTypeDrop)
 	rk.execute.AddByFunc(func(any) (any, bool) {
-		ct := &rk.counter.routes[idx]
+		ct := rk.counter.routeTable().routes[idx]
 
 		ct.rtLock.Lock()
 		ct.drops++
@@ -15,7 +15,9 @@ const CountInterval = time.Minute
 type RequestKeeper struct {
 	counter *reqCounter          // 请求统计器
 	execute *exec.IntervalUnsafe // 定时打印统计数据
+	metric  bool                 // 是否同时记入 Prometheus
 
+	// 兼容老的用法，和当前路由表中的一致，路由热更新时会整体替换。请求处理中请用 Breaker(idx) 和 Limiter(idx)
 	Breakers []*Breaker // 不同路径的熔断统计器
 	Limiters []*Limiter // 限制器
 }
@@ -30,20 +32,47 @@ func NewReqKeeper(name string) *RequestKeeper {
 
 // 开启监控统计
 func (rk *RequestKeeper) InitAndRun(routePaths, extraPaths []string) {
-	rk.counter.paths = routePaths                             // 初始化整个路由统计结构
-	rk.counter.routes = make([]routeCounter, len(routePaths)) // 初始化整个路由统计结构
 	rk.counter.extraPaths = extraPaths                        // 其它统计
 	rk.counter.extras = make([]extraCounter, len(extraPaths)) // 其它统计
+	rk.Resize(routePaths)                                     // 初始化整个路由统计结构
+}
+
+// 路由的熔断统计器
+func (rk *RequestKeeper) Breaker(idx uint16) *Breaker {
+	return rk.counter.routeTable().breakers[idx]
+}
 
+// 路由的降载信息收集器
+func (rk *RequestKeeper) Limiter(idx uint16) *Limiter {
+	return rk.counter.routeTable().limiters[idx]
+}
+
+// 按路由列表生成新的统计器、熔断器和降载器，已有路由沿用原来的对象，数据不会丢失
+// 新的路由表整体替换，处理中的请求不受影响，路由热更新时调用
+func (rk *RequestKeeper) Resize(routePaths []string) {
+	ct := rk.counter
+	ct.rmLock.Lock()
+	defer ct.rmLock.Unlock()
+
+	old := ct.routeTable()
+	if old == nil {
+		old = &routeTable{}
+	}
 	routesLen := len(routePaths)
-	// 初始化所有Breaker，每个路由都有自己单独的熔断计数器
-	rk.Breakers = make([]*Breaker, routesLen)
-	for i := 0; i < routesLen; i++ {
-		rk.Breakers[i] = NewBreaker(rk.counter.name + "#" + routePaths[i])
+	tb := &routeTable{
+		paths:    routePaths,
+		routes:   make([]*routeCounter, routesLen),
+		breakers: make([]*Breaker, routesLen),
+		limiters: make([]*Limiter, routesLen),
 	}
-	// 初始化降载信息收集器
-	rk.Limiters = make([]*Limiter, routesLen)
-	for i := 0; i < routesLen; i++ {
-		rk.Limiters[i] = NewLimiter()
+	copy(tb.routes, old.routes)
+	copy(tb.breakers, old.breakers)
+	copy(tb.limiters, old.limiters)
+	for i := len(old.routes); i < routesLen; i++ {
+		tb.routes[i] = new(routeCounter)
+		tb.breakers[i] = NewBreaker(ct.name + "#" + routePaths[i]) // 每个路由都有自己单独的熔断计数器
+		tb.limiters[i] = NewLimiter()
 	}
+	ct.table.Store(tb)
+	rk.Breakers, rk.Limiters = tb.breakers, tb.limiters
 }
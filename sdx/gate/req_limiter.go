@@ -28,7 +28,7 @@ func NewLimiter() *Limiter {
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 // 新增请求数
 func (rk *RequestKeeper) LimiterIncome(idx uint16) {
-	rk.Limiters[idx].sWin.MarkIncome()
+	rk.Limiter(idx).sWin.MarkIncome()
 }
 
 // 记录请求耗时
@@ -37,12 +37,12 @@ func (rk *RequestKeeper) LimiterFinished(idx uint16, ms int32, defMS int32) {
 	if ms > fixMS {
 		ms = fixMS
 	}
-	rk.Limiters[idx].sWin.MarkFinish(int64(ms))
+	rk.Limiter(idx).sWin.MarkFinish(int64(ms))
 }
 
 // 是否允许本次请求通过
 func (rk *RequestKeeper) LimiterAllow(idx uint16, defMS int32) bool {
-	lt := rk.Limiters[idx]
+	lt := rk.Limiter(idx)
 
 	_, finish, totalTimeMS := lt.sWin.CurrWin()
 
@@ -0,0 +1,64 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package gate
+
+import (
+	"github.com/qinchende/gofast/skill/metric"
+	"github.com/qinchende/gofast/skill/sysx"
+)
+
+const gateNamespace = "gate"
+
+const (
+	metricTypeAccept  = "accept"
+	metricTypeTimeout = "timeout"
+	metricTypeDrop    = "drop"
+)
+
+var (
+	metricRouteReqTotal = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: gateNamespace,
+		Subsystem: "route",
+		Name:      "requests_total",
+		Help:      "route requests count by type(accept|timeout|drop).",
+		Labels:    []string{"keeper", "path", "type"},
+	})
+
+	metricBreakerDropRatio = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: gateNamespace,
+		Subsystem: "breaker",
+		Name:      "drop_ratio",
+		Help:      "route breaker drop ratio, 0 means closed.",
+		Labels:    []string{"keeper", "path"},
+	})
+
+	metricSysCpuUsage = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: gateNamespace,
+		Subsystem: "sys",
+		Name:      "cpu_usage",
+		Help:      "cpu usage(percent), cur: last 3 seconds, smooth: smoothed.",
+		Labels:    []string{"type"},
+	})
+)
+
+// 开启 Prometheus 统计，请求计数会同时记入 Prometheus 计数器
+func (rk *RequestKeeper) EnableMetric() {
+	rk.metric = true
+}
+
+// 熔断器和CPU这类状态值，在每次抓取数据之前刷新
+func (rk *RequestKeeper) RefreshMetric() {
+	if tb := rk.counter.routeTable(); tb != nil {
+		for i, brk := range tb.breakers {
+			metricBreakerDropRatio.Set(brk.DropRatio(), rk.counter.name, tb.paths[i])
+		}
+	}
+	metricSysCpuUsage.Set(sysx.CpuCurUsage, "cur")
+	metricSysCpuUsage.Set(sysx.CpuSmoothUsage, "smooth")
+}
+
+func (rk *RequestKeeper) incMetric(idx uint16, typ string) {
+	if rk.metric {
+		metricRouteReqTotal.Inc(rk.counter.name, rk.counter.routeTable().paths[idx], typ)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package sdx
+
+import (
+	"github.com/qinchende/gofast/cst"
+	"github.com/qinchende/gofast/fst"
+	"strings"
+)
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 解析 Authorization: Bearer [token]，验证通过后 claims 作为 c.Sess 的值供后面的 handler 使用
+// 没有携带 token 的请求给一个空的 session，是否必须登录交给 JwtMustLogin 判断
+func JwtBuilder(c *fst.Context) {
+	// 不可重复执行 token 检查，Sess构造的过程
+	if c.Sess != nil {
+		return
+	}
+	fst.GFPanicIf(MyJwt == nil, "Jwt is not setup, call sdx.SetupJwt first.")
+	MyJwt.buildSession(c)
+}
+
+// 用 jk 验证请求中的 token，结果放入 c.Sess；验证失败的请求直接中止
+func (jk *JwtKeeper) buildSession(c *fst.Context) *JwtSession {
+	ss := &JwtSession{values: make(cst.KV)}
+	c.Sess = ss
+	ss.token = jwtFromRequest(c)
+	if ss.token == "" {
+		return ss
+	}
+
+	claims, err := jk.Parse(ss.token)
+	if err != nil {
+		c.AbortFai(110, "Jwt auth error: "+err.Error())
+		return ss
+	}
+	ss.values = claims
+	ss.keeper = jk
+	return ss
+}
+
+// 验证请求是否携带了合法的 token
+func JwtMustLogin(c *fst.Context) {
+	ss, ok := c.Sess.(*JwtSession)
+	if !ok || ss.keeper == nil {
+		c.AbortFai(110, "User login auth error.")
+		return
+	}
+	uid := ss.Get(ss.keeper.GuidField)
+	if uid == nil || uid == "" {
+		c.AbortFai(110, "User login auth error.")
+	}
+}
+
+// 取得当前请求 token 中的所有 claims，没有经过 JwtBuilder 时返回 nil
+func JwtClaims(c *fst.Context) cst.KV {
+	if ss, ok := c.Sess.(*JwtSession); ok {
+		return ss.values
+	}
+	return nil
+}
+
+// 只认请求头 Authorization: Bearer [token]，token 放在 URL 参数中容易被日志和 Referer 泄露
+func jwtFromRequest(c *fst.Context) string {
+	auth := c.GetHeader(jwtHeaderKey)
+	if len(auth) > len(jwtBearerPrefix) && strings.EqualFold(auth[:len(jwtBearerPrefix)], jwtBearerPrefix) {
+		return strings.TrimSpace(auth[len(jwtBearerPrefix):])
+	}
+	return ""
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// JWT 是无状态的，claims 只在当前请求中有效，修改之后需要重新 Sign 签发给客户端
+type JwtSession struct {
+	values cst.KV
+	token  string
+	keeper *JwtKeeper // 验证通过 token 的秘钥配置，nil 表示没有通过验证
+}
+
+// JwtSession 需要实现 sessionKeeper 所有接口
+var _ fst.SessionKeeper = &JwtSession{}
+
+func (ss *JwtSession) GetValues() cst.KV {
+	return ss.values
+}
+
+func (ss *JwtSession) Get(key string) any {
+	if ss.values == nil {
+		return nil
+	}
+	return ss.values[key]
+}
+
+func (ss *JwtSession) Set(key string, val any) {
+	ss.values[key] = val
+}
+
+func (ss *JwtSession) SetKV(kvs cst.KV) {
+	for k, v := range kvs {
+		ss.values[k] = v
+	}
+}
+
+func (ss *JwtSession) Del(key string) {
+	delete(ss.values, key)
+}
+
+func (ss *JwtSession) Save() error {
+	return nil
+}
+
+func (ss *JwtSession) Saved() bool {
+	return true
+}
+
+func (ss *JwtSession) Expire(ttl int32) {
+}
+
+func (ss *JwtSession) SidIsNew() bool {
+	return false
+}
+
+func (ss *JwtSession) Sid() string {
+	return ss.token
+}
+
+func (ss *JwtSession) Destroy() {
+	ss.values = make(cst.KV)
+	ss.token = ""
+	ss.keeper = nil
+}
+
+func (ss *JwtSession) Recreate(c *fst.Context) {
+	ss.Destroy()
+}
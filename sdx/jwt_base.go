@@ -0,0 +1,127 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package sdx
+
+import (
+	"crypto/rsa"
+	"errors"
+	"github.com/qinchende/gofast/fst"
+	"github.com/qinchende/gofast/logx"
+	"github.com/qinchende/gofast/skill/codec"
+	"sync"
+)
+
+const (
+	jwtHeaderKey    = "Authorization" // 携带 token 的请求头
+	jwtBearerPrefix = "Bearer "       // token 前缀
+
+	JwtAlgHS256 = "HS256"
+	JwtAlgHS384 = "HS384"
+	JwtAlgHS512 = "HS512"
+	JwtAlgRS256 = "RS256"
+)
+
+var (
+	ErrJwtMissing    = errors.New("jwt: token missing")
+	ErrJwtFormat     = errors.New("jwt: token format error")
+	ErrJwtAlgorithm  = errors.New("jwt: algorithm not supported")
+	ErrJwtKeyUnknown = errors.New("jwt: signing key not found")
+	ErrJwtSignature  = errors.New("jwt: signature invalid")
+	ErrJwtExpired    = errors.New("jwt: token is expired")
+	ErrJwtNotBefore  = errors.New("jwt: token not valid yet")
+	ErrJwtIssuer     = errors.New("jwt: issuer invalid")
+	ErrJwtAudience   = errors.New("jwt: audience invalid")
+)
+
+type JwtCnf struct {
+	Secret    string `v:""`                     // 默认 HS256 秘钥（token 没有 kid 时使用）
+	Issuer    string `v:""`                     // 不为空时校验 iss
+	Audience  string `v:""`                     // 不为空时校验 aud
+	LeewayS   int64  `v:"def=0,range=[0:3600]"` // exp/nbf 校验允许的时钟偏差（秒）
+	GuidField string `v:"def=uid"`              // 标记当前登录用户的 claim 字段
+	TTL       int64  `v:"def=14400,range=[0:]"` // Sign 签发 token 的默认有效期（秒）
+}
+
+// 签名秘钥，HS 系列用 secret，RS 系列用 RSA 公私钥（私钥只在需要签发 token 时提供）
+type jwtKey struct {
+	alg    string
+	secret []byte
+	pubKey *rsa.PublicKey
+	priKey *rsa.PrivateKey
+}
+
+// 参数配置，所有签名秘钥按 kid 索引，方便秘钥轮换
+type JwtKeeper struct {
+	JwtCnf
+	keys   map[string]*jwtKey
+	keysMu sync.RWMutex
+}
+
+// 每个进程只有一个全局 Jwt 配置对象
+var MyJwt *JwtKeeper
+
+func NewJwtKeeper(cnf *JwtCnf) *JwtKeeper {
+	jk := &JwtKeeper{JwtCnf: *cnf, keys: make(map[string]*jwtKey)}
+	if jk.GuidField == "" {
+		jk.GuidField = "uid"
+	}
+	if jk.Secret != "" {
+		_ = jk.AddHmacKey("", JwtAlgHS256, jk.Secret)
+	}
+	return jk
+}
+
+// 采用 JWT 无状态认证的时候需要先初始化参数
+// 可以重复调用，后面的配置覆盖前面的。比如 SuperHandlers 按 JwtSecret 初始化了 HS256，应用再换成 RS256 的配置
+func SetupJwt(jk *JwtKeeper) {
+	fst.GFPanicIf(jk == nil, "Jwt keeper is nil.")
+	if MyJwt != nil && MyJwt != jk {
+		logx.Warn("Jwt setup again, the new config replaces the old one.")
+	}
+	MyJwt = jk
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 添加 HS256|HS384|HS512 秘钥，kid 为空表示默认秘钥
+func (jk *JwtKeeper) AddHmacKey(kid, alg, secret string) error {
+	if hmacHash(alg) == nil || secret == "" {
+		return ErrJwtAlgorithm
+	}
+	jk.setKey(kid, &jwtKey{alg: alg, secret: []byte(secret)})
+	return nil
+}
+
+// 添加 RS256 秘钥，pubPem 用于验证，priPem 可以为空（只验证不签发）
+func (jk *JwtKeeper) AddRsaKey(kid string, pubPem, priPem []byte) error {
+	pubKey, err := codec.ParseRsaPublicKey(pubPem)
+	if err != nil {
+		return err
+	}
+	key := &jwtKey{alg: JwtAlgRS256, pubKey: pubKey}
+	if len(priPem) > 0 {
+		if key.priKey, err = codec.ParseRsaPrivateKey(priPem); err != nil {
+			return err
+		}
+	}
+	jk.setKey(kid, key)
+	return nil
+}
+
+// 秘钥轮换的时候，删除已经下线的秘钥
+func (jk *JwtKeeper) DelKey(kid string) {
+	jk.keysMu.Lock()
+	delete(jk.keys, kid)
+	jk.keysMu.Unlock()
+}
+
+func (jk *JwtKeeper) setKey(kid string, key *jwtKey) {
+	jk.keysMu.Lock()
+	jk.keys[kid] = key
+	jk.keysMu.Unlock()
+}
+
+func (jk *JwtKeeper) getKey(kid string) *jwtKey {
+	jk.keysMu.RLock()
+	defer jk.keysMu.RUnlock()
+	return jk.keys[kid]
+}
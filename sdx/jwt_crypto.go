@@ -0,0 +1,199 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package sdx
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"github.com/qinchende/gofast/cst"
+	"github.com/qinchende/gofast/skill/jsonx"
+	"github.com/qinchende/gofast/skill/lang"
+	"hash"
+	"strings"
+	"time"
+)
+
+var jwtEncoding = base64.RawURLEncoding
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+func hmacHash(alg string) func() hash.Hash {
+	switch alg {
+	case JwtAlgHS256:
+		return sha256.New
+	case JwtAlgHS384:
+		return sha512.New384
+	case JwtAlgHS512:
+		return sha512.New
+	}
+	return nil
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// tok=[header].[claims].[signature] 验证签名以及 exp|nbf|iss|aud，返回所有 claims
+func (jk *JwtKeeper) Parse(tok string) (cst.KV, error) {
+	parts := strings.Split(tok, ".")
+	if len(parts) != 3 {
+		return nil, ErrJwtFormat
+	}
+
+	var hd jwtHeader
+	if err := jwtDecodeSegment(parts[0], &hd); err != nil {
+		return nil, ErrJwtFormat
+	}
+	key := jk.getKey(hd.Kid)
+	if key == nil {
+		return nil, ErrJwtKeyUnknown
+	}
+	// 算法必须和秘钥绑定的算法一致，不能由 token 自己决定（防止算法混淆攻击）
+	if hd.Alg != key.alg {
+		return nil, ErrJwtAlgorithm
+	}
+	sig, err := jwtEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrJwtFormat
+	}
+	if !key.verify(parts[0]+"."+parts[1], sig) {
+		return nil, ErrJwtSignature
+	}
+
+	claims := make(cst.KV)
+	if err = jwtDecodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrJwtFormat
+	}
+	if err = jk.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// 用 kid 对应的秘钥签发 token，claims 中没有 exp 时按 TTL 自动补上
+func (jk *JwtKeeper) Sign(kid string, claims cst.KV) (string, error) {
+	key := jk.getKey(kid)
+	if key == nil {
+		return "", ErrJwtKeyUnknown
+	}
+
+	// 不修改调用者传入的 claims
+	cls := make(cst.KV, len(claims)+3)
+	for k, v := range claims {
+		cls[k] = v
+	}
+	claims = cls
+
+	now := time.Now().Unix()
+	if _, ok := claims["iat"]; !ok {
+		claims["iat"] = now
+	}
+	if _, ok := claims["exp"]; !ok && jk.TTL > 0 {
+		claims["exp"] = now + jk.TTL
+	}
+	if _, ok := claims["iss"]; !ok && jk.Issuer != "" {
+		claims["iss"] = jk.Issuer
+	}
+
+	hdBytes, err := jsonx.Marshal(jwtHeader{Alg: key.alg, Typ: "JWT", Kid: kid})
+	if err != nil {
+		return "", err
+	}
+	clBytes, err := jsonx.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signing := jwtEncoding.EncodeToString(hdBytes) + "." + jwtEncoding.EncodeToString(clBytes)
+	sig, err := key.sign(signing)
+	if err != nil {
+		return "", err
+	}
+	return signing + "." + jwtEncoding.EncodeToString(sig), nil
+}
+
+// exp|nbf 是 NumericDate，按规范可以带小数
+func (jk *JwtKeeper) checkClaims(claims cst.KV) error {
+	now := float64(time.Now().Unix())
+	leeway := float64(jk.LeewayS)
+	if v, ok := claims["exp"]; ok {
+		exp, err := lang.ToFloat64(v)
+		if err != nil {
+			return ErrJwtFormat
+		}
+		if now > exp+leeway {
+			return ErrJwtExpired
+		}
+	}
+	if v, ok := claims["nbf"]; ok {
+		nbf, err := lang.ToFloat64(v)
+		if err != nil {
+			return ErrJwtFormat
+		}
+		if now+leeway < nbf {
+			return ErrJwtNotBefore
+		}
+	}
+	if jk.Issuer != "" && claims["iss"] != jk.Issuer {
+		return ErrJwtIssuer
+	}
+	if jk.Audience != "" && !jwtHasAudience(claims["aud"], jk.Audience) {
+		return ErrJwtAudience
+	}
+	return nil
+}
+
+// aud 可以是单个字符串，也可以是字符串数组
+func jwtHasAudience(aud any, want string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == want
+	case []any:
+		for _, item := range v {
+			if item == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jwtDecodeSegment(seg string, v any) error {
+	bs, err := jwtEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return jsonx.UnmarshalFromString(v, lang.BytesToString(bs))
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+func (key *jwtKey) verify(signing string, sig []byte) bool {
+	if hf := hmacHash(key.alg); hf != nil {
+		mac := hmac.New(hf, key.secret)
+		mac.Write([]byte(signing))
+		return hmac.Equal(sig, mac.Sum(nil))
+	}
+	if key.alg == JwtAlgRS256 && key.pubKey != nil {
+		sum := sha256.Sum256([]byte(signing))
+		return rsa.VerifyPKCS1v15(key.pubKey, crypto.SHA256, sum[:], sig) == nil
+	}
+	return false
+}
+
+func (key *jwtKey) sign(signing string) ([]byte, error) {
+	if hf := hmacHash(key.alg); hf != nil {
+		mac := hmac.New(hf, key.secret)
+		mac.Write([]byte(signing))
+		return mac.Sum(nil), nil
+	}
+	if key.alg == JwtAlgRS256 && key.priKey != nil {
+		sum := sha256.Sum256([]byte(signing))
+		return rsa.SignPKCS1v15(rand.Reader, key.priKey, crypto.SHA256, sum[:])
+	}
+	return nil, ErrJwtAlgorithm
+}
@@ -0,0 +1,222 @@
+package sdx
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/qinchende/gofast/cst"
+	"github.com/qinchende/gofast/logx"
+	"github.com/qinchende/gofast/skill/jsonx"
+	"strings"
+	"testing"
+	"time"
+)
+
+func init() {
+	logx.MustSetup(&logx.LogConfig{LogMedium: "console", LogLevel: "error", LogStyle: "sdx"})
+}
+
+func testRsaPem(t *testing.T) (pubPem, priPem []byte) {
+	priKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubBytes, err := x509.MarshalPKIXPublicKey(&priKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPem = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes})
+	priPem = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priKey)})
+	return
+}
+
+// 不经过 JwtKeeper 直接拼一个 token，secret 为 nil 时签名为空，否则用 secret 做 HS256 签名
+func rawJwt(t *testing.T, hd jwtHeader, claims cst.KV, secret []byte) string {
+	hdBytes, err := jsonx.Marshal(hd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clBytes, err := jsonx.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signing := jwtEncoding.EncodeToString(hdBytes) + "." + jwtEncoding.EncodeToString(clBytes)
+	if secret == nil {
+		return signing + "."
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signing))
+	return signing + "." + jwtEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestJwtRoundTrip(t *testing.T) {
+	pubPem, priPem := testRsaPem(t)
+	jk := NewJwtKeeper(&JwtCnf{Secret: "hs-secret", TTL: 60})
+	if err := jk.AddRsaKey("rk", pubPem, priPem); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, kid := range []string{"", "rk"} {
+		tok, err := jk.Sign(kid, cst.KV{"uid": "u1"})
+		if err != nil {
+			t.Fatalf("kid %q sign: %v", kid, err)
+		}
+		claims, err := jk.Parse(tok)
+		if err != nil {
+			t.Fatalf("kid %q parse: %v", kid, err)
+		}
+		if claims["uid"] != "u1" || claims["exp"] == nil || claims["iat"] == nil {
+			t.Fatalf("kid %q claims %v", kid, claims)
+		}
+	}
+
+	// 只有公钥的时候只能验证，不能签发
+	verifier := NewJwtKeeper(&JwtCnf{})
+	if err := verifier.AddRsaKey("rk", pubPem, nil); err != nil {
+		t.Fatal(err)
+	}
+	tok, _ := jk.Sign("rk", cst.KV{"uid": "u1"})
+	if _, err := verifier.Parse(tok); err != nil {
+		t.Fatalf("verify only: %v", err)
+	}
+	if _, err := verifier.Sign("rk", nil); !errors.Is(err, ErrJwtAlgorithm) {
+		t.Fatalf("sign without private key: %v", err)
+	}
+}
+
+// token 不能自己决定算法：alg=none 和用 RSA 公钥当 HMAC 秘钥签名的 token 都要拒绝
+func TestJwtAlgConfusion(t *testing.T) {
+	pubPem, _ := testRsaPem(t)
+	jk := NewJwtKeeper(&JwtCnf{Secret: "hs-secret"})
+	if err := jk.AddRsaKey("rk", pubPem, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []string{
+		rawJwt(t, jwtHeader{Alg: "none"}, cst.KV{"uid": "u1"}, nil),
+		rawJwt(t, jwtHeader{Alg: "none", Kid: "rk"}, cst.KV{"uid": "u1"}, nil),
+		rawJwt(t, jwtHeader{Alg: JwtAlgHS256, Kid: "rk"}, cst.KV{"uid": "u1"}, pubPem),
+		rawJwt(t, jwtHeader{Alg: JwtAlgRS256}, cst.KV{"uid": "u1"}, []byte("hs-secret")),
+	}
+	for _, tok := range cases {
+		if _, err := jk.Parse(tok); !errors.Is(err, ErrJwtAlgorithm) {
+			t.Errorf("token %s: got %v, want ErrJwtAlgorithm", tok, err)
+		}
+	}
+}
+
+func TestJwtKid(t *testing.T) {
+	jk := NewJwtKeeper(&JwtCnf{})
+	_ = jk.AddHmacKey("k1", JwtAlgHS256, "secret-1")
+	_ = jk.AddHmacKey("k2", JwtAlgHS512, "secret-2")
+	if err := jk.AddHmacKey("k3", "HS128", "x"); !errors.Is(err, ErrJwtAlgorithm) {
+		t.Fatalf("bad alg: %v", err)
+	}
+
+	tok1, _ := jk.Sign("k1", cst.KV{"uid": "u1"})
+	tok2, _ := jk.Sign("k2", cst.KV{"uid": "u2"})
+	if c, err := jk.Parse(tok1); err != nil || c["uid"] != "u1" {
+		t.Fatalf("k1: %v %v", c, err)
+	}
+	if c, err := jk.Parse(tok2); err != nil || c["uid"] != "u2" {
+		t.Fatalf("k2: %v %v", c, err)
+	}
+
+	// 没有默认秘钥，kid 未知
+	if _, err := jk.Sign("", nil); !errors.Is(err, ErrJwtKeyUnknown) {
+		t.Fatalf("sign unknown kid: %v", err)
+	}
+	if _, err := jk.Parse(rawJwt(t, jwtHeader{Alg: JwtAlgHS256, Kid: "k9"}, cst.KV{}, []byte("secret-1"))); !errors.Is(err, ErrJwtKeyUnknown) {
+		t.Fatalf("parse unknown kid: %v", err)
+	}
+
+	// 秘钥下线之后，它签发的 token 不能再通过
+	jk.DelKey("k1")
+	if _, err := jk.Parse(tok1); !errors.Is(err, ErrJwtKeyUnknown) {
+		t.Fatalf("deleted kid: %v", err)
+	}
+}
+
+func TestJwtClaims(t *testing.T) {
+	secret := []byte("hs-secret")
+	now := time.Now().Unix()
+	jk := NewJwtKeeper(&JwtCnf{Secret: string(secret), Issuer: "gf", Audience: "app", LeewayS: 5})
+
+	cases := []struct {
+		claims cst.KV
+		err    error
+	}{
+		{cst.KV{"iss": "gf", "aud": "app"}, nil},
+		{cst.KV{"iss": "gf", "aud": []string{"web", "app"}}, nil},
+		{cst.KV{"iss": "gf", "aud": "app", "exp": now + 60, "nbf": now - 60}, nil},
+		{cst.KV{"iss": "gf", "aud": "app", "exp": now - 3}, nil}, // 在允许的时钟偏差内
+		{cst.KV{"iss": "gf", "aud": "app", "exp": float64(now) - 60.5}, ErrJwtExpired},
+		{cst.KV{"iss": "gf", "aud": "app", "nbf": now + 60}, ErrJwtNotBefore},
+		{cst.KV{"iss": "gf", "aud": "app", "exp": "tomorrow"}, ErrJwtFormat},
+		{cst.KV{"iss": "other", "aud": "app"}, ErrJwtIssuer},
+		{cst.KV{"aud": "app"}, ErrJwtIssuer},
+		{cst.KV{"iss": "gf", "aud": "web"}, ErrJwtAudience},
+		{cst.KV{"iss": "gf", "aud": []string{"web"}}, ErrJwtAudience},
+		{cst.KV{"iss": "gf"}, ErrJwtAudience},
+	}
+	for _, c := range cases {
+		_, err := jk.Parse(rawJwt(t, jwtHeader{Alg: JwtAlgHS256}, c.claims, secret))
+		if !errors.Is(err, c.err) {
+			t.Errorf("claims %v: got %v, want %v", c.claims, err, c.err)
+		}
+	}
+
+	// Sign 自动补上 iss 和 exp
+	jk.TTL = 60
+	tok, _ := jk.Sign("", cst.KV{"aud": "app"})
+	if c, err := jk.Parse(tok); err != nil || c["iss"] != "gf" {
+		t.Fatalf("signed claims %v: %v", c, err)
+	}
+}
+
+func TestJwtTampered(t *testing.T) {
+	jk := NewJwtKeeper(&JwtCnf{Secret: "hs-secret"})
+	tok, _ := jk.Sign("", cst.KV{"uid": "u1"})
+
+	// 改签名中的一个字节，改 claims，或者换一个秘钥签名
+	dot := strings.LastIndexByte(tok, '.')
+	sig, _ := jwtEncoding.DecodeString(tok[dot+1:])
+	sig[0] ^= 0xff
+	sigFlip := tok[:dot+1] + jwtEncoding.EncodeToString(sig)
+	forged := rawJwt(t, jwtHeader{Alg: JwtAlgHS256, Typ: "JWT"}, cst.KV{"uid": "admin"}, []byte("other"))
+	cases := map[string]error{
+		sigFlip: ErrJwtSignature,
+		forged:  ErrJwtSignature,
+		rawJwt(t, jwtHeader{Alg: JwtAlgHS256}, cst.KV{"uid": "admin"}, nil): ErrJwtSignature,
+		"a.b":                  ErrJwtFormat,
+		"!!.e30.AAAA":          ErrJwtFormat,
+		tok[:len(tok)-1] + "*": ErrJwtFormat,
+	}
+	for tk, want := range cases {
+		if _, err := jk.Parse(tk); !errors.Is(err, want) {
+			t.Errorf("token %q: got %v, want %v", tk, err, want)
+		}
+	}
+}
+
+// SuperHandlers 按 JwtSecret 初始化之后，应用还可以换成自己的配置
+func TestSetupJwtOverride(t *testing.T) {
+	old := MyJwt
+	defer func() { MyJwt = old }()
+
+	pubPem, priPem := testRsaPem(t)
+	hs := NewJwtKeeper(&JwtCnf{Secret: "hs-secret"})
+	rs := NewJwtKeeper(&JwtCnf{})
+	if err := rs.AddRsaKey("", pubPem, priPem); err != nil {
+		t.Fatal(err)
+	}
+	SetupJwt(hs)
+	SetupJwt(rs)
+	if MyJwt != rs {
+		t.Fatal("second SetupJwt should replace the first one")
+	}
+}
@@ -4,6 +4,7 @@ package mid
 
 import (
 	"github.com/qinchende/gofast/cst"
+	"sync/atomic"
 )
 
 type (
@@ -19,19 +20,26 @@ type (
 	allAttrs []*Attrs // 高级功能：每项路由可选配置，精准控制
 )
 
-var AllAttrs allAttrs // 所有配置项汇总
+var (
+	AllAttrs   allAttrs     // 所有配置项汇总，Rebuild 之后按路由索引排好
+	routeAttrs atomic.Value // AllAttrs 的快照，请求中只读这个，路由热更新时整体替换
+)
 
 func (ras *Attrs) SetRouteIndex(routeIdx uint16) {
 	ras.RIndex = routeIdx
 	AllAttrs = append(AllAttrs, ras)
 }
 
+// 路由对应的配置，必须先调用 Rebuild
+func RouteAttrs(routeIdx uint16) *Attrs {
+	return routeAttrs.Load().(allAttrs)[routeIdx]
+}
+
 // 构建所有路由的属性数组。没有指定的就用默认值填充。
 func (*allAttrs) Rebuild(routesLen uint16, cnf *cst.SdxConfig) {
-	old := AllAttrs
-	AllAttrs = make(allAttrs, routesLen)
-	for _, it := range old {
-		AllAttrs[it.RIndex] = it
+	attrs := make(allAttrs, routesLen)
+	for _, it := range AllAttrs {
+		attrs[it.RIndex] = it
 	}
 
 	defAttrs := Attrs{
@@ -40,9 +48,14 @@ func (*allAttrs) Rebuild(routesLen uint16, cnf *cst.SdxConfig) {
 		//MaxReq:    1000000,
 		//BreakRate: 1.5,
 	}
-	for idx, it := range AllAttrs {
+	for idx, it := range attrs {
 		if it == nil {
-			AllAttrs[idx] = &defAttrs
+			// 每个路由一份默认配置，带上自己的索引，热更新再次 Rebuild 时位置不会错
+			def := defAttrs
+			def.RIndex = uint16(idx)
+			attrs[idx] = &def
 		}
 	}
+	AllAttrs = attrs
+	routeAttrs.Store(attrs)
 }
@@ -3,8 +3,12 @@
 package mid
 
 import (
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/qinchende/gofast/fst"
+	"github.com/qinchende/gofast/sdx/gate"
 	"github.com/qinchende/gofast/skill/metric"
+	"github.com/qinchende/gofast/skill/timex"
+	"strconv"
 )
 
 const serverNamespace = "http_server"
@@ -28,26 +32,23 @@ var (
 	})
 )
 
-//func PrometheusHandler(path string) func(http.Handler) http.Handler {
-//	return func(next http.Handler) http.Handler {
-//		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-//			startTime := timex.Now()
-//			cw := &security.WithCodeResponseWriter{Writer: w}
-//			defer func() {
-//				metricServerReqDur.Observe(int64(timex.Since(startTime)/time.Millisecond), path)
-//				metricServerReqCodeTotal.Inc(path, strconv.Itoa(cw.Code))
-//			}()
-//
-//			next.ServeHTTP(cw, r)
-//		})
-//	}
-//}
-
+// 按路由统计请求耗时和返回状态码，路由用 c.FullPath() 作为标签，避免 url 参数造成标签爆炸
 func Prometheus(c *fst.Context) {
-	//startTime := timex.Now()
-	//cw := &security.WithCodeResponseWriter{Writer: w}
-	//defer func() {
-	// metricServerReqDur.Observe(int64(timex.Since(startTime)/time.Millisecond), path)
-	// metricServerReqCodeTotal.Inc(path, strconv.Itoa(cw.Code))
-	//}()
+	defer func() {
+		path := c.FullPath()
+		metricServerReqDur.Observe(timex.NowDiffMS(c.EnterTime), path)
+		metricServerReqCodeTotal.Inc(path, strconv.Itoa(c.ResWrap.Status()))
+	}()
+
+	c.Next()
+}
+
+// 提供给 Prometheus 抓取数据的路由，抓取之前刷新熔断器和CPU等状态值
+func PrometheusMetrics(kp *gate.RequestKeeper) fst.CtxHandler {
+	hd := promhttp.Handler()
+	return func(c *fst.Context) {
+		kp.RefreshMetric()
+		hd.ServeHTTP(c.ResWrap, c.ReqRaw)
+		_, _ = c.ResWrap.Send()
+	}
 }
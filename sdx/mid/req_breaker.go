@@ -16,7 +16,7 @@ func Breaker(kp *gate.RequestKeeper) fst.CtxHandler {
 
 	return func(c *fst.Context) {
 		// 检查是否允许本次访问通过，主要是滑动窗口判断是否达到熔断条件
-		brk := kp.Breakers[c.RouteIdx]
+		brk := kp.Breaker(c.RouteIdx)
 		// 有错误信息返回，证明本次请求被熔断，接下来：
 		// 1. 本次记入丢弃请求统计  2. 打印错误信息  3. 返回服务器出错
 		if err := brk.Allow(); err != nil {
@@ -15,6 +15,10 @@ func SuperHandlers(app *fst.GoFast) *fst.GoFast {
 
 	// 初始化一个全局的 请求管理器（记录访问数据，分析统计，限流降载熔断，定时日志）
 	keeper := gate.NewReqKeeper(app.ProjectName())
+	// 配置了 JwtSecret，就初始化全局的 JWT 验证秘钥，路由上使用 sdx.JwtBuilder | sdx.JwtMustLogin
+	if len(cnf.JwtSecret) > 0 {
+		SetupJwt(NewJwtKeeper(&JwtCnf{Secret: cnf.JwtSecret}))
+	}
 	app.OnBeforeBuildRoutes(func(app *fst.GoFast) {
 		// 因为Routes的数量只能在加载完所有路由之后才知道,所以这里选择延时构造所有Breakers
 		mid.AllAttrs.Rebuild(app.RoutesLen(), &cnf) // 所有路由配置
@@ -24,6 +28,11 @@ func SuperHandlers(app *fst.GoFast) *fst.GoFast {
 		extraPaths := []string{"AllRequest", "RouteMatched", "LoadShedding"}
 		keeper.InitAndRun(routePaths, extraPaths) // 看守上岗
 	})
+	// 热更新路由之后，新增的路由也要有对应的配置和统计器
+	app.OnReloadRoutes(func(app *fst.GoFast) {
+		mid.AllAttrs.Rebuild(app.RoutesLen(), &cnf)
+		keeper.Resize(app.RoutePathsWithMethod())
+	})
 
 	// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 	// 第一级：HttpHandlers
@@ -47,6 +56,13 @@ func SuperHandlers(app *fst.GoFast) *fst.GoFast {
 	app.Before(mid.MaxContentLength)                            // 分路由判断请求长度
 	app.Before(mid.Gunzip(cnf.EnableGunzip))                    // 自动 gunzip 解压缩
 
+	// Prometheus 统计，同时注册抓取数据的路由
+	if cnf.EnableMetrics {
+		keeper.EnableMetric()
+		app.Before(mid.Prometheus)
+		app.Get(cnf.MetricsPath, mid.PrometheusMetrics(keeper))
+	}
+
 	// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 	// 特殊路由的处理链
 	// 正确匹配路由之外的情况，比如特殊的404,504等路由处理链
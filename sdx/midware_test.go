@@ -0,0 +1,51 @@
+package sdx
+
+import (
+	"github.com/qinchende/gofast/fst"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// 开启 EnableMetrics 之后，请求经过整个处理链，抓取路由能拿到按路由统计的计数和耗时
+func TestSuperHandlersMetrics(t *testing.T) {
+	app := fst.Default()
+	app.SdxConfig.EnableMetrics = true
+	app.SdxConfig.MetricsPath = "/metrics"
+	SuperHandlers(app)
+	app.Get("/pets/:id", func(c *fst.Context) {
+		c.String(http.StatusOK, "pet")
+	})
+	app.BuildRoutes()
+
+	for _, p := range []string{"/pets/1", "/pets/2"} {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, p, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s got %d", p, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("scrape got %d", w.Code)
+	}
+	body := w.Body.String()
+	// 标签用路由模板，不是请求的 url
+	for _, line := range []string{
+		`http_server_requests_code_total{code="200",path="/pets/:id"} 2`,
+		`http_server_requests_duration_ms_bucket{path="/pets/:id",le="+Inf"} 2`,
+		`http_server_requests_duration_ms_count{path="/pets/:id"} 2`,
+		`gate_route_requests_total{keeper="",path="GET@/pets/:id",type="accept"} 2`,
+		`gate_breaker_drop_ratio{keeper="",path="GET@/pets/:id"} 0`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics missing %s", line)
+		}
+	}
+	if strings.Contains(body, `path="/pets/1"`) {
+		t.Error("request url used as metric label")
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package openapi
+
+import "github.com/qinchende/gofast/fst"
+
+// 根据注册的路由自动生成 OpenAPI 3 文档，路由上用 Attrs 挂载请求和返回的结构体：
+// app.Post("/user", handler).Attrs(&openapi.Doc{Summary: "新增用户", Req: UserReq{}, Resp: User{}})
+// 请求结构体中 pms 标签是参数名，v 标签（required,enum,range,len,regex,match,def）转换成 Schema 的约束
+// 返回结构体按 JSON 序列化的规则取字段名（json 标签）
+// 没有挂载 Doc 的路由也会出现在文档中，只是没有参数和返回的说明
+type Doc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Req         any  // 请求参数结构体，GET|DELETE|HEAD|OPTIONS 转换成 query 参数，其它的转换成 body
+	Resp        any  // SucData 返回的 data 结构
+	RawResp     bool // Resp 就是整个返回体，不用 status|code|msg|data 包装（比如用 c.Json 返回的）
+	Deprecated  bool
+	Hidden      bool // 不出现在文档中
+}
+
+// 实现 fst.RouteAttrs 接口，Doc 挂在路由上，生成文档时从 fst.RouteInfo.Attrs 中取出
+func (d *Doc) SetRouteIndex(uint16) {
+}
+
+func docOf(ri *fst.RouteInfo) *Doc {
+	for _, ra := range ri.Attrs {
+		if d, ok := ra.(*Doc); ok {
+			return d
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package openapi
+
+import (
+	"github.com/qinchende/gofast/fst"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// OpenAPI 支持的 method，其它自定义的 method 不出现在文档中
+var docMethods = map[string]bool{
+	http.MethodGet: true, http.MethodPost: true, http.MethodPut: true, http.MethodDelete: true,
+	http.MethodPatch: true, http.MethodHead: true, http.MethodOptions: true, http.MethodTrace: true,
+}
+
+// 这些 method 的请求参数放在 query 中，其它的放在 body 中
+var queryMethods = map[string]bool{
+	http.MethodGet: true, http.MethodDelete: true, http.MethodHead: true, http.MethodOptions: true,
+}
+
+// 路由参数约束对应的 Schema，见 fst/tree_param.go
+var paramTypes = map[string]func() *Schema{
+	"int":   func() *Schema { return &Schema{Type: "integer", Format: "int64"} },
+	"uint":  func() *Schema { return &Schema{Type: "integer", Format: "int64", Minimum: new(float64)} },
+	"alpha": func() *Schema { return &Schema{Type: "string", Pattern: "^[a-zA-Z]+$"} },
+	"alnum": func() *Schema { return &Schema{Type: "string", Pattern: "^[a-zA-Z0-9]+$"} },
+	"hex":   func() *Schema { return &Schema{Type: "string", Pattern: "^[0-9a-fA-F]+$"} },
+	"uuid":  func() *Schema { return &Schema{Type: "string", Format: "uuid"} },
+}
+
+// 一个路由可能对应多个文档路径（可选参数）
+type docPath struct {
+	path   string
+	params []*Parameter
+}
+
+// 根据当前注册的所有路由生成文档，必须在 BuildRoutes 之前调用（之后原始路由信息可能已经释放）
+func Build(app *fst.GoFast, info Info, servers ...Server) *Spec {
+	sp := &Spec{
+		OpenAPI:   specVersion,
+		Info:      info,
+		Servers:   servers,
+		Paths:     make(map[string]PathItem),
+		schemas:   make(map[string]*Schema),
+		typeNames: make(map[string]string),
+	}
+
+	tags := make(map[string]bool)
+	for _, ri := range app.RouteInfos() {
+		doc := docOf(&ri)
+		if (doc != nil && doc.Hidden) || !docMethods[ri.Method] {
+			continue
+		}
+		sp.addRoute(&ri, doc)
+		if doc != nil {
+			for _, t := range doc.Tags {
+				tags[t] = true
+			}
+		}
+	}
+
+	if len(sp.schemas) > 0 {
+		sp.Components = &Components{Schemas: sp.schemas}
+	}
+	for t := range tags {
+		sp.Tags = append(sp.Tags, Tag{Name: t})
+	}
+	sort.Slice(sp.Tags, func(i, j int) bool { return sp.Tags[i].Name < sp.Tags[j].Name })
+	return sp
+}
+
+func (sp *Spec) addRoute(ri *fst.RouteInfo, doc *Doc) {
+	method := strings.ToLower(ri.Method)
+	for _, dp := range parsePath(ri.Path) {
+		op := sp.newOperation(ri, doc, dp.params)
+		item := sp.Paths[dp.path]
+		if item == nil {
+			item = make(PathItem)
+			sp.Paths[dp.path] = item
+		}
+		item[method] = op
+	}
+}
+
+func (sp *Spec) newOperation(ri *fst.RouteInfo, doc *Doc, pathParams []*Parameter) *Operation {
+	op := &Operation{
+		Parameters: append([]*Parameter(nil), pathParams...), // 可选参数的两个路径共用 pathParams，不能直接 append
+		Responses:  map[string]*Response{"200": {Description: "OK"}},
+		Host:       ri.Host,
+	}
+	if doc == nil {
+		return op
+	}
+	op.Summary, op.Description, op.Tags, op.Deprecated = doc.Summary, doc.Description, doc.Tags, doc.Deprecated
+
+	if doc.Req != nil {
+		fields := structFields(reflect.TypeOf(doc.Req), false)
+		// 路由参数没有约束时，用结构体中同名字段的定义
+		for _, pm := range pathParams {
+			for i := range fields {
+				if fields[i].name == pm.Name && pm.Schema.Type == "string" && pm.Schema.Pattern == "" && pm.Schema.Format == "" {
+					pm.Schema = sp.fieldSchema(&fields[i])
+				}
+			}
+		}
+
+		if queryMethods[ri.Method] {
+			for i := range fields {
+				if hasParam(pathParams, fields[i].name) {
+					continue
+				}
+				op.Parameters = append(op.Parameters, &Parameter{
+					Name:     fields[i].name,
+					In:       "query",
+					Required: fields[i].opts != nil && fields[i].opts.Required,
+					Schema:   sp.fieldSchema(&fields[i]),
+				})
+			}
+		} else {
+			reqSchema := sp.schemaOf(reflect.TypeOf(doc.Req), false)
+			required := false
+			for i := range fields {
+				required = required || (fields[i].opts != nil && fields[i].opts.Required)
+			}
+			op.RequestBody = &RequestBody{
+				Required: required,
+				Content: map[string]*MediaType{
+					jsonContent: {Schema: reqSchema},
+					formContent: {Schema: reqSchema},
+				},
+			}
+		}
+	}
+
+	if doc.Resp != nil {
+		data := sp.schemaOf(reflect.TypeOf(doc.Resp), true)
+		if !doc.RawResp {
+			data = sucSchema(data)
+		}
+		op.Responses["200"].Content = map[string]*MediaType{jsonContent: {Schema: data}}
+	} else if !doc.RawResp {
+		op.Responses["200"].Content = map[string]*MediaType{jsonContent: {Schema: sucSchema(nil)}}
+	}
+	return op
+}
+
+// SucData 等函数返回的结构，见 fst/ctx_render.go
+func sucSchema(data *Schema) *Schema {
+	sc := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"status": {Type: "string", Enum: []any{"suc", "fai"}},
+			"code":   {Type: "integer", Format: "int32"},
+			"msg":    {Type: "string"},
+		},
+		Required: []string{"status", "code", "msg"},
+	}
+	if data != nil {
+		sc.Properties["data"] = data
+	}
+	return sc
+}
+
+func hasParam(pms []*Parameter, name string) bool {
+	for _, pm := range pms {
+		if pm.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 路由路径转换成文档路径，比如 /user/:id<int>/*file 转换成 /user/{id}/{file}
+// 最后一段是可选参数时，同时返回去掉这一段的路径
+func parsePath(fullPath string) []docPath {
+	var segs []string
+	var params []*Parameter
+	optional := false
+
+	for i := 1; i <= len(fullPath); {
+		end := i
+		if i < len(fullPath) && (fullPath[i] == ':' || fullPath[i] == '*') {
+			end = i + 1
+			for end < len(fullPath) && fullPath[end] != '/' && fullPath[end] != '<' && fullPath[end] != '?' {
+				end++
+			}
+			name := fullPath[i+1 : end]
+			pm := &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}}
+			if end < len(fullPath) && fullPath[end] == '<' {
+				if cEnd := consEnd(fullPath, end); cEnd > 0 {
+					pm.Schema = consSchema(fullPath[end+1 : cEnd])
+					end = cEnd + 1
+				}
+			}
+			if end < len(fullPath) && fullPath[end] == '?' {
+				optional = true
+				end++
+			}
+			params = append(params, pm)
+			segs = append(segs, "{"+name+"}")
+		} else {
+			for end < len(fullPath) && fullPath[end] != '/' {
+				end++
+			}
+			segs = append(segs, fullPath[i:end])
+		}
+		i = end + 1
+	}
+
+	paths := []docPath{{path: "/" + strings.Join(segs, "/"), params: params}}
+	if optional {
+		paths = append(paths, docPath{path: "/" + strings.Join(segs[:len(segs)-1], "/"), params: params[:len(params)-1]})
+	}
+	return paths
+}
+
+// 约束的结束位置：后面紧跟 '/'、'?' 或者路径结束的 '>'
+func consEnd(path string, start int) int {
+	for i := start + 1; i < len(path); i++ {
+		if path[i] == '>' && (i == len(path)-1 || path[i+1] == '/' || path[i+1] == '?') {
+			return i
+		}
+	}
+	return -1
+}
+
+func consSchema(expr string) *Schema {
+	if fn, ok := paramTypes[expr]; ok {
+		return fn()
+	}
+	return &Schema{Type: "string", Pattern: "^(?:" + expr + ")$"}
+}
@@ -0,0 +1,86 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"github.com/qinchende/gofast/fst"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+type pageReq struct {
+	Page int `pms:"page" v:"def=1,range=[1:1000]"`
+	Size int `v:"def=20,range=[1:100]"`
+}
+
+type userReq struct {
+	pageReq
+	Name   string `pms:"name" v:"required,len=[2:32]"`
+	Email  string `pms:"email" v:"match=email"`
+	Status int8   `pms:"status" v:"enum=0|1|2"`
+}
+
+type baseResp struct {
+	ID        int64     `json:"id,string"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type userResp struct {
+	baseResp
+	Name     string            `json:"name"`
+	Nick     string            // 没有 json 标签，用字段名
+	Password string            `json:"-"`
+	Extra    map[string]string `json:"extra,omitempty"`
+	Friends  []*userResp       `json:"friends"`
+	Profile  *profile          `json:"profile"`
+}
+
+type profile struct {
+	Age      int    `json:"age"`
+	NickName string `json:"nick"`
+}
+
+func TestBuildGolden(t *testing.T) {
+	app := fst.Default()
+	app.Get("/users", func(c *fst.Context) {}).
+		Attrs(&Doc{Summary: "用户列表", Tags: []string{"user"}, Req: userReq{}, Resp: []userResp{}})
+	app.Post("/users", func(c *fst.Context) {}).
+		Attrs(&Doc{Summary: "新增用户", Tags: []string{"user"}, Req: &userReq{}, Resp: userResp{}})
+	app.Get("/users/:id<int>", func(c *fst.Context) {}).
+		Attrs(&Doc{Summary: "用户详情", Tags: []string{"user"}, Resp: &userResp{}, Deprecated: true})
+	app.Get("/files/:dir<[a-z]+>/:name?", func(c *fst.Context) {}).
+		Attrs(&Doc{Summary: "文件", RawResp: true, Resp: profile{}})
+	app.Put("/profile", func(c *fst.Context) {}).
+		Attrs(&Doc{Summary: "同一个结构体做参数和返回", Req: profile{}, Resp: profile{}})
+	app.Get("/ping", func(c *fst.Context) {})
+	app.Get("/internal", func(c *fst.Context) {}).Attrs(&Doc{Hidden: true})
+
+	spec := Build(app, Info{Title: "golden", Version: "1.0.0"})
+	got, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	golden := filepath.Join("testdata", "openapi.golden.json")
+	if *update {
+		if err = os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("spec mismatch with %s (run go test -update to regenerate):\n%s", golden, got)
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package openapi
+
+import (
+	"github.com/qinchende/gofast/cst"
+	"github.com/qinchende/gofast/skill/lang"
+	"github.com/qinchende/gofast/skill/valid"
+	"math"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// valid 中 match 的格式对应 OpenAPI 的 format，其它的直接用 match 的名称
+var matchFormats = map[string]string{
+	"email":    "email",
+	"ipv4":     "ipv4",
+	"ipv6":     "ipv6",
+	"url":      "uri",
+	"base64":   "byte",
+	"datetime": "date-time",
+}
+
+type fieldItem struct {
+	name string
+	typ  reflect.Type
+	opts *valid.FieldOpts
+	resp bool // 返回结构体的字段，按 json 规则命名
+	str  bool // json 标签带 string 选项，数字和布尔值序列化成字符串
+}
+
+// Go 类型转成 Schema，有名字的结构体放入 components 中，这里只返回引用
+// resp 为 true 时是返回结构体，字段名按 JSON 序列化的规则，否则是请求参数，字段名按 pms 标签
+func (sp *Spec) schemaOf(rTyp reflect.Type, resp bool) *Schema {
+	for rTyp.Kind() == reflect.Ptr {
+		rTyp = rTyp.Elem()
+	}
+	if rTyp == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch rTyp.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32", Minimum: new(float64)}
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: "int64", Minimum: new(float64)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if rTyp.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: sp.schemaOf(rTyp.Elem(), resp)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: sp.schemaOf(rTyp.Elem(), resp)}
+	case reflect.Struct:
+		if rTyp.Name() == "" {
+			return sp.structSchema(rTyp, resp)
+		}
+		name := sp.schemaName(rTyp, resp)
+		if _, ok := sp.schemas[name]; !ok {
+			sp.schemas[name] = nil // 先占位，防止类型自己引用自己时死循环
+			sp.schemas[name] = sp.structSchema(rTyp, resp)
+		}
+		return &Schema{Ref: refPrefix + name}
+	default:
+		return &Schema{} // interface 等，任意类型
+	}
+}
+
+func (sp *Spec) structSchema(rTyp reflect.Type, resp bool) *Schema {
+	sc := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, fi := range structFields(rTyp, resp) {
+		sc.Properties[fi.name] = sp.fieldSchema(&fi)
+		if fi.opts != nil && fi.opts.Required {
+			sc.Required = append(sc.Required, fi.name)
+		}
+	}
+	return sc
+}
+
+func (sp *Spec) fieldSchema(fi *fieldItem) *Schema {
+	sc := sp.schemaOf(fi.typ, fi.resp)
+	if fi.str && (sc.Type == "integer" || sc.Type == "number" || sc.Type == "boolean") {
+		sc = &Schema{Type: "string", Format: sc.Format}
+	}
+	applyOptions(sc, fi.opts)
+	return sc
+}
+
+// 同一个类型做请求参数和返回结构时字段名可能不同，是两个 Schema，后出现的加上 Req 或 Resp 后缀区分
+// 同名的类型（不同包下）加上包名区分
+func (sp *Spec) schemaName(rTyp reflect.Type, resp bool) string {
+	full, other, suffix := rTyp.PkgPath()+"."+rTyp.Name(), "", "Req"
+	if resp {
+		full, other, suffix = full+"#resp", full, "Resp"
+	} else {
+		other = full + "#resp"
+	}
+	if name, ok := sp.typeNames[full]; ok {
+		return name
+	}
+
+	name := cleanName(rTyp.Name())
+	if _, ok := sp.typeNames[other]; ok && sp.nameUsed(name) {
+		name += suffix
+	}
+	if sp.nameUsed(name) {
+		name = cleanName(path.Base(rTyp.PkgPath()) + "." + rTyp.Name())
+	}
+	for i, base := 2, name; sp.nameUsed(name); i++ {
+		name = base + strconv.Itoa(i)
+	}
+	sp.typeNames[full] = name
+	return name
+}
+
+func (sp *Spec) nameUsed(name string) bool {
+	for _, v := range sp.typeNames {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Schema 名称只能是 [a-zA-Z0-9._-]，泛型类型名中的其它字符替换成 _
+func cleanName(name string) string {
+	bs := []byte(name)
+	for i, c := range bs {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			bs[i] = '_'
+		}
+	}
+	return string(bs)
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 提取结构体的字段，匿名结构体的字段展开
+// 请求参数的字段名称规则和 mapx 解析时一致，返回结构的字段名称规则和 encoding/json 一致
+func structFields(rTyp reflect.Type, resp bool) []fieldItem {
+	for rTyp.Kind() == reflect.Ptr {
+		rTyp = rTyp.Elem()
+	}
+	if rTyp.Kind() != reflect.Struct {
+		return nil
+	}
+	if resp {
+		return jsonFields(rTyp)
+	}
+
+	items := make([]fieldItem, 0, rTyp.NumField())
+	for i := 0; i < rTyp.NumField(); i++ {
+		fi := rTyp.Field(i)
+		if fi.Anonymous && fi.Type.Kind() == reflect.Struct && fi.Type != timeType {
+			items = append(items, structFields(fi.Type, false)...)
+			continue
+		}
+		if !fi.IsExported() {
+			continue
+		}
+
+		name := fi.Tag.Get(cst.FieldTag)
+		if name == "" {
+			name = lang.Camel2Snake(fi.Name)
+		}
+		opts, err := valid.ParseOptions(&fi, fi.Tag.Get(cst.FieldValidTag))
+		if err != nil {
+			panic(err)
+		}
+		items = append(items, fieldItem{name: name, typ: fi.Type, opts: opts})
+	}
+	return items
+}
+
+// 和 encoding/json 一样：json:"-" 忽略，没有标签用字段名，没有指定名称的匿名结构体字段展开
+// 返回结构不做参数验证，v 标签中只取 enum 等描述信息
+func jsonFields(rTyp reflect.Type) []fieldItem {
+	items := make([]fieldItem, 0, rTyp.NumField())
+	for i := 0; i < rTyp.NumField(); i++ {
+		fi := rTyp.Field(i)
+		tag := fi.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		fTyp := fi.Type
+		if fTyp.Kind() == reflect.Ptr {
+			fTyp = fTyp.Elem()
+		}
+		if fi.Anonymous && name == "" && fTyp.Kind() == reflect.Struct && fTyp != timeType {
+			items = append(items, jsonFields(fTyp)...)
+			continue
+		}
+		if !fi.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = fi.Name
+		}
+		vOpts, err := valid.ParseOptions(&fi, fi.Tag.Get(cst.FieldValidTag))
+		if err != nil {
+			panic(err)
+		}
+		if vOpts != nil {
+			vOpts.Required = false
+		}
+		str := false
+		for _, op := range strings.Split(opts, ",") {
+			str = str || op == "string"
+		}
+		items = append(items, fieldItem{name: name, typ: fi.Type, opts: vOpts, resp: true, str: str})
+	}
+	return items
+}
+
+// 验证规则转换成 Schema 的约束，引用类型不能再加约束
+func applyOptions(sc *Schema, opts *valid.FieldOpts) {
+	if opts == nil || sc.Ref != "" {
+		return
+	}
+
+	if nr := opts.Range; nr != nil {
+		if v := nr.Min(); v > -math.MaxFloat64 {
+			sc.Minimum, sc.ExclusiveMinimum = &v, !nr.IncludeMin()
+		}
+		if v := nr.Max(); v < math.MaxFloat64 {
+			sc.Maximum, sc.ExclusiveMaximum = &v, !nr.IncludeMax()
+		}
+	}
+	if nr := opts.Len; nr != nil {
+		if v := nr.Min(); v > -math.MaxFloat64 {
+			n := int64(math.Ceil(v))
+			if !nr.IncludeMin() && float64(n) == v {
+				n++
+			}
+			if n > 0 {
+				sc.MinLength = &n
+			}
+		}
+		if v := nr.Max(); v < math.MaxFloat64 {
+			n := int64(math.Floor(v))
+			if !nr.IncludeMax() && float64(n) == v {
+				n--
+			}
+			sc.MaxLength = &n
+		}
+	}
+	for _, it := range opts.Enum {
+		sc.Enum = append(sc.Enum, typedValue(sc.Type, it))
+	}
+	if opts.DefValue != "" {
+		sc.Default = typedValue(sc.Type, opts.DefValue)
+	}
+	if opts.Regex != "" {
+		sc.Pattern = opts.Regex
+	}
+	if opts.Match != "" {
+		if format, ok := matchFormats[opts.Match]; ok {
+			sc.Format = format
+		} else {
+			sc.Format = opts.Match
+			if sc.Pattern == "" {
+				sc.Pattern = valid.MatchRegex(opts.Match)
+			}
+		}
+	}
+}
+
+// 标签中的值都是字符串，按 Schema 的类型转换
+func typedValue(typ, val string) any {
+	switch typ {
+	case "integer":
+		if v, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return v
+		}
+	case "number":
+		if v, err := strconv.ParseFloat(val, 64); err == nil {
+			return v
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(val); err == nil {
+			return v
+		}
+	}
+	return val
+}
@@ -0,0 +1,104 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"github.com/qinchende/gofast/fst"
+	"github.com/qinchende/gofast/skill/jsonx"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+//go:embed swagger.html
+var swaggerHtml string
+
+// swagger-ui-dist 的静态资源打包进程序，内网环境也能直接打开文档页面
+var (
+	//go:embed ui/swagger-ui.css
+	uiCss []byte
+	//go:embed ui/swagger-ui-bundle.js
+	uiBundleJs []byte
+)
+
+type Config struct {
+	Path     string   // 文档页面的路由，文档 JSON 在 Path + "/openapi.json"，默认 /docs
+	Info     Info     // 文档标题、版本等
+	Servers  []Server // 可选，服务地址
+	UIAssets string   // 可选，swagger-ui-dist 静态资源地址，默认用程序内嵌的资源（Path 下）
+}
+
+type rawRender struct {
+	contentType string
+	data        []byte
+}
+
+func (r rawRender) Write(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	_, err := w.Write(r.data)
+	return err
+}
+
+func (r rawRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", r.contentType)
+}
+
+// 注册 Swagger UI 页面和文档 JSON 的路由
+// 文档在构建路由树之前生成，热更新路由之后重新生成
+func Serve(app *fst.GoFast, cnf *Config) {
+	docPath := strings.TrimRight(cnf.Path, "/")
+	if docPath == "" {
+		docPath = "/docs"
+	}
+	assets := strings.TrimRight(cnf.UIAssets, "/")
+	if assets == "" {
+		assets = docPath
+	}
+	if cnf.Info.Title == "" {
+		cnf.Info.Title = app.AppName
+	}
+	if cnf.Info.Title == "" {
+		cnf.Info.Title = "GoFast API"
+	}
+	if cnf.Info.Version == "" {
+		cnf.Info.Version = "1.0.0"
+	}
+	specPath := docPath + "/openapi.json"
+
+	var page bytes.Buffer
+	tpl := template.Must(template.New("swagger").Parse(swaggerHtml))
+	if err := tpl.Execute(&page, map[string]string{"Title": cnf.Info.Title, "Assets": assets, "SpecURL": specPath}); err != nil {
+		panic(err)
+	}
+
+	var spec atomic.Value
+	genSpec := func(app *fst.GoFast) {
+		bs, err := jsonx.Marshal(Build(app, cnf.Info, cnf.Servers...))
+		if err != nil {
+			panic(err)
+		}
+		spec.Store(bs)
+	}
+	app.OnBeforeBuildRoutes(genSpec)
+	app.OnReloadRoutes(genSpec)
+
+	hidden := &Doc{Hidden: true}
+	app.Get(docPath, func(c *fst.Context) {
+		c.Render(http.StatusOK, rawRender{contentType: "text/html; charset=utf-8", data: page.Bytes()})
+	}).Attrs(hidden)
+	app.Get(specPath, func(c *fst.Context) {
+		bs, _ := spec.Load().([]byte)
+		c.Render(http.StatusOK, rawRender{contentType: "application/json; charset=utf-8", data: bs})
+	}).Attrs(hidden)
+	if cnf.UIAssets == "" {
+		app.Get(docPath+"/swagger-ui.css", func(c *fst.Context) {
+			c.Render(http.StatusOK, rawRender{contentType: "text/css; charset=utf-8", data: uiCss})
+		}).Attrs(hidden)
+		app.Get(docPath+"/swagger-ui-bundle.js", func(c *fst.Context) {
+			c.Render(http.StatusOK, rawRender{contentType: "application/javascript; charset=utf-8", data: uiBundleJs})
+		}).Attrs(hidden)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package openapi
+
+// OpenAPI 3.0 文档结构，只包含生成文档用到的部分
+// 字段名称由规范决定，所以这里用 json 标签
+type (
+	Spec struct {
+		OpenAPI    string              `json:"openapi"`
+		Info       Info                `json:"info"`
+		Servers    []Server            `json:"servers,omitempty"`
+		Paths      map[string]PathItem `json:"paths"`
+		Components *Components         `json:"components,omitempty"`
+		Tags       []Tag               `json:"tags,omitempty"`
+		schemas    map[string]*Schema  // 结构体类型对应的 Schema，最后放入 Components
+		typeNames  map[string]string   // 类型全名 -> Schema 名称，处理不同包下的同名类型
+	}
+
+	Info struct {
+		Title       string `json:"title"`
+		Description string `json:"description,omitempty"`
+		Version     string `json:"version"`
+	}
+
+	Server struct {
+		URL         string `json:"url"`
+		Description string `json:"description,omitempty"`
+	}
+
+	Tag struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+	}
+
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas,omitempty"`
+	}
+
+	// key 是小写的 method
+	PathItem map[string]*Operation
+
+	Operation struct {
+		Tags        []string             `json:"tags,omitempty"`
+		Summary     string               `json:"summary,omitempty"`
+		Description string               `json:"description,omitempty"`
+		Parameters  []*Parameter         `json:"parameters,omitempty"`
+		RequestBody *RequestBody         `json:"requestBody,omitempty"`
+		Responses   map[string]*Response `json:"responses"`
+		Deprecated  bool                 `json:"deprecated,omitempty"`
+		Host        string               `json:"x-host,omitempty"` // 路由限定的 Host
+	}
+
+	Parameter struct {
+		Name        string  `json:"name"`
+		In          string  `json:"in"` // path | query | header
+		Description string  `json:"description,omitempty"`
+		Required    bool    `json:"required,omitempty"`
+		Schema      *Schema `json:"schema"`
+	}
+
+	RequestBody struct {
+		Required bool                  `json:"required,omitempty"`
+		Content  map[string]*MediaType `json:"content"`
+	}
+
+	Response struct {
+		Description string                `json:"description"`
+		Content     map[string]*MediaType `json:"content,omitempty"`
+	}
+
+	MediaType struct {
+		Schema *Schema `json:"schema"`
+	}
+
+	Schema struct {
+		Ref                  string             `json:"$ref,omitempty"`
+		Type                 string             `json:"type,omitempty"`
+		Format               string             `json:"format,omitempty"`
+		Description          string             `json:"description,omitempty"`
+		Enum                 []any              `json:"enum,omitempty"`
+		Default              any                `json:"default,omitempty"`
+		Pattern              string             `json:"pattern,omitempty"`
+		Minimum              *float64           `json:"minimum,omitempty"`
+		Maximum              *float64           `json:"maximum,omitempty"`
+		ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+		ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+		MinLength            *int64             `json:"minLength,omitempty"`
+		MaxLength            *int64             `json:"maxLength,omitempty"`
+		Items                *Schema            `json:"items,omitempty"`
+		Properties           map[string]*Schema `json:"properties,omitempty"`
+		AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+		Required             []string           `json:"required,omitempty"`
+	}
+)
+
+const (
+	specVersion = "3.0.3"
+	jsonContent = "application/json"
+	formContent = "application/x-www-form-urlencoded"
+	refPrefix   = "#/components/schemas/"
+)
@@ -53,12 +53,7 @@ func NewRsaDecrypter(file string) (RsaDecrypter, error) {
 		return nil, err
 	}
 
-	block, _ := pem.Decode(content)
-	if block == nil {
-		return nil, ErrPrivateKey
-	}
-
-	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	privateKey, err := ParseRsaPrivateKey(content)
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +87,34 @@ func (r *rsaDecrypter) DecryptBase64(input string) ([]byte, error) {
 
 // NewRsaEncrypter returns a RsaEncrypter with the given key.
 func NewRsaEncrypter(key []byte) (RsaEncrypter, error) {
+	pubKey, err := ParseRsaPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rsaEncrypter{
+		rsaBase: rsaBase{
+			// https://www.ietf.org/rfc/rfc2313.txt
+			// The length of the data D shall not be more than k-11 octets, which is
+			// positive since the length k of the modulus is at least 12 octets.
+			bytesLimit: (pubKey.N.BitLen() >> 3) - 11,
+		},
+		publicKey: pubKey,
+	}, nil
+}
+
+// ParseRsaPrivateKey parses a PEM encoded PKCS1 private key.
+func ParseRsaPrivateKey(key []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return nil, ErrPrivateKey
+	}
+
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+// ParseRsaPublicKey parses a PEM encoded PKIX public key.
+func ParseRsaPublicKey(key []byte) (*rsa.PublicKey, error) {
 	block, _ := pem.Decode(key)
 	if block == nil {
 		return nil, ErrPublicKey
@@ -102,20 +125,10 @@ func NewRsaEncrypter(key []byte) (RsaEncrypter, error) {
 		return nil, err
 	}
 
-	switch pubKey := pub.(type) {
-	case *rsa.PublicKey:
-		return &rsaEncrypter{
-			rsaBase: rsaBase{
-				// https://www.ietf.org/rfc/rfc2313.txt
-				// The length of the data D shall not be more than k-11 octets, which is
-				// positive since the length k of the modulus is at least 12 octets.
-				bytesLimit: (pubKey.N.BitLen() >> 3) - 11,
-			},
-			publicKey: pubKey,
-		}, nil
-	default:
-		return nil, ErrNotRsaKey
+	if pubKey, ok := pub.(*rsa.PublicKey); ok {
+		return pubKey, nil
 	}
+	return nil, ErrNotRsaKey
 }
 
 func (r *rsaEncrypter) Encrypt(input []byte) ([]byte, error) {
@@ -56,3 +56,14 @@ func TestBadPubKey(t *testing.T) {
 	_, err := NewRsaEncrypter([]byte("foo"))
 	assert.Equal(t, ErrPublicKey, err)
 }
+
+func TestParseRsaKeys(t *testing.T) {
+	pri, err := ParseRsaPrivateKey([]byte(priKey))
+	assert.Nil(t, err)
+	pub, err := ParseRsaPublicKey([]byte(pubKey))
+	assert.Nil(t, err)
+	assert.Equal(t, pri.PublicKey.N, pub.N)
+
+	_, err = ParseRsaPrivateKey([]byte("foo"))
+	assert.Equal(t, ErrPrivateKey, err)
+}
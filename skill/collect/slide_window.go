@@ -68,8 +68,9 @@ func (rw *slideWindowBase) nowOffset() int {
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 // 返回当前滑动窗口汇总数据
 func (rw *SlideWindow) CurrWin() SlideWinBucket {
-	rw.lock.RLock()
-	defer rw.lock.RUnlock()
+	// 剔除过期的桶会修改数据，这里要用写锁
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
 
 	rw.expireBuckets(rw.nowOffset())
 	return rw.win
@@ -40,8 +40,9 @@ func NewSlideWindowBreak(size int, dur time.Duration) *SlideWindowBreak {
 
 // 返回当前滑动窗口汇总数据
 func (rw *SlideWindowBreak) CurrWin() (float64, int64) {
-	rw.lock.RLock()
-	defer rw.lock.RUnlock()
+	// 剔除过期的桶会修改数据，这里要用写锁
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
 
 	rw.expireBuckets(rw.nowOffset())
 	return rw.win.accepts, rw.win.total
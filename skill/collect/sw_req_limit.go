@@ -41,8 +41,9 @@ func NewSlideWindowLimit(size int, dur time.Duration) *SlideWindowLimit {
 
 // 返回当前滑动窗口汇总数据
 func (rw *SlideWindowLimit) CurrWin() (int32, int32, int64) {
-	rw.lock.RLock()
-	defer rw.lock.RUnlock()
+	// 剔除过期的桶会修改数据，这里要用写锁
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
 
 	rw.expireBuckets(rw.nowOffset())
 	return rw.win.income, rw.win.finish, rw.win.totalTimeMS
@@ -0,0 +1,45 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package discov
+
+import "github.com/qinchende/gofast/skill/hash"
+
+const (
+	LbRoundRobin = "round_robin"
+	LbWeighted   = "weighted"
+	LbP2C        = "p2c"
+	LbHash       = "hash"
+)
+
+// 调用结束之后的回调，P2C 等需要根据调用结果调整实例的负载
+type DoneFunc func(err error)
+
+// 负载均衡：从当前实例列表中选出一个，key 只对一致性 hash 有意义
+type Balancer interface {
+	Update(eps []Endpoint)
+	Pick(key string) (Endpoint, DoneFunc, error)
+}
+
+// 按名称创建 Balancer，名称不认识的时候用 P2C
+func NewBalancer(name string) Balancer {
+	switch name {
+	case LbRoundRobin:
+		return NewRoundRobin()
+	case LbWeighted:
+		return NewWeighted()
+	case LbHash:
+		return NewConsistentHash()
+	default:
+		return NewP2C()
+	}
+}
+
+func doneNothing(error) {}
+
+// 没有设置权重的实例按最大权重处理
+func weightOf(ep Endpoint) int {
+	if ep.Weight == 0 {
+		return hash.TopWeight
+	}
+	return int(ep.Weight)
+}
@@ -0,0 +1,40 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package discov
+
+import (
+	"github.com/qinchende/gofast/skill/hash"
+	"sync"
+)
+
+// 一致性 hash：相同 key 的请求总是落在同一个实例上，实例变化时只影响少量 key
+type consistentHash struct {
+	lock sync.RWMutex
+	ring *hash.ConsistentHash
+}
+
+func NewConsistentHash() Balancer {
+	return &consistentHash{ring: hash.NewConsistentHash()}
+}
+
+func (cb *consistentHash) Update(eps []Endpoint) {
+	ring := hash.NewConsistentHash()
+	for _, ep := range eps {
+		ring.AddWithWeight(ep, weightOf(ep))
+	}
+
+	cb.lock.Lock()
+	cb.ring = ring
+	cb.lock.Unlock()
+}
+
+func (cb *consistentHash) Pick(key string) (Endpoint, DoneFunc, error) {
+	cb.lock.RLock()
+	ring := cb.ring
+	cb.lock.RUnlock()
+
+	if node, ok := ring.Get(key); ok {
+		return node.(Endpoint), doneNothing, nil
+	}
+	return Endpoint{}, nil, ErrNoEndpoint
+}
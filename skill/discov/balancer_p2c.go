@@ -0,0 +1,111 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package discov
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	p2cDecayTime   = float64(10 * time.Second) // EWMA 衰减周期
+	p2cPenalty     = int64(time.Second)        // 调用失败时按这个耗时计算
+	p2cInitLatency = int64(time.Millisecond)   // 新实例的初始耗时
+)
+
+// P2C-EWMA：随机选两个实例，比较 EWMA耗时 * (处理中请求数+1) / 权重，选负载低的那个
+type p2cNode struct {
+	ep       Endpoint
+	weight   float64
+	inflight int64
+	latency  int64 // EWMA 耗时（纳秒）
+	lastTime int64 // 上次更新 EWMA 的时间（纳秒）
+}
+
+type p2c struct {
+	lock  sync.RWMutex
+	nodes []*p2cNode
+	rand  *rand.Rand
+	rLock sync.Mutex
+}
+
+func NewP2C() Balancer {
+	return &p2c{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// 保留已有实例的统计数据，只增删变化的实例
+func (pb *p2c) Update(eps []Endpoint) {
+	pb.lock.Lock()
+	defer pb.lock.Unlock()
+
+	old := make(map[Endpoint]*p2cNode, len(pb.nodes))
+	for _, nd := range pb.nodes {
+		old[nd.ep] = nd
+	}
+	nodes := make([]*p2cNode, len(eps))
+	for i, ep := range eps {
+		if nd, ok := old[ep]; ok {
+			nodes[i] = nd
+			continue
+		}
+		nodes[i] = &p2cNode{ep: ep, weight: float64(weightOf(ep)), latency: p2cInitLatency, lastTime: time.Now().UnixNano()}
+	}
+	pb.nodes = nodes
+}
+
+func (pb *p2c) Pick(string) (Endpoint, DoneFunc, error) {
+	pb.lock.RLock()
+	defer pb.lock.RUnlock()
+
+	var nd *p2cNode
+	switch len(pb.nodes) {
+	case 0:
+		return Endpoint{}, nil, ErrNoEndpoint
+	case 1:
+		nd = pb.nodes[0]
+	default:
+		pb.rLock.Lock()
+		a := pb.rand.Intn(len(pb.nodes))
+		b := pb.rand.Intn(len(pb.nodes) - 1)
+		pb.rLock.Unlock()
+		if b >= a {
+			b++
+		}
+		nd = pb.nodes[a]
+		if pb.nodes[b].load() < nd.load() {
+			nd = pb.nodes[b]
+		}
+	}
+
+	atomic.AddInt64(&nd.inflight, 1)
+	start := time.Now().UnixNano()
+	return nd.ep, func(err error) {
+		atomic.AddInt64(&nd.inflight, -1)
+		now := time.Now().UnixNano()
+		lat := now - start
+		if err != nil && lat < p2cPenalty {
+			lat = p2cPenalty
+		}
+		nd.observe(now, lat)
+	}, nil
+}
+
+func (nd *p2cNode) load() float64 {
+	lat := float64(atomic.LoadInt64(&nd.latency))
+	return lat * float64(atomic.LoadInt64(&nd.inflight)+1) / nd.weight
+}
+
+// 距离上次更新越久，旧值的权重越低
+func (nd *p2cNode) observe(now, lat int64) {
+	last := atomic.SwapInt64(&nd.lastTime, now)
+	td := now - last
+	if td < 0 {
+		td = 0
+	}
+	w := math.Exp(-float64(td) / p2cDecayTime)
+	old := atomic.LoadInt64(&nd.latency)
+	atomic.StoreInt64(&nd.latency, int64(float64(old)*w+float64(lat)*(1-w)))
+}
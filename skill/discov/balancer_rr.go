@@ -0,0 +1,86 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package discov
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// 轮询
+type roundRobin struct {
+	lock sync.RWMutex
+	eps  []Endpoint
+	next uint64
+}
+
+func NewRoundRobin() Balancer {
+	return &roundRobin{}
+}
+
+func (rb *roundRobin) Update(eps []Endpoint) {
+	rb.lock.Lock()
+	rb.eps = eps
+	rb.lock.Unlock()
+}
+
+func (rb *roundRobin) Pick(string) (Endpoint, DoneFunc, error) {
+	rb.lock.RLock()
+	defer rb.lock.RUnlock()
+
+	if len(rb.eps) == 0 {
+		return Endpoint{}, nil, ErrNoEndpoint
+	}
+	idx := atomic.AddUint64(&rb.next, 1) % uint64(len(rb.eps))
+	return rb.eps[idx], doneNothing, nil
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 平滑加权轮询（nginx 的算法），权重大的实例被选中的次数多，但不会连续集中在同一个实例上
+type weightedNode struct {
+	ep      Endpoint
+	weight  int
+	current int
+}
+
+type weighted struct {
+	lock  sync.Mutex
+	nodes []*weightedNode
+	total int
+}
+
+func NewWeighted() Balancer {
+	return &weighted{}
+}
+
+func (wb *weighted) Update(eps []Endpoint) {
+	nodes := make([]*weightedNode, len(eps))
+	total := 0
+	for i, ep := range eps {
+		nodes[i] = &weightedNode{ep: ep, weight: weightOf(ep)}
+		total += nodes[i].weight
+	}
+
+	wb.lock.Lock()
+	wb.nodes = nodes
+	wb.total = total
+	wb.lock.Unlock()
+}
+
+func (wb *weighted) Pick(string) (Endpoint, DoneFunc, error) {
+	wb.lock.Lock()
+	defer wb.lock.Unlock()
+
+	if len(wb.nodes) == 0 {
+		return Endpoint{}, nil, ErrNoEndpoint
+	}
+	var best *weightedNode
+	for _, nd := range wb.nodes {
+		nd.current += nd.weight
+		if best == nil || nd.current > best.current {
+			best = nd
+		}
+	}
+	best.current -= wb.total
+	return best.ep, doneNothing, nil
+}
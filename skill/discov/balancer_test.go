@@ -0,0 +1,77 @@
+package discov
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testEps = []Endpoint{
+	{Addr: "10.0.0.1:80", Weight: 10},
+	{Addr: "10.0.0.2:80", Weight: 20},
+	{Addr: "10.0.0.3:80", Weight: 70},
+}
+
+func pickCounts(b Balancer, n int, key func(int) string) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		ep, done, err := b.Pick(key(i))
+		if err != nil {
+			panic(err)
+		}
+		counts[ep.Addr]++
+		done(nil)
+	}
+	return counts
+}
+
+func TestBalancerEmpty(t *testing.T) {
+	for _, name := range []string{LbRoundRobin, LbWeighted, LbP2C, LbHash} {
+		_, _, err := NewBalancer(name).Pick("k")
+		assert.Equal(t, ErrNoEndpoint, err, name)
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	b := NewRoundRobin()
+	b.Update(testEps)
+	counts := pickCounts(b, 300, func(int) string { return "" })
+	for _, ep := range testEps {
+		assert.Equal(t, 100, counts[ep.Addr])
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	b := NewWeighted()
+	b.Update(testEps)
+	counts := pickCounts(b, 1000, func(int) string { return "" })
+	assert.Equal(t, 100, counts["10.0.0.1:80"])
+	assert.Equal(t, 200, counts["10.0.0.2:80"])
+	assert.Equal(t, 700, counts["10.0.0.3:80"])
+}
+
+func TestConsistentHashPick(t *testing.T) {
+	b := NewConsistentHash()
+	b.Update(testEps)
+	first, _, _ := b.Pick("user:1001")
+	for i := 0; i < 10; i++ {
+		ep, _, _ := b.Pick("user:1001")
+		assert.Equal(t, first, ep)
+	}
+}
+
+func TestP2CAvoidsFailingNode(t *testing.T) {
+	b := NewP2C()
+	b.Update(testEps[:2])
+	for i := 0; i < 50; i++ {
+		ep, done, _ := b.Pick("")
+		if ep.Addr == "10.0.0.1:80" {
+			done(errors.New("fail"))
+		} else {
+			done(nil)
+		}
+	}
+	counts := pickCounts(b, 100, func(int) string { return "" })
+	assert.True(t, counts["10.0.0.2:80"] > counts["10.0.0.1:80"])
+}
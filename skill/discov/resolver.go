@@ -0,0 +1,131 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package discov
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var ErrNoEndpoint = errors.New("discov: no endpoint available")
+
+// 服务的一个实例地址
+type Endpoint struct {
+	Addr   string `json:"addr"`   // ip:port
+	Weight uint16 `json:"weight"` // 权重，0 表示默认权重
+}
+
+// 服务发现：给出当前可用的实例列表，实例有变化时通知订阅者
+// Watch 返回取消订阅的函数，订阅者的生命周期比 Resolver 短时要调用它，否则一直不能释放
+type Resolver interface {
+	Endpoints() []Endpoint
+	Watch(fn func([]Endpoint)) (unwatch func())
+	Close()
+}
+
+// 把 Resolver 的实例变化同步到 Balancer，HTTP 和 gRPC 客户端都用这个组合
+func Bind(r Resolver, b Balancer) Balancer {
+	b.Update(r.Endpoints())
+	r.Watch(b.Update)
+	return b
+}
+
+// 解析 "10.0.0.1:8080|50,10.0.0.2:8080" 这种格式的地址列表，竖线后面是权重
+func ParseEndpoints(str string) []Endpoint {
+	items := strings.Split(str, ",")
+	eps := make([]Endpoint, 0, len(items))
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		ep := Endpoint{Addr: item}
+		if idx := strings.IndexByte(item, '|'); idx > 0 {
+			ep.Addr = item[:idx]
+			w, _ := strconv.ParseUint(item[idx+1:], 10, 16)
+			ep.Weight = uint16(w)
+		}
+		eps = append(eps, ep)
+	}
+	return eps
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 各种 Resolver 公用的订阅管理，实例列表有变化才通知
+type watchers struct {
+	lock sync.Mutex
+	eps  []Endpoint
+	fns  []*watchFn
+}
+
+type watchFn struct {
+	fn func([]Endpoint)
+}
+
+func (ws *watchers) Endpoints() []Endpoint {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+	return ws.eps
+}
+
+func (ws *watchers) Watch(fn func([]Endpoint)) func() {
+	wf := &watchFn{fn: fn}
+	ws.lock.Lock()
+	ws.fns = append(ws.fns, wf)
+	ws.lock.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { ws.unwatch(wf) })
+	}
+}
+
+// 生成新的切片，不影响 update 中正在通知的副本
+func (ws *watchers) unwatch(wf *watchFn) {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+
+	fns := make([]*watchFn, 0, len(ws.fns))
+	for _, item := range ws.fns {
+		if item != wf {
+			fns = append(fns, item)
+		}
+	}
+	ws.fns = fns
+}
+
+// 排序用的是副本，不修改调用者的数据
+func (ws *watchers) update(eps []Endpoint) {
+	eps = append([]Endpoint(nil), eps...)
+	sort.Slice(eps, func(i, j int) bool {
+		return eps[i].Addr < eps[j].Addr
+	})
+
+	ws.lock.Lock()
+	if sameEndpoints(ws.eps, eps) {
+		ws.lock.Unlock()
+		return
+	}
+	ws.eps = eps
+	fns := ws.fns
+	ws.lock.Unlock()
+
+	for _, wf := range fns {
+		wf.fn(eps)
+	}
+}
+
+func sameEndpoints(a, b []Endpoint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
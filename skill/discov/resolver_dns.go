@@ -0,0 +1,80 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package discov
+
+import (
+	"context"
+	"github.com/qinchende/gofast/logx"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 通过 DNS SRV 记录发现实例，SRV 记录中的 weight 作为实例权重
+type DnsResolver struct {
+	watchers
+	service  string
+	proto    string
+	name     string
+	interval time.Duration
+	cancel   context.CancelFunc
+	lookup   func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+const defDnsInterval = 10 * time.Second // 没有指定刷新间隔时的默认值
+
+// 比如 _grpc._tcp.user.svc.local 对应 NewDnsResolver("grpc", "tcp", "user.svc.local", 10*time.Second)
+func NewDnsResolver(service, proto, name string, interval time.Duration) *DnsResolver {
+	if interval <= 0 {
+		interval = defDnsInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	dr := &DnsResolver{
+		service:  service,
+		proto:    proto,
+		name:     name,
+		interval: interval,
+		cancel:   cancel,
+		lookup:   net.DefaultResolver.LookupSRV,
+	}
+	dr.refresh(ctx)
+	go dr.loop(ctx)
+	return dr
+}
+
+func (dr *DnsResolver) Close() {
+	dr.cancel()
+}
+
+func (dr *DnsResolver) loop(ctx context.Context) {
+	ticker := time.NewTicker(dr.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			dr.refresh(ctx)
+		}
+	}
+}
+
+// 查询失败的时候保留上一次的结果，避免 DNS 抖动导致没有实例可用
+func (dr *DnsResolver) refresh(ctx context.Context) {
+	_, srvs, err := dr.lookup(ctx, dr.service, dr.proto, dr.name)
+	if err != nil {
+		logx.ErrorF("Discov: lookup srv %s error: %s", dr.name, err)
+		return
+	}
+
+	eps := make([]Endpoint, 0, len(srvs))
+	for _, srv := range srvs {
+		host := strings.TrimSuffix(srv.Target, ".")
+		eps = append(eps, Endpoint{
+			Addr:   net.JoinHostPort(host, strconv.Itoa(int(srv.Port))),
+			Weight: srv.Weight,
+		})
+	}
+	dr.update(eps)
+}
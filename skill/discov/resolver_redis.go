@@ -0,0 +1,138 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package discov
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/qinchende/gofast/connx/gfrds"
+	"github.com/qinchende/gofast/logx"
+	"strconv"
+	"time"
+)
+
+const (
+	redisDiscovPrefix  = "discov:"
+	defRedisTTL        = 15 * time.Second // 没有指定实例 TTL 时的默认值
+	defRedisPullPeriod = 5 * time.Second  // 没有指定拉取间隔时的默认值
+)
+
+// 基于 Redis 的注册中心：每个服务一个 ZSET，member 是 addr|weight，score 是过期时间（毫秒）
+// 实例按 TTL/3 的间隔心跳续期，进程异常退出的实例在 TTL 之后自动失效
+type RedisRegistry struct {
+	rds     *gfrds.GfRedis
+	service string
+	ttl     time.Duration
+}
+
+func NewRedisRegistry(rds *gfrds.GfRedis, service string, ttl time.Duration) *RedisRegistry {
+	if ttl <= 0 {
+		ttl = defRedisTTL
+	}
+	return &RedisRegistry{rds: rds, service: service, ttl: ttl}
+}
+
+func (rr *RedisRegistry) key() string {
+	return redisDiscovPrefix + rr.service
+}
+
+func (rr *RedisRegistry) member(ep Endpoint) string {
+	return ep.Addr + "|" + strconv.Itoa(int(ep.Weight))
+}
+
+// 注册当前实例并开始心跳，返回的函数用于下线
+func (rr *RedisRegistry) Register(ep Endpoint) (deregister func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rr.heartbeat(ep)
+
+	go func() {
+		beat := rr.ttl / 3
+		if beat <= 0 {
+			beat = rr.ttl
+		}
+		ticker := time.NewTicker(beat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				rr.heartbeat(ep)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		if err := rr.rds.Cli.ZRem(rr.rds.Ctx, rr.key(), rr.member(ep)).Err(); err != nil {
+			logx.ErrorF("Discov: deregister %s error: %s", ep.Addr, err)
+		}
+	}
+}
+
+func (rr *RedisRegistry) heartbeat(ep Endpoint) {
+	expire := time.Now().Add(rr.ttl).UnixMilli()
+	err := rr.rds.Cli.ZAdd(rr.rds.Ctx, rr.key(), &redis.Z{Score: float64(expire), Member: rr.member(ep)}).Err()
+	if err != nil {
+		logx.ErrorF("Discov: heartbeat %s error: %s", ep.Addr, err)
+	}
+}
+
+// 查询当前还没过期的实例，顺便清理已经过期的
+func (rr *RedisRegistry) Endpoints() ([]Endpoint, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	rr.rds.Cli.ZRemRangeByScore(rr.rds.Ctx, rr.key(), "-inf", "("+now)
+
+	members, err := rr.rds.Cli.ZRangeByScore(rr.rds.Ctx, rr.key(), &redis.ZRangeBy{Min: now, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, err
+	}
+	eps := make([]Endpoint, 0, len(members))
+	for _, m := range members {
+		eps = append(eps, ParseEndpoints(m)...)
+	}
+	return eps, nil
+}
+
+// 定时拉取注册中心的实例列表
+func (rr *RedisRegistry) NewResolver(interval time.Duration) *RedisResolver {
+	if interval <= 0 {
+		interval = defRedisPullPeriod
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	res := &RedisResolver{reg: rr, cancel: cancel}
+	res.refresh()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				res.refresh()
+			}
+		}
+	}()
+	return res
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+type RedisResolver struct {
+	watchers
+	reg    *RedisRegistry
+	cancel context.CancelFunc
+}
+
+func (res *RedisResolver) Close() {
+	res.cancel()
+}
+
+func (res *RedisResolver) refresh() {
+	eps, err := res.reg.Endpoints()
+	if err != nil {
+		logx.ErrorF("Discov: resolve %s error: %s", res.reg.service, err)
+		return
+	}
+	res.update(eps)
+}
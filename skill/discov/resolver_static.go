@@ -0,0 +1,22 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package discov
+
+// 固定的实例列表，适合配置文件中直接写好地址的场景
+type StaticResolver struct {
+	watchers
+}
+
+func NewStaticResolver(eps ...Endpoint) *StaticResolver {
+	sr := &StaticResolver{}
+	sr.update(eps)
+	return sr
+}
+
+// 手动更新实例列表，比如配置文件热加载之后
+func (sr *StaticResolver) Update(eps []Endpoint) {
+	sr.update(eps)
+}
+
+func (sr *StaticResolver) Close() {
+}
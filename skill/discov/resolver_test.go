@@ -0,0 +1,51 @@
+package discov
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEndpoints(t *testing.T) {
+	eps := ParseEndpoints("10.0.0.1:80|50, 10.0.0.2:80,")
+	assert.Equal(t, []Endpoint{{Addr: "10.0.0.1:80", Weight: 50}, {Addr: "10.0.0.2:80"}}, eps)
+}
+
+func TestStaticResolverBind(t *testing.T) {
+	sr := NewStaticResolver(Endpoint{Addr: "10.0.0.1:80"})
+	b := Bind(sr, NewRoundRobin())
+	ep, _, err := b.Pick("")
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1:80", ep.Addr)
+
+	var notified int
+	sr.Watch(func([]Endpoint) { notified++ })
+	sr.Update([]Endpoint{{Addr: "10.0.0.2:80"}})
+	sr.Update([]Endpoint{{Addr: "10.0.0.2:80"}})
+	assert.Equal(t, 1, notified)
+
+	ep, _, _ = b.Pick("")
+	assert.Equal(t, "10.0.0.2:80", ep.Addr)
+}
+
+func TestWatchersUpdateKeepsInput(t *testing.T) {
+	var ws watchers
+	eps := []Endpoint{{Addr: "10.0.0.2:80"}, {Addr: "10.0.0.1:80"}}
+	ws.update(eps)
+	assert.Equal(t, "10.0.0.2:80", eps[0].Addr)
+	assert.Equal(t, "10.0.0.1:80", ws.Endpoints()[0].Addr)
+}
+
+func TestWatchersUnwatch(t *testing.T) {
+	sr := NewStaticResolver()
+	var first, second int
+	unwatch := sr.Watch(func([]Endpoint) { first++ })
+	sr.Watch(func([]Endpoint) { second++ })
+
+	sr.Update([]Endpoint{{Addr: "10.0.0.1:80"}})
+	unwatch()
+	unwatch() // 多次调用没有影响
+	sr.Update([]Endpoint{{Addr: "10.0.0.2:80"}})
+	assert.Equal(t, 1, first)
+	assert.Equal(t, 2, second)
+}
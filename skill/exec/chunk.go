@@ -14,6 +14,7 @@ type (
 
 	chunkOptions struct {
 		chunkSize     int
+		chunkTasks    int // 任务数也达到上限时执行，0代表不限制
 		flushInterval time.Duration
 	}
 )
@@ -27,6 +28,7 @@ func NewChunk(execute FuncExecute, opts ...ChunkOption) *Chunk {
 	container := &chunkContainer{
 		execute:      execute,
 		maxChunkSize: options.chunkSize,
+		maxTasks:     options.chunkTasks,
 	}
 	executor := &Chunk{
 		executor:  NewInterval(options.flushInterval, container),
@@ -58,6 +60,12 @@ func WithChunkBytes(size int) ChunkOption {
 	}
 }
 
+func WithChunkTasks(tasks int) ChunkOption {
+	return func(options *chunkOptions) {
+		options.chunkTasks = tasks
+	}
+}
+
 func WithFlushInterval(duration time.Duration) ChunkOption {
 	return func(options *chunkOptions) {
 		options.flushInterval = duration
@@ -76,13 +84,14 @@ type chunkContainer struct {
 	execute      FuncExecute
 	size         int
 	maxChunkSize int
+	maxTasks     int
 }
 
 func (bc *chunkContainer) AddItem(task any) bool {
 	ck := task.(chunk)
 	bc.tasks = append(bc.tasks, ck.val)
 	bc.size += ck.size
-	return bc.size >= bc.maxChunkSize
+	return bc.size >= bc.maxChunkSize || (bc.maxTasks > 0 && len(bc.tasks) >= bc.maxTasks)
 }
 
 func (bc *chunkContainer) Execute(tasks any) {
@@ -0,0 +1,38 @@
+package exec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChunkContainer(t *testing.T) {
+	var executed []any
+	bc := &chunkContainer{maxChunkSize: 10, maxTasks: 3, execute: func(tasks []any) { executed = tasks }}
+
+	// 字节数达到上限
+	if bc.AddItem(chunk{val: 1, size: 4}) || !bc.AddItem(chunk{val: 2, size: 6}) {
+		t.Fatal("should be full when size reaches maxChunkSize")
+	}
+	tasks := bc.RemoveAll()
+	if !reflect.DeepEqual(tasks, []any{1, 2}) || bc.size != 0 || bc.tasks != nil {
+		t.Fatalf("RemoveAll got %v, size %d", tasks, bc.size)
+	}
+	bc.Execute(tasks)
+	if !reflect.DeepEqual(executed, []any{1, 2}) {
+		t.Fatalf("Execute got %v", executed)
+	}
+
+	// 任务数达到上限
+	if bc.AddItem(chunk{val: 1, size: 1}) || bc.AddItem(chunk{val: 2, size: 1}) || !bc.AddItem(chunk{val: 3, size: 1}) {
+		t.Fatal("should be full when tasks reach maxTasks")
+	}
+	bc.RemoveAll()
+
+	// maxTasks 为0不限制任务数
+	bc.maxTasks = 0
+	for i := 0; i < 9; i++ {
+		if bc.AddItem(chunk{val: i, size: 1}) {
+			t.Fatalf("should not be full at %d tasks", i+1)
+		}
+	}
+}
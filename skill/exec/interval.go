@@ -36,7 +36,7 @@ type (
 		taskLock sync.Mutex
 		execWG   sync.WaitGroup
 
-		isRunning bool
+		isRunning int32 // 1：后台循环在运行；checkLoop 不加锁读取，所以用原子操作
 		runLock   sync.Mutex
 	}
 )
@@ -121,7 +121,7 @@ func (run *Interval) AddByFunc(fc AddFunc, item any) {
 // 有增加任务的动作，就要想办法激活循环检测
 // 因为这个没有加锁运行，所以在添加任务的时候要前后都检测一次。
 func (run *Interval) checkLoop() {
-	if run.isRunning == false {
+	if atomic.LoadInt32(&run.isRunning) == 0 {
 		run.raiseLoop()
 	}
 }
@@ -129,11 +129,11 @@ func (run *Interval) checkLoop() {
 // 后台启动新的协程运行周期任务
 func (run *Interval) raiseLoop() {
 	run.runLock.Lock()
-	if run.isRunning {
+	if run.isRunning == 1 {
 		run.runLock.Unlock()
 		return
 	}
-	run.isRunning = true
+	atomic.StoreInt32(&run.isRunning, 1)
 	run.runLock.Unlock()
 
 	gmp.GoSafe(func() {
@@ -177,7 +177,7 @@ func (run *Interval) quitLoop(last time.Duration) (stop bool) {
 
 	run.runLock.Lock()
 	if atomic.LoadInt32(&run.inflight) == 0 {
-		run.isRunning = false
+		atomic.StoreInt32(&run.isRunning, 0)
 		stop = true
 	}
 	run.runLock.Unlock()
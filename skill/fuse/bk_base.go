@@ -18,6 +18,7 @@ type (
 		Errors(join string) string // reason
 
 		Allow() error         //
+		DropRatio() float64   // 当前窗口的熔断比率，0 表示没有熔断
 		Accept()              // allow successful.
 		Reject(reason string) // allow failed.
 
@@ -32,6 +33,7 @@ type (
 
 	throttle interface {
 		allow() error
+		dropRatio() float64
 		doReq(req funcReq, fb funcFallback, cpt funcAcceptable) error
 		markValue(val float64)
 	}
@@ -50,6 +50,10 @@ func (ab *autoBreaker) Allow() error {
 	return ab.throttle.allow()
 }
 
+func (ab *autoBreaker) DropRatio() float64 {
+	return ab.throttle.dropRatio()
+}
+
 func (ab *autoBreaker) AcceptValue(v float64) {
 	ab.throttle.markValue(v)
 }
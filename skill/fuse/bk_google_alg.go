@@ -78,11 +78,7 @@ func (gtl *googleThrottle) markValue(val float64) {
 // 是否接收本次请求
 // 谷歌公布的一段熔断算法：max(0, (requests - k*accepts) / (requests + 1))
 func (gtl *googleThrottle) accept() error {
-	accepts, total := gtl.sWin.CurrWin()
-
-	// https://landing.google.com/sre/sre-book/chapters/handling-overload/#eq2101
-	// 比例(k-1)/k的请求出现错误，才会进入熔断的判断。如k=1.5时，失败达到33.3%以上可能熔断
-	dropRatio := math.Max(0, (float64(total-protection)-gtl.k*accepts)/float64(total+1)) // 出错概率值[0,1)之间
+	dropRatio := gtl.dropRatio()
 	if dropRatio <= 0 {
 		return nil
 	}
@@ -94,3 +90,10 @@ func (gtl *googleThrottle) accept() error {
 	}
 	return nil
 }
+
+// https://landing.google.com/sre/sre-book/chapters/handling-overload/#eq2101
+// 比例(k-1)/k的请求出现错误，才会进入熔断的判断。如k=1.5时，失败达到33.3%以上可能熔断
+func (gtl *googleThrottle) dropRatio() float64 {
+	accepts, total := gtl.sWin.CurrWin()
+	return math.Max(0, (float64(total-protection)-gtl.k*accepts)/float64(total+1)) // 出错概率值[0,1)之间
+}
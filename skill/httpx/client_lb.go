@@ -0,0 +1,59 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package httpx
+
+import (
+	"context"
+	"errors"
+	"github.com/qinchende/gofast/cst"
+	"github.com/qinchende/gofast/skill/discov"
+	"net/http"
+)
+
+// 5xx 被认为是实例自身的问题，P2C 据此降低该实例的优先级
+var errServerStatus = errors.New("httpx: server error status")
+
+// 带服务发现和负载均衡的 client，请求 Url 中的 host 会被替换成选中实例的地址
+// 比如：Url = "http://user-svc/user/info"，实际请求 "http://10.0.0.1:8080/user/info"
+type LbClient struct {
+	HttpClient
+	bl discov.Balancer
+}
+
+func NewLbClient(r discov.Resolver, b discov.Balancer) *LbClient {
+	return &LbClient{bl: discov.Bind(r, b)}
+}
+
+// key 只在一致性 hash 时有意义，比如传 user_id 让同一个用户的请求落在同一个实例
+func (cli *LbClient) DoKey(req *http.Request, key string) (*http.Response, error) {
+	ep, done, err := cli.bl.Pick(key)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.Host = ep.Addr
+	req.Host = ep.Addr
+
+	resp, err := cli.Client.Do(req)
+	if err == nil && resp.StatusCode >= http.StatusInternalServerError {
+		done(errServerStatus)
+	} else {
+		done(err)
+	}
+	return resp, err
+}
+
+func (cli *LbClient) Do(req *http.Request) (*http.Response, error) {
+	return cli.DoKey(req, "")
+}
+
+func (cli *LbClient) DoGetKV(req *http.Request) (cst.KV, error) {
+	return parseJsonResponse(cli.Do(req))
+}
+
+func (cli *LbClient) DoRequestCtx(ctx context.Context, pet *RequestPet, key string) (*http.Response, error) {
+	if req, err := buildRequest(ctx, pet); err != nil {
+		return nil, err
+	} else {
+		return cli.DoKey(req, key)
+	}
+}
@@ -32,3 +32,19 @@ type (
 		includeMax bool    // 包括最大
 	}
 )
+
+func (nr *numRange) Max() float64 {
+	return nr.max
+}
+
+func (nr *numRange) Min() float64 {
+	return nr.min
+}
+
+func (nr *numRange) IncludeMin() bool {
+	return nr.includeMin
+}
+
+func (nr *numRange) IncludeMax() bool {
+	return nr.includeMax
+}
@@ -21,6 +21,14 @@ var regexMap = map[string]*regexp.Regexp{
 	"base64URL": regexp.MustCompile(base64URLRegexString),
 }
 
+// 内置 match 格式对应的正则表达式，没有的返回空字符串
+func MatchRegex(name string) string {
+	if reg := regexMap[name]; reg != nil {
+		return reg.String()
+	}
+	return ""
+}
+
 func ValidateField(fValue reflect.Value, fOpts *FieldOpts) error {
 	if fOpts == nil {
 		return nil
@@ -1,6 +1,16 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
 package cache
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("cache: key not found")
+	ErrNotPointer = errors.New("cache: dest value must be a non-nil pointer")
+)
 
 type (
 	// Cache interface is used to define the cache implementation.
@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"github.com/qinchende/gofast/skill/timex"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newMem(t *testing.T, limit int) *MemCache {
+	mc, err := NewMemCache(&MemCnf{Name: t.Name(), Limit: limit, ExpireS: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mc
+}
+
+func TestMemCacheExpire(t *testing.T) {
+	mc := newMem(t, 0)
+	_ = mc.SetExpire("short", "v", 30*time.Millisecond)
+	_ = mc.Set("long", "v")
+
+	var str string
+	if err := mc.Get("short", &str); err != nil || str != "v" {
+		t.Fatalf("got %q %v", str, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := mc.Get("short", &str); err != ErrNotFound {
+		t.Fatalf("expired key got %v", err)
+	}
+	if err := mc.Get("long", &str); err != nil {
+		t.Fatalf("long key got %v", err)
+	}
+
+	// 超过默认过期时间的按默认时间算，0代表默认时间
+	_ = mc.SetExpire("max", "v", time.Hour)
+	_ = mc.SetExpire("zero", "v", 0)
+	for _, key := range []string{"max", "zero"} {
+		item, ok := mc.getItem(key)
+		if !ok || item.expire-timex.Now().Milliseconds() > 60*1000 {
+			t.Fatalf("%s expire not capped", key)
+		}
+	}
+}
+
+func TestMemCacheEvict(t *testing.T) {
+	mc := newMem(t, 2)
+	_ = mc.Set("a", 1)
+	_ = mc.Set("b", 2)
+	var v int
+	_ = mc.Get("a", &v) // a 最近用过，淘汰 b
+	_ = mc.Set("c", 3)
+
+	if err := mc.Get("b", &v); err != ErrNotFound {
+		t.Fatalf("b should be evicted, got %v", err)
+	}
+	for key, want := range map[string]int{"a": 1, "c": 3} {
+		if err := mc.Get(key, &v); err != nil || v != want {
+			t.Fatalf("%s got %d %v", key, v, err)
+		}
+	}
+
+	_ = mc.Del("a", "c", "none")
+	if err := mc.Get("a", &v); err != ErrNotFound {
+		t.Fatalf("deleted key got %v", err)
+	}
+}
+
+// 模拟 redis：只存字符串，记录访问次数
+type strCache struct {
+	mu   sync.Mutex
+	data map[string]string
+	gets int
+}
+
+func (sc *strCache) Del(keys ...string) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for _, k := range keys {
+		delete(sc.data, k)
+	}
+	return nil
+}
+
+func (sc *strCache) Get(key string, v any) error {
+	sc.mu.Lock()
+	sc.gets++
+	str, ok := sc.data[key]
+	sc.mu.Unlock()
+	if !ok {
+		return ErrNotFound
+	}
+	return decodeValue(str, v)
+}
+
+func (sc *strCache) Set(key string, v any) error {
+	return sc.SetExpire(key, v, 0)
+}
+
+func (sc *strCache) SetExpire(key string, v any, _ time.Duration) error {
+	str, err := encodeValue(v)
+	if err != nil {
+		return err
+	}
+	sc.mu.Lock()
+	sc.data[key] = str
+	sc.mu.Unlock()
+	return nil
+}
+
+type gUser struct {
+	ID   int64    `json:"id"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+func TestTwoLevelReadThrough(t *testing.T) {
+	l2 := &strCache{data: map[string]string{"u1": `{"id":1,"name":"amy","tags":["a"]}`}}
+	tc := NewTwoLevelCache(newMem(t, 0), l2, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		var u gUser
+		if err := tc.Get("u1", &u); err != nil || u.Name != "amy" || !reflect.DeepEqual(u.Tags, []string{"a"}) {
+			t.Fatalf("round %d got %+v %v", i, u, err)
+		}
+	}
+	if l2.gets != 1 {
+		t.Fatalf("L2 read %d times, want 1", l2.gets)
+	}
+
+	var u gUser
+	if err := tc.Get("none", &u); err != ErrNotFound {
+		t.Fatalf("missing key got %v", err)
+	}
+}
+
+func TestTwoLevelSetAndDel(t *testing.T) {
+	l1 := newMem(t, 0)
+	l2 := &strCache{data: map[string]string{}}
+	tc := NewTwoLevelCache(l1, l2, time.Minute)
+
+	_ = tc.SetExpire("u1", &gUser{ID: 1, Name: "amy"}, 20*time.Millisecond)
+	if l2.data["u1"] != `{"id":1,"name":"amy","tags":null}` {
+		t.Fatalf("L2 got %q", l2.data["u1"])
+	}
+	// L1 的过期时间不超过设置的时间
+	time.Sleep(30 * time.Millisecond)
+	var u gUser
+	if err := l1.Get("u1", &u); err != ErrNotFound {
+		t.Fatalf("L1 should expire with the key, got %v", err)
+	}
+
+	_ = tc.Set("u2", "v2")
+	if err := tc.Del("u1", "u2"); err != nil {
+		t.Fatal(err)
+	}
+	var str string
+	for _, c := range []Cache{l1, l2, tc} {
+		if err := c.Get("u2", &str); err != ErrNotFound {
+			t.Fatalf("%T after Del got %q %v", c, str, err)
+		}
+	}
+}
+
+func TestGsonRoundTrip(t *testing.T) {
+	u := gUser{ID: 7, Name: "amy", Tags: []string{"x", "y"}}
+	str, err := encodeValue(&u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got gUser
+	if err = decodeValue(str, &got); err != nil || !reflect.DeepEqual(got, u) {
+		t.Fatalf("got %+v %v", got, err)
+	}
+
+	// string 和 []byte 原样存取
+	if str, _ = encodeValue("raw"); str != "raw" {
+		t.Fatalf("string got %q", str)
+	}
+	if str, _ = encodeValue([]byte("raw")); str != "raw" {
+		t.Fatalf("bytes got %q", str)
+	}
+	var bs []byte
+	if err = decodeValue("raw", &bs); err != nil || string(bs) != "raw" {
+		t.Fatalf("bytes got %q %v", bs, err)
+	}
+	if err = decodeValue(str, got); err != ErrNotPointer {
+		t.Fatalf("non pointer got %v", err)
+	}
+
+	// 类型一致直接赋值，不一致时走一遍序列化
+	var same gUser
+	if err = assignValue(u, &same); err != nil || !reflect.DeepEqual(same, u) {
+		t.Fatalf("assign same got %+v %v", same, err)
+	}
+	var conv gUser
+	if err = assignValue(map[string]any{"id": 8, "name": "bob"}, &conv); err != nil || conv.ID != 8 || conv.Name != "bob" {
+		t.Fatalf("assign convert got %+v %v", conv, err)
+	}
+	if err = assignValue(u, nil); err != ErrNotPointer {
+		t.Fatalf("nil dest got %v", err)
+	}
+}
@@ -1 +1,58 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
 package cache
+
+import (
+	"github.com/qinchende/gofast/skill/jsonx"
+	"github.com/qinchende/gofast/skill/lang"
+	"reflect"
+)
+
+// 写入外部存储前的序列化：string和[]byte原样存储，其它类型转成JSON
+func encodeValue(v any) (string, error) {
+	switch val := v.(type) {
+	case string:
+		return val, nil
+	case []byte:
+		return lang.BytesToString(val), nil
+	}
+	bs, err := jsonx.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return lang.BytesToString(bs), nil
+}
+
+// 从外部存储读出后反序列化，目标是*string和*[]byte时直接赋值
+func decodeValue(str string, v any) error {
+	switch dst := v.(type) {
+	case *string:
+		*dst = str
+		return nil
+	case *[]byte:
+		*dst = []byte(str)
+		return nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return ErrNotPointer
+	}
+	return jsonx.UnmarshalFromString(v, str)
+}
+
+// 内存缓存中的值拷贝给目标对象，类型一致时直接赋值，否则走一遍序列化
+func assignValue(src, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return ErrNotPointer
+	}
+	sv := reflect.ValueOf(src)
+	if sv.IsValid() && sv.Type().AssignableTo(rv.Elem().Type()) {
+		rv.Elem().Set(sv)
+		return nil
+	}
+	str, err := encodeValue(src)
+	if err != nil {
+		return err
+	}
+	return decodeValue(str, v)
+}
@@ -1,12 +1,71 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
 package cache
 
-var gfMemCache map[string]MemItem
+import (
+	"github.com/qinchende/gofast/skill/collect"
+	"github.com/qinchende/gofast/skill/timex"
+	"time"
+)
 
-func init() {
-	gfMemCache = make(map[string]MemItem, 0)
+// 进程内缓存，LRU淘汰 + 过期时间。能直接存取对象，不需要序列化，但无法做到分布式一致
+// 注意：直接存取对象时缓存和调用方共享同一份数据，取出后不要修改其内部字段
+type MemCache struct {
+	store  *collect.Cache
+	expire time.Duration
+}
+
+type MemCnf struct {
+	Name    string `v:"def=mem"`
+	Limit   int    `v:"def=10000,range=[0:]"` // 最多缓存多少个Key，0代表不限制
+	ExpireS int64  `v:"def=3600,range=[1:]"`  // 默认和最大过期时间
 }
 
 type MemItem struct {
-	expire uint64
+	expire int64 // 过期时间点（毫秒）
 	Val    any
 }
+
+func NewMemCache(cnf *MemCnf) (*MemCache, error) {
+	opts := []collect.CacheOption{collect.WithName(cnf.Name)}
+	if cnf.Limit > 0 {
+		opts = append(opts, collect.WithLimit(cnf.Limit))
+	}
+	if cnf.ExpireS <= 0 {
+		cnf.ExpireS = 3600
+	}
+	expire := time.Duration(cnf.ExpireS) * time.Second
+	store, err := collect.NewCache(expire, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &MemCache{store: store, expire: expire}, nil
+}
+
+func (mc *MemCache) Del(keys ...string) error {
+	for _, key := range keys {
+		mc.store.Del(key)
+	}
+	return nil
+}
+
+func (mc *MemCache) Get(key string, v any) error {
+	item, ok := mc.getItem(key)
+	if !ok {
+		return ErrNotFound
+	}
+	return assignValue(item.Val, v)
+}
+
+func (mc *MemCache) Set(key string, v any) error {
+	return mc.SetExpire(key, v, mc.expire)
+}
+
+// 过期时间不能超过底层 collect.Cache 的统一过期时间，超过的按统一时间算
+func (mc *MemCache) SetExpire(key string, v any, expire time.Duration) error {
+	if expire <= 0 || expire > mc.expire {
+		expire = mc.expire
+	}
+	mc.store.Set(key, &MemItem{expire: timex.Now().Milliseconds() + expire.Milliseconds(), Val: v})
+	return nil
+}
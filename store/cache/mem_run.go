@@ -1 +1,21 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
 package cache
+
+import (
+	"github.com/qinchende/gofast/skill/timex"
+)
+
+// 底层 collect.Cache 只按统一时间清理，单个Key的过期时间在读取时判断
+func (mc *MemCache) getItem(key string) (*MemItem, bool) {
+	val, ok := mc.store.Get(key)
+	if !ok {
+		return nil, false
+	}
+	item := val.(*MemItem)
+	if item.expire <= timex.Now().Milliseconds() {
+		mc.store.Del(key)
+		return nil, false
+	}
+	return item, true
+}
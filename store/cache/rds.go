@@ -1 +1,54 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
 package cache
+
+import (
+	"github.com/go-redis/redis/v8"
+	"github.com/qinchende/gofast/connx/gfrds"
+	"time"
+)
+
+// 基于 redis 的分布式缓存，值需要序列化，string和[]byte原样存储
+type RedisCache struct {
+	rds    *gfrds.GfRedis
+	expire time.Duration
+}
+
+// expire 是 Set 时的默认过期时间，0代表永不过期
+func NewRedisCache(rds *gfrds.GfRedis, expire time.Duration) *RedisCache {
+	return &RedisCache{rds: rds, expire: expire}
+}
+
+func (rc *RedisCache) Redis() *gfrds.GfRedis {
+	return rc.rds
+}
+
+func (rc *RedisCache) Del(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return rc.rds.Cli.Del(rc.rds.Ctx, keys...).Err()
+}
+
+func (rc *RedisCache) Get(key string, v any) error {
+	str, err := rc.rds.Get(key)
+	if err == redis.Nil {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	return decodeValue(str, v)
+}
+
+func (rc *RedisCache) Set(key string, v any) error {
+	return rc.SetExpire(key, v, rc.expire)
+}
+
+func (rc *RedisCache) SetExpire(key string, v any, expire time.Duration) error {
+	str, err := encodeValue(v)
+	if err != nil {
+		return err
+	}
+	_, err = rc.rds.Set(key, str, expire)
+	return err
+}
@@ -0,0 +1,157 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"github.com/qinchende/gofast/connx/gfrds"
+	"github.com/qinchende/gofast/logx"
+	"github.com/qinchende/gofast/skill/gmp"
+	"github.com/qinchende/gofast/skill/hash"
+	"sync"
+	"time"
+)
+
+const (
+	shardCheckInterval = 3 * time.Second // 节点健康检查周期
+	shardMaxFails      = 3               // 连续失败这么多次就把节点摘掉
+)
+
+var ErrNoRedisNode = errors.New("cache: no available redis node")
+
+// 多个redis节点组成的分片缓存，Key通过一致性hash分布到各个节点
+// 后台定时Ping所有节点，连续失败的节点从hash环上摘掉，恢复之后再加回来
+// 节点列表创建之后不再变化，fails和online只在健康检查协程中读写，hash环自带锁
+type ShardRedisCache struct {
+	ring   *hash.ConsistentHash
+	nodes  []*shardNode
+	byName map[string]*RedisCache
+	expire time.Duration
+	quit   chan struct{}
+	once   sync.Once
+}
+
+type shardNode struct {
+	name   string
+	weight int
+	rc     *RedisCache
+	fails  int
+	online bool
+}
+
+func NewShardRedisCache(nodes []*gfrds.GfRedis, expire time.Duration) *ShardRedisCache {
+	sc := &ShardRedisCache{
+		ring:   hash.NewConsistentHash(),
+		nodes:  make([]*shardNode, len(nodes)),
+		byName: make(map[string]*RedisCache, len(nodes)),
+		expire: expire,
+		quit:   make(chan struct{}),
+	}
+	for i, rds := range nodes {
+		nd := &shardNode{
+			name:   fmt.Sprintf("%d#%s", i, rds.Cli.Options().Addr),
+			weight: int(rds.Weight),
+			rc:     NewRedisCache(rds, expire),
+			online: true,
+		}
+		if nd.weight <= 0 || nd.weight > hash.TopWeight {
+			nd.weight = hash.TopWeight
+		}
+		sc.nodes[i] = nd
+		sc.byName[nd.name] = nd.rc
+		sc.ring.AddWithWeight(nd.name, nd.weight)
+	}
+	gmp.GoSafe(sc.healthLoop)
+	return sc
+}
+
+// 停止健康检查
+func (sc *ShardRedisCache) Close() {
+	sc.once.Do(func() {
+		close(sc.quit)
+	})
+}
+
+func (sc *ShardRedisCache) Del(keys ...string) error {
+	groups := make(map[*RedisCache][]string)
+	for _, key := range keys {
+		rc, err := sc.pick(key)
+		if err != nil {
+			return err
+		}
+		groups[rc] = append(groups[rc], key)
+	}
+
+	var lastErr error
+	for rc, ks := range groups {
+		if err := rc.Del(ks...); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+func (sc *ShardRedisCache) Get(key string, v any) error {
+	rc, err := sc.pick(key)
+	if err != nil {
+		return err
+	}
+	return rc.Get(key, v)
+}
+
+func (sc *ShardRedisCache) Set(key string, v any) error {
+	return sc.SetExpire(key, v, sc.expire)
+}
+
+func (sc *ShardRedisCache) SetExpire(key string, v any, expire time.Duration) error {
+	rc, err := sc.pick(key)
+	if err != nil {
+		return err
+	}
+	return rc.SetExpire(key, v, expire)
+}
+
+func (sc *ShardRedisCache) pick(key string) (*RedisCache, error) {
+	if name, ok := sc.ring.Get(key); ok {
+		return sc.byName[name.(string)], nil
+	}
+	return nil, ErrNoRedisNode
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+func (sc *ShardRedisCache) healthLoop() {
+	ticker := time.NewTicker(shardCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sc.quit:
+			return
+		case <-ticker.C:
+			sc.checkNodes()
+		}
+	}
+}
+
+func (sc *ShardRedisCache) checkNodes() {
+	for _, nd := range sc.nodes {
+		_, err := nd.rc.Redis().Ping()
+		if err == nil {
+			nd.fails = 0
+			if !nd.online {
+				nd.online = true
+				sc.ring.AddWithWeight(nd.name, nd.weight)
+				logx.InfoF("Redis cache node %s recovered, added back to the ring.", nd.name)
+			}
+			continue
+		}
+
+		nd.fails++
+		if nd.online && nd.fails >= shardMaxFails {
+			nd.online = false
+			sc.ring.Remove(nd.name)
+			logx.ErrorF("Redis cache node %s removed from the ring: %s", nd.name, err)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package cache
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/qinchende/gofast/connx/gfrds"
+	"github.com/qinchende/gofast/logx"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func init() {
+	logx.MustSetup(&logx.LogConfig{LogMedium: "console", LogLevel: "error", LogStyle: "sdx"})
+}
+
+// 只会回复 PONG 的假 redis，健康检查用。stop 之后连接全部断开，start 可以在原地址上重新启动
+type pongServer struct {
+	addr  string
+	mu    sync.Mutex
+	ln    net.Listener
+	conns []net.Conn
+}
+
+func newPongServer(t *testing.T) *pongServer {
+	ps := &pongServer{addr: "127.0.0.1:0"}
+	ps.start(t)
+	ps.addr = ps.ln.Addr().String()
+	t.Cleanup(ps.stop)
+	return ps
+}
+
+func (ps *pongServer) start(t *testing.T) {
+	ln, err := net.Listen("tcp", ps.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps.mu.Lock()
+	ps.ln = ln
+	ps.mu.Unlock()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			ps.mu.Lock()
+			ps.conns = append(ps.conns, conn)
+			ps.mu.Unlock()
+			go func() {
+				buf := make([]byte, 1024)
+				for {
+					if _, err := conn.Read(buf); err != nil {
+						return
+					}
+					if _, err := conn.Write([]byte("+PONG\r\n")); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+}
+
+func (ps *pongServer) stop() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	_ = ps.ln.Close()
+	for _, c := range ps.conns {
+		_ = c.Close()
+	}
+	ps.conns = nil
+}
+
+func newShard(t *testing.T, addrs ...string) *ShardRedisCache {
+	nodes := make([]*gfrds.GfRedis, len(addrs))
+	for i, addr := range addrs {
+		nodes[i] = &gfrds.GfRedis{
+			Cli: redis.NewClient(&redis.Options{Addr: addr, MaxRetries: -1}),
+			Ctx: context.Background(),
+		}
+	}
+	sc := NewShardRedisCache(nodes, 0)
+	sc.Close() // 测试中手动调用 checkNodes
+	return sc
+}
+
+func shardOf(t *testing.T, sc *ShardRedisCache, key string) string {
+	rc, err := sc.pick(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, nd := range sc.nodes {
+		if nd.rc == rc {
+			return nd.name
+		}
+	}
+	t.Fatalf("key %s picked an unknown node", key)
+	return ""
+}
+
+func TestShardStable(t *testing.T) {
+	addrs := []string{"10.0.0.1:6379", "10.0.0.2:6379", "10.0.0.3:6379"}
+	sc1, sc2 := newShard(t, addrs...), newShard(t, addrs...)
+
+	used := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		key := "Gf#Pet#" + strconv.Itoa(i)
+		n1 := shardOf(t, sc1, key)
+		if n2 := shardOf(t, sc1, key); n2 != n1 {
+			t.Fatalf("key %s moved from %s to %s", key, n1, n2)
+		}
+		// 相同的节点配置，不同进程中的分布一样
+		if n3 := shardOf(t, sc2, key); n3 != n1 {
+			t.Fatalf("key %s on %s, another instance %s", key, n1, n3)
+		}
+		used[n1]++
+	}
+	if len(used) != len(addrs) {
+		t.Fatalf("keys not spread over all nodes: %v", used)
+	}
+}
+
+func TestShardEjectAndRecover(t *testing.T) {
+	s1, s2, s3 := newPongServer(t), newPongServer(t), newPongServer(t)
+	sc := newShard(t, s1.addr, s2.addr, s3.addr)
+	dead := sc.nodes[1].name
+
+	keys := make([]string, 300)
+	before := make(map[string]string, len(keys))
+	for i := range keys {
+		keys[i] = "key#" + strconv.Itoa(i)
+		before[keys[i]] = shardOf(t, sc, keys[i])
+	}
+
+	// 连续失败 shardMaxFails 次才摘掉
+	s2.stop()
+	for i := 0; i < shardMaxFails; i++ {
+		if !sc.nodes[1].online {
+			t.Fatalf("node removed after %d fails", i)
+		}
+		sc.checkNodes()
+	}
+	if sc.nodes[1].online || !sc.nodes[0].online || !sc.nodes[2].online {
+		t.Fatal("only the failed node should be removed")
+	}
+
+	moved := 0
+	for _, key := range keys {
+		now := shardOf(t, sc, key)
+		switch {
+		case before[key] == dead:
+			if now == dead {
+				t.Fatalf("key %s still on removed node", key)
+			}
+			moved++
+		case now != before[key]:
+			t.Fatalf("key %s on healthy node %s moved to %s", key, before[key], now)
+		}
+	}
+	if moved == 0 {
+		t.Fatal("no key was on the removed node")
+	}
+
+	// 恢复之后回到原来的节点
+	s2.start(t)
+	sc.checkNodes()
+	if !sc.nodes[1].online {
+		t.Fatal("node not recovered")
+	}
+	for _, key := range keys {
+		if now := shardOf(t, sc, key); now != before[key] {
+			t.Fatalf("key %s on %s after recover, want %s", key, now, before[key])
+		}
+	}
+}
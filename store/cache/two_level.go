@@ -0,0 +1,65 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package cache
+
+import (
+	"github.com/qinchende/gofast/skill/syncx"
+	"time"
+)
+
+// 两级缓存：L1进程内存 + L2分布式缓存（一般是redis）
+// L1只能通过较短的过期时间来保证和L2的最终一致，其它节点删除Key时本节点的L1并不会立即失效
+type TwoLevelCache struct {
+	l1       *MemCache
+	l2       Cache
+	l1Expire time.Duration
+	barrier  syncx.SharedCalls
+}
+
+// l1Expire 是L1的最长过期时间，一般设置为几秒到几十秒
+func NewTwoLevelCache(l1 *MemCache, l2 Cache, l1Expire time.Duration) *TwoLevelCache {
+	return &TwoLevelCache{l1: l1, l2: l2, l1Expire: l1Expire, barrier: syncx.NewSharedCalls()}
+}
+
+func (tc *TwoLevelCache) Del(keys ...string) error {
+	_ = tc.l1.Del(keys...)
+	return tc.l2.Del(keys...)
+}
+
+// L1没有命中时，相同Key的并发请求只会有一个去访问L2
+func (tc *TwoLevelCache) Get(key string, v any) error {
+	if err := tc.l1.Get(key, v); err != ErrNotFound {
+		return err
+	}
+
+	val, err := tc.barrier.Do(key, func() (any, error) {
+		var str string
+		if err := tc.l2.Get(key, &str); err != nil {
+			return nil, err
+		}
+		_ = tc.l1.SetExpire(key, str, tc.l1Expire)
+		return str, nil
+	})
+	if err != nil {
+		return err
+	}
+	return decodeValue(val.(string), v)
+}
+
+func (tc *TwoLevelCache) Set(key string, v any) error {
+	if err := tc.l2.Set(key, v); err != nil {
+		return err
+	}
+	return tc.l1.SetExpire(key, v, tc.l1Expire)
+}
+
+func (tc *TwoLevelCache) SetExpire(key string, v any, expire time.Duration) error {
+	if err := tc.l2.SetExpire(key, v, expire); err != nil {
+		return err
+	}
+	l1Expire := tc.l1Expire
+	if expire > 0 && expire < l1Expire {
+		l1Expire = expire
+	}
+	return tc.l1.SetExpire(key, v, l1Expire)
+}
@@ -0,0 +1,296 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package migrate
+
+import (
+	"fmt"
+	"github.com/qinchende/gofast/cst"
+	"github.com/qinchende/gofast/skill/valid"
+	"github.com/qinchende/gofast/store/orm"
+	"github.com/qinchende/gofast/store/sqlx"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	dbConfigTag     = "dbc"    // 和 orm 中的 dbc 标记相同
+	dbIndexFlag     = "index"  // 普通索引：dbc:"index" 或者 dbc:"index=idx_name"，同名的字段组成联合索引
+	dbUniqueFlag    = "unique" // 唯一索引：dbc:"unique" 或者 dbc:"unique=uk_name"
+	defVarcharLen   = 255      // 字符串字段默认长度，可以用 v:"len=[0:64]" 指定
+	maxVarcharLen   = 16383    // 超过这个长度的字符串字段用 TEXT
+	mysqlTableOpts  = " ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"
+	defNowValue     = "now" // 时间字段 v:"def=now" 代表默认当前时间
+	sqlCurrentStamp = "CURRENT_TIMESTAMP"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type (
+	tableDef struct {
+		name    string
+		pk      string
+		columns []columnDef
+		indexes []indexDef
+	}
+
+	columnDef struct {
+		name    string
+		sqlType string
+		notNull bool
+		defVal  string // 已经格式化好的默认值，空字符串代表没有默认值
+		auto    bool
+	}
+
+	indexDef struct {
+		name   string
+		unique bool
+		cols   []string
+	}
+)
+
+// 根据 Model 生成建表语句，第一条是 CREATE TABLE，后面是建索引的语句
+func CreateTableSqls(d sqlx.Dialect, obj any) []string {
+	return parseModel(d, obj).createSqls(d)
+}
+
+// 解析 Model 对应的表结构：字段类型来自Go类型，默认值和字符串长度来自 v 标记，索引来自 dbc 标记
+func parseModel(d sqlx.Dialect, obj any) *tableDef {
+	sm := orm.Schema(obj)
+	rTyp := reflect.TypeOf(obj)
+	for rTyp.Kind() == reflect.Ptr {
+		rTyp = rTyp.Elem()
+	}
+
+	cls := sm.Columns()
+	td := &tableDef{name: sm.TableName(), pk: cls[sm.PrimaryIndex()]}
+	idxKV := make(map[string]int)
+	for i := range cls {
+		fi := rTyp.FieldByIndex(sm.FieldIndex(int8(i)))
+		opts, err := valid.ParseOptions(&fi, fi.Tag.Get(cst.FieldValidTag))
+		if err != nil {
+			panic(err)
+		}
+
+		cd := columnDef{name: cls[i], auto: int8(i) == sm.AutoIndex()}
+		fTyp := fi.Type
+		if fTyp.Kind() == reflect.Ptr {
+			fTyp = fTyp.Elem()
+		} else {
+			cd.notNull = true
+		}
+		cd.sqlType = columnType(d.Name(), fTyp, opts, cd.auto)
+		if opts != nil && opts.DefValue != "" && !cd.auto {
+			cd.defVal = defaultValue(fTyp, opts.DefValue)
+		}
+		td.columns = append(td.columns, cd)
+
+		// 索引
+		for _, item := range strings.Split(fi.Tag.Get(dbConfigTag), ",") {
+			kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+			if kv[0] != dbIndexFlag && kv[0] != dbUniqueFlag {
+				continue
+			}
+			unique := kv[0] == dbUniqueFlag
+			name := ""
+			if len(kv) == 2 {
+				name = strings.TrimSpace(kv[1])
+			}
+			if name == "" {
+				name = indexName(td.name, unique, cls[i])
+			}
+			if pos, ok := idxKV[name]; ok {
+				td.indexes[pos].cols = append(td.indexes[pos].cols, cls[i])
+				continue
+			}
+			idxKV[name] = len(td.indexes)
+			td.indexes = append(td.indexes, indexDef{name: name, unique: unique, cols: []string{cls[i]}})
+		}
+	}
+	return td
+}
+
+func indexName(table string, unique bool, col string) string {
+	if unique {
+		return "uk_" + table + "_" + col
+	}
+	return "idx_" + table + "_" + col
+}
+
+func (td *tableDef) createSqls(d sqlx.Dialect) []string {
+	sBuf := strings.Builder{}
+	sBuf.Grow(512)
+	sBuf.WriteString("CREATE TABLE IF NOT EXISTS " + td.name + " (\n")
+
+	inlinePk := false
+	for i := range td.columns {
+		sBuf.WriteString("  " + td.columns[i].define(d.Name()))
+		sBuf.WriteString(",\n")
+		// SQLite 的自增字段必须写成 INTEGER PRIMARY KEY AUTOINCREMENT
+		if td.columns[i].auto && d.Name() == sqlx.DriverSqlite {
+			inlinePk = true
+		}
+	}
+	ddl := strings.TrimSuffix(sBuf.String(), ",\n")
+	if !inlinePk {
+		ddl += ",\n  PRIMARY KEY (" + td.pk + ")"
+	}
+	ddl += "\n)"
+	if d.Name() == sqlx.DriverMysql {
+		ddl += mysqlTableOpts
+	}
+
+	sqls := []string{ddl + ";"}
+	for i := range td.indexes {
+		sqls = append(sqls, td.indexes[i].createSql(d.Name(), td.name))
+	}
+	return sqls
+}
+
+func (cd *columnDef) define(dn string) string {
+	def := cd.name + " " + cd.sqlType
+	if cd.auto && dn != sqlx.DriverMysql {
+		return def
+	}
+	if cd.notNull {
+		def += " NOT NULL"
+	}
+	if cd.auto {
+		return def + " AUTO_INCREMENT"
+	}
+	if cd.defVal != "" {
+		def += " DEFAULT " + cd.defVal
+	}
+	return def
+}
+
+// 和 CREATE TABLE IF NOT EXISTS 一样可以重复执行；MySQL 不支持 CREATE INDEX IF NOT EXISTS，只能靠 AlterTableSqls 先查已有索引
+func (idx *indexDef) createSql(dn string, table string) string {
+	kind := "INDEX"
+	if idx.unique {
+		kind = "UNIQUE INDEX"
+	}
+	if dn != sqlx.DriverMysql {
+		kind += " IF NOT EXISTS"
+	}
+	return fmt.Sprintf("CREATE %s %s ON %s (%s);", kind, idx.name, table, strings.Join(idx.cols, ","))
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// Go类型对应的数据库字段类型
+func columnType(dn string, fTyp reflect.Type, opts *valid.FieldOpts, auto bool) string {
+	if auto {
+		switch dn {
+		case sqlx.DriverPostgres:
+			if fTyp.Kind() == reflect.Int32 || fTyp.Kind() == reflect.Uint32 {
+				return "SERIAL"
+			}
+			return "BIGSERIAL"
+		case sqlx.DriverSqlite:
+			return "INTEGER PRIMARY KEY AUTOINCREMENT"
+		}
+	}
+
+	if fTyp == timeType {
+		if dn == sqlx.DriverPostgres {
+			return "TIMESTAMP"
+		}
+		return "DATETIME"
+	}
+
+	sqlite := dn == sqlx.DriverSqlite
+	pg := dn == sqlx.DriverPostgres
+	unsigned := ""
+	if dn == sqlx.DriverMysql {
+		unsigned = " UNSIGNED"
+	}
+	switch fTyp.Kind() {
+	case reflect.Bool:
+		if pg {
+			return "BOOLEAN"
+		} else if sqlite {
+			return "INTEGER"
+		}
+		return "TINYINT(1)"
+	case reflect.Int8, reflect.Uint8, reflect.Int16, reflect.Uint16:
+		if sqlite {
+			return "INTEGER"
+		} else if pg {
+			return "SMALLINT"
+		}
+		if fTyp.Kind() == reflect.Int8 {
+			return "TINYINT"
+		} else if fTyp.Kind() == reflect.Uint8 {
+			return "TINYINT" + unsigned
+		} else if fTyp.Kind() == reflect.Uint16 {
+			return "SMALLINT" + unsigned
+		}
+		return "SMALLINT"
+	case reflect.Int32, reflect.Uint32:
+		if sqlite {
+			return "INTEGER"
+		} else if pg {
+			return "INTEGER"
+		}
+		if fTyp.Kind() == reflect.Uint32 {
+			return "INT" + unsigned
+		}
+		return "INT"
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		if sqlite {
+			return "INTEGER"
+		}
+		if fTyp.Kind() == reflect.Uint || fTyp.Kind() == reflect.Uint64 {
+			return "BIGINT" + unsigned
+		}
+		return "BIGINT"
+	case reflect.Float32:
+		if pg || sqlite {
+			return "REAL"
+		}
+		return "FLOAT"
+	case reflect.Float64:
+		if pg {
+			return "DOUBLE PRECISION"
+		} else if sqlite {
+			return "REAL"
+		}
+		return "DOUBLE"
+	case reflect.String:
+		size := defVarcharLen
+		if opts != nil && opts.Len != nil && opts.Len.Max() > 0 {
+			size = int(opts.Len.Max())
+			if opts.Len.Max() > maxVarcharLen {
+				size = maxVarcharLen + 1
+			}
+		}
+		if sqlite || size > maxVarcharLen {
+			return "TEXT"
+		}
+		return fmt.Sprintf("VARCHAR(%d)", size)
+	case reflect.Slice:
+		if fTyp.Elem().Kind() == reflect.Uint8 {
+			if pg {
+				return "BYTEA"
+			}
+			return "BLOB"
+		}
+	}
+	panic(fmt.Errorf("migrate: unsupported field type %s", fTyp))
+}
+
+// 字符串默认值需要加引号，时间字段只支持 def=now
+func defaultValue(fTyp reflect.Type, def string) string {
+	switch {
+	case fTyp == timeType:
+		if strings.ToLower(def) == defNowValue {
+			return sqlCurrentStamp
+		}
+		return ""
+	case fTyp.Kind() == reflect.String:
+		return "'" + strings.ReplaceAll(def, "'", "''") + "'"
+	case fTyp.Kind() == reflect.Bool:
+		return strings.ToUpper(def)
+	}
+	return def
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package migrate
+
+import (
+	"github.com/qinchende/gofast/logx"
+	"github.com/qinchende/gofast/store/sqlx"
+)
+
+// 查询已有字段和索引的SQL，参数都是表名
+var (
+	columnsSqlKV = map[string]string{
+		sqlx.DriverMysql:    "SELECT column_name FROM information_schema.columns WHERE table_schema=DATABASE() AND table_name=?;",
+		sqlx.DriverPostgres: "SELECT column_name FROM information_schema.columns WHERE table_schema=current_schema() AND table_name=?;",
+		sqlx.DriverSqlite:   "SELECT name FROM pragma_table_info(?);",
+	}
+	indexesSqlKV = map[string]string{
+		sqlx.DriverMysql:    "SELECT DISTINCT index_name FROM information_schema.statistics WHERE table_schema=DATABASE() AND table_name=?;",
+		sqlx.DriverPostgres: "SELECT indexname FROM pg_indexes WHERE schemaname=current_schema() AND tablename=?;",
+		sqlx.DriverSqlite:   "SELECT name FROM sqlite_master WHERE type='index' AND tbl_name=?;",
+	}
+)
+
+// 对比 Model 和数据库中的表结构，生成需要执行的DDL
+// 表不存在就建表；表存在只增加缺少的字段和索引，不会删除和修改已有的字段
+func AlterTableSqls(conn *sqlx.OrmDB, obj any) []string {
+	d := conn.Dialect()
+	td := parseModel(d, obj)
+
+	cls := queryNames(conn, columnsSqlKV[d.Name()], td.name)
+	if len(cls) == 0 {
+		return td.createSqls(d)
+	}
+
+	var sqls []string
+	for i := range td.columns {
+		cd := &td.columns[i]
+		if cls[cd.name] || cd.auto {
+			continue
+		}
+		// 已有数据的表，新增的非空字段必须有默认值，没有默认值的去掉 NOT NULL 并提示
+		if cd.notNull && cd.defVal == "" {
+			cd.notNull = false
+			logx.WarnF("migrate: column %s.%s has no default value, added as NULL instead of NOT NULL", td.name, cd.name)
+		}
+		sqls = append(sqls, "ALTER TABLE "+td.name+" ADD COLUMN "+cd.define(d.Name())+";")
+	}
+
+	idxes := queryNames(conn, indexesSqlKV[d.Name()], td.name)
+	for i := range td.indexes {
+		if !idxes[td.indexes[i].name] {
+			sqls = append(sqls, td.indexes[i].createSql(d.Name(), td.name))
+		}
+	}
+	return sqls
+}
+
+// 依次同步所有 Model 的表结构
+func AutoMigrate(conn *sqlx.OrmDB, objs ...any) {
+	for _, obj := range objs {
+		for _, sqlStr := range AlterTableSqls(conn, obj) {
+			logx.InfoF("migrate: %s", sqlStr)
+			conn.ExecSql(sqlStr)
+		}
+	}
+}
+
+func queryNames(conn *sqlx.OrmDB, sqlStr string, table string) map[string]bool {
+	// 表结构以主库为准，从库可能还没同步刚执行的 DDL
+	sqlRows := conn.CloneWithCtx(sqlx.WithWriter(conn.Ctx)).QuerySql(sqlStr, table)
+	defer sqlx.CloseSqlRows(sqlRows)
+
+	names := make(map[string]bool)
+	for sqlRows.Next() {
+		var name string
+		sqlx.ErrPanic(sqlRows.Scan(&name))
+		names[name] = true
+	}
+	sqlx.ErrPanic(sqlRows.Err())
+	return names
+}
@@ -0,0 +1,167 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package migrate
+
+import (
+	"fmt"
+	"github.com/qinchende/gofast/logx"
+	"github.com/qinchende/gofast/store/sqlx"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const migrationsTable = "gf_migrations" // 记录已经执行过的版本
+
+type migrationFile struct {
+	version uint64
+	name    string
+}
+
+// 执行目录中的版本迁移文件
+func RunDir(conn *sqlx.OrmDB, dir string) {
+	RunFiles(conn, os.DirFS(dir))
+}
+
+// 文件命名：<版本号>_<说明>.sql，比如 0001_init_user.sql。按版本号从小到大执行，已经执行过的版本跳过
+// 每个文件在一个事务中执行，文件中的多条语句用分号隔开。注意：MySQL 的 DDL 会隐式提交事务
+func RunFiles(conn *sqlx.OrmDB, fsys fs.FS) {
+	ensureTable(conn)
+	applied := queryApplied(conn)
+
+	files := listFiles(fsys)
+	for _, mf := range files {
+		if applied[mf.version] {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, mf.name)
+		if err != nil {
+			panic(err)
+		}
+
+		logx.InfoF("migrate: apply %s", mf.name)
+		runFile(conn, mf, splitStatements(string(content)))
+	}
+}
+
+func ensureTable(conn *sqlx.OrmDB) {
+	tType := "DATETIME"
+	if conn.Dialect().Name() == sqlx.DriverPostgres {
+		tType = "TIMESTAMP"
+	}
+	conn.ExecSql("CREATE TABLE IF NOT EXISTS " + migrationsTable + " (version BIGINT NOT NULL, name VARCHAR(255) NOT NULL, " +
+		"applied_at " + tType + " NOT NULL, PRIMARY KEY (version));")
+}
+
+func queryApplied(conn *sqlx.OrmDB) map[uint64]bool {
+	// 必须读主库，从库有延迟会把已经执行过的迁移再执行一遍
+	sqlRows := conn.CloneWithCtx(sqlx.WithWriter(conn.Ctx)).QuerySql("SELECT version FROM " + migrationsTable + ";")
+	defer sqlx.CloseSqlRows(sqlRows)
+
+	applied := make(map[uint64]bool)
+	for sqlRows.Next() {
+		var ver uint64
+		sqlx.ErrPanic(sqlRows.Scan(&ver))
+		applied[ver] = true
+	}
+	sqlx.ErrPanic(sqlRows.Err())
+	return applied
+}
+
+func listFiles(fsys fs.FS) []migrationFile {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		panic(err)
+	}
+
+	files := make([]migrationFile, 0, len(entries))
+	versions := make(map[uint64]string, len(entries))
+	for _, et := range entries {
+		name := et.Name()
+		if et.IsDir() || path.Ext(name) != ".sql" {
+			continue
+		}
+		ver, err := strconv.ParseUint(strings.SplitN(name, "_", 2)[0], 10, 64)
+		if err != nil {
+			logx.InfoF("migrate: skip file %s, name must be like 0001_desc.sql", name)
+			continue
+		}
+		if old, ok := versions[ver]; ok {
+			panic(fmt.Errorf("migrate: duplicate version %d in %s and %s", ver, old, name))
+		}
+		versions[ver] = name
+		files = append(files, migrationFile{version: ver, name: name})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].version < files[j].version
+	})
+	return files
+}
+
+// 出错时回滚，并继续向上抛出异常，后面的版本不再执行
+func runFile(conn *sqlx.OrmDB, mf migrationFile, stmts []string) {
+	tx := conn.TransBegin()
+	defer func() {
+		if pic := recover(); pic != nil {
+			sqlx.ErrLog(tx.Rollback())
+			panic(fmt.Errorf("migrate: %s failed: %v", mf.name, pic))
+		}
+	}()
+
+	for _, stmt := range stmts {
+		tx.ExecSql(stmt)
+	}
+	tx.ExecSql("INSERT INTO "+migrationsTable+" (version, name, applied_at) VALUES (?, ?, ?);", mf.version, mf.name, time.Now())
+	sqlx.ErrPanic(tx.Commit())
+}
+
+// 按分号拆分SQL语句，忽略引号中的分号（支持反斜杠转义）、-- 注释和 /* */ 注释
+func splitStatements(content string) []string {
+	var stmts []string
+	var quote byte
+	sBuf := strings.Builder{}
+	for i := 0; i < len(content); i++ {
+		ch := content[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote != '`' && i+1 < len(content) {
+				sBuf.WriteByte(ch)
+				i++
+				ch = content[i]
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '-' && i+1 < len(content) && content[i+1] == '-':
+			for i+1 < len(content) && content[i+1] != '\n' {
+				i++
+			}
+			continue
+		case ch == '/' && i+1 < len(content) && content[i+1] == '*':
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				i = len(content)
+			} else {
+				i += end + 3
+			}
+			sBuf.WriteByte(' ')
+			continue
+		case ch == ';':
+			if stmt := strings.TrimSpace(sBuf.String()); stmt != "" {
+				stmts = append(stmts, stmt+";")
+			}
+			sBuf.Reset()
+			continue
+		}
+		sBuf.WriteByte(ch)
+	}
+	if stmt := strings.TrimSpace(sBuf.String()); stmt != "" {
+		stmts = append(stmts, stmt+";")
+	}
+	return stmts
+}
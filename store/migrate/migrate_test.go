@@ -0,0 +1,147 @@
+package migrate
+
+import (
+	"database/sql"
+	"github.com/qinchende/gofast/logx"
+	"github.com/qinchende/gofast/store/orm"
+	"github.com/qinchende/gofast/store/sqlx"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func init() {
+	logx.MustSetup(&logx.LogConfig{LogMedium: "console", LogLevel: "error", LogStyle: "sdx"})
+}
+
+type migUser struct {
+	ID        int64     `dbc:"primary_field"`
+	Name      string    `dbc:"unique" v:"len=[0:64]"`
+	Email     string    `dbc:"index=idx_contact"`
+	Phone     string    `dbc:"index=idx_contact"`
+	Status    int8      `v:"def=1"`
+	Remark    *string   `v:"def=it's"`
+	CreatedAt time.Time `v:"def=now"`
+}
+
+func (u *migUser) GfAttrs(orm.OrmStruct) *orm.ModelAttrs {
+	return &orm.ModelAttrs{TableName: "users"}
+}
+func (u *migUser) BeforeSave()            {}
+func (u *migUser) AfterInsert(sql.Result) {}
+
+func TestCreateTableSqls(t *testing.T) {
+	cases := map[string][]string{
+		sqlx.DriverMysql: {
+			"CREATE TABLE IF NOT EXISTS users (\n" +
+				"  id BIGINT NOT NULL AUTO_INCREMENT,\n" +
+				"  name VARCHAR(64) NOT NULL,\n" +
+				"  email VARCHAR(255) NOT NULL,\n" +
+				"  phone VARCHAR(255) NOT NULL,\n" +
+				"  status TINYINT NOT NULL DEFAULT 1,\n" +
+				"  remark VARCHAR(255) DEFAULT 'it''s',\n" +
+				"  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+				"  PRIMARY KEY (id)\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;",
+			"CREATE UNIQUE INDEX uk_users_name ON users (name);",
+			"CREATE INDEX idx_contact ON users (email,phone);",
+		},
+		sqlx.DriverPostgres: {
+			"CREATE TABLE IF NOT EXISTS users (\n" +
+				"  id BIGSERIAL,\n" +
+				"  name VARCHAR(64) NOT NULL,\n" +
+				"  email VARCHAR(255) NOT NULL,\n" +
+				"  phone VARCHAR(255) NOT NULL,\n" +
+				"  status SMALLINT NOT NULL DEFAULT 1,\n" +
+				"  remark VARCHAR(255) DEFAULT 'it''s',\n" +
+				"  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+				"  PRIMARY KEY (id)\n" +
+				");",
+			"CREATE UNIQUE INDEX IF NOT EXISTS uk_users_name ON users (name);",
+			"CREATE INDEX IF NOT EXISTS idx_contact ON users (email,phone);",
+		},
+		sqlx.DriverSqlite: {
+			"CREATE TABLE IF NOT EXISTS users (\n" +
+				"  id INTEGER PRIMARY KEY AUTOINCREMENT,\n" +
+				"  name TEXT NOT NULL,\n" +
+				"  email TEXT NOT NULL,\n" +
+				"  phone TEXT NOT NULL,\n" +
+				"  status INTEGER NOT NULL DEFAULT 1,\n" +
+				"  remark TEXT DEFAULT 'it''s',\n" +
+				"  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP\n" +
+				");",
+			"CREATE UNIQUE INDEX IF NOT EXISTS uk_users_name ON users (name);",
+			"CREATE INDEX IF NOT EXISTS idx_contact ON users (email,phone);",
+		},
+	}
+	for dn, want := range cases {
+		got := CreateTableSqls(sqlx.DialectOf(dn), &migUser{})
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s:\ngot  %q\nwant %q", dn, got, want)
+		}
+	}
+}
+
+func TestColumnDefine(t *testing.T) {
+	cd := columnDef{name: "age", sqlType: "INT", notNull: true, defVal: "0"}
+	if got := cd.define(sqlx.DriverMysql); got != "age INT NOT NULL DEFAULT 0" {
+		t.Errorf("got %q", got)
+	}
+	// 给已有表加字段时没有默认值的非空字段会去掉 NOT NULL
+	cd = columnDef{name: "age", sqlType: "INT"}
+	if got := cd.define(sqlx.DriverPostgres); got != "age INT" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	cases := []struct {
+		content string
+		want    []string
+	}{
+		{"", nil},
+		{"SELECT 1", []string{"SELECT 1;"}},
+		{"SELECT 1;\n\n;SELECT 2;", []string{"SELECT 1;", "SELECT 2;"}},
+		{"INSERT INTO t VALUES ('a;b', \"c;d\", `e;f`);", []string{"INSERT INTO t VALUES ('a;b', \"c;d\", `e;f`);"}},
+		{"INSERT INTO t VALUES ('it''s;');", []string{"INSERT INTO t VALUES ('it''s;');"}},
+		{"INSERT INTO t VALUES ('a\\';b');SELECT 2;", []string{"INSERT INTO t VALUES ('a\\';b');", "SELECT 2;"}},
+		{"INSERT INTO t VALUES (\"a\\\";b\");", []string{"INSERT INTO t VALUES (\"a\\\";b\");"}},
+		{"-- a; comment\nSELECT 1; -- tail;\nSELECT 2;", []string{"SELECT 1;", "SELECT 2;"}},
+		{"/* a;\n b; */SELECT 1;/**/SELECT/*;*/2;", []string{"SELECT 1;", "SELECT 2;"}},
+		{"SELECT 1; /* not closed;", []string{"SELECT 1;"}},
+		{"SELECT '/* not comment; */';", []string{"SELECT '/* not comment; */';"}},
+	}
+	for _, c := range cases {
+		if got := splitStatements(c.content); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("splitStatements(%q):\ngot  %q\nwant %q", c.content, got, c.want)
+		}
+	}
+}
+
+func TestListFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_email.sql": {Data: []byte("")},
+		"0001_init.sql":      {Data: []byte("")},
+		"0010_index.sql":     {Data: []byte("")},
+		"readme.md":          {Data: []byte("")},
+		"init.sql":           {Data: []byte("")},
+		"0003_dir":           {Mode: 0755 | 1<<31},
+	}
+	var names []string
+	for _, mf := range listFiles(fsys) {
+		names = append(names, mf.name)
+	}
+	want := []string{"0001_init.sql", "0002_add_email.sql", "0010_index.sql"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+
+	fsys["1_dup.sql"] = &fstest.MapFile{}
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicate version should panic")
+		}
+	}()
+	listFiles(fsys)
+}
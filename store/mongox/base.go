@@ -0,0 +1,105 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package mongox
+
+import (
+	"context"
+	"errors"
+	"github.com/qinchende/gofast/logx"
+	"github.com/qinchende/gofast/skill/timex"
+	"github.com/qinchende/gofast/store/cache"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// MongoDB 的 ORM，用法和 sqlx.OrmDB 保持一致：
+// 结构体和文档的映射沿用 orm.ModelSchema（pms/dbf 标签决定字段名），主键字段对应文档的 _id，集合名就是表名
+// 出错时 panic（recover 之后是 *DBError），查询没有记录返回 0
+type MgoDB struct {
+	DB            *mongo.Database
+	Ctx           context.Context
+	SlowThreshold time.Duration // 慢日志阈值，0代表默认500ms
+	cache         cache.Cache   // 行记录缓存
+}
+
+// 执行超过500ms的操作打印慢日志
+const slowThreshold = time.Millisecond * 500
+
+const (
+	mgoIdKey       = "_id"
+	mgoCounterColl = "gf_counters" // 整数主键的自增序列，每个集合一条记录
+)
+
+// 功能和 sqlx.SelectPet 类似的高级查询
+type SelectPet struct {
+	Target   any      // 解析的目标对象数组，*[]T 或者 *[]*T
+	Filter   any      // 查询条件，bson.M 或者 bson.D，nil代表全部
+	Sort     bson.D   // 排序，比如 bson.D{{"created_at", -1}}
+	Columns  []string // 只返回这些字段，空代表全部
+	Skip     int64    // 查询偏移量
+	Limit    int64    // 查询限量，默认100
+	Page     int64    // 分页查询的当前页，默认1
+	PageSize int64    // 分页大小，默认100
+}
+
+var ErrNotPointer = errors.New("mongox: dest must be pointer")
+
+// 数据库错误，Err 是驱动返回的原始错误
+type DBError struct {
+	Op  string
+	Err error
+}
+
+func (e *DBError) Error() string {
+	return "mongox: " + e.Op + ": " + e.Err.Error()
+}
+
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
+
+func NewMgoDB(db *mongo.Database) *MgoDB {
+	return &MgoDB{DB: db, Ctx: context.Background()}
+}
+
+// 任意实现了 cache.Cache 的缓存，传nil代表不用缓存
+func (conn *MgoDB) SetCache(c cache.Cache) {
+	conn.cache = c
+}
+
+func (conn *MgoDB) Cache() cache.Cache {
+	return conn.cache
+}
+
+func (conn *MgoDB) CloneWithCtx(ctx context.Context) *MgoDB {
+	newConn := *conn
+	newConn.Ctx = ctx
+	return &newConn
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+func errPanic(op string, err error) {
+	if err != nil {
+		logx.Stack(err.Error())
+		panic(&DBError{Op: op, Err: err})
+	}
+}
+
+func errLog(err error) {
+	if err != nil {
+		logx.Stack(err.Error())
+	}
+}
+
+// 记录操作耗时，超过阈值打印慢日志
+func (conn *MgoDB) logSlow(op, coll string, filter any, startTime time.Duration) {
+	dur := timex.NowDiff(startTime)
+	threshold := conn.SlowThreshold
+	if threshold <= 0 {
+		threshold = slowThreshold
+	}
+	if dur > threshold {
+		logx.SlowF("[Mongo][%dms] %s %s: slow-call - %v", dur/time.Millisecond, op, coll, filter)
+	}
+}
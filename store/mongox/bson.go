@@ -0,0 +1,114 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package mongox
+
+import (
+	"github.com/qinchende/gofast/store/orm"
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+)
+
+// 结构体转成文档，字段名用 ModelSchema 的列名，主键对应 _id
+func toDoc(sm *orm.ModelSchema, obj any) bson.D {
+	_, values := orm.SchemaValues(obj)
+	cls := sm.Columns()
+	priIdx := int(sm.PrimaryIndex())
+
+	doc := make(bson.D, 0, len(cls))
+	doc = append(doc, bson.E{Key: mgoIdKey, Value: values[priIdx]})
+	for i, col := range cls {
+		if i != priIdx {
+			doc = append(doc, bson.E{Key: col, Value: values[i]})
+		}
+	}
+	return doc
+}
+
+// 只包含指定字段的 $set 文档，fNames 是结构体字段名
+func toSetDoc(sm *orm.ModelSchema, rVal *reflect.Value, fNames []string) bson.D {
+	cls := sm.Columns()
+	flsKV := sm.FieldsKV()
+	doc := make(bson.D, 0, len(fNames)+1)
+	for _, name := range fNames {
+		idx, ok := flsKV[name]
+		if !ok {
+			panic("mongox: field " + name + " not exist")
+		}
+		doc = append(doc, bson.E{Key: cls[idx], Value: sm.ValueByIndex(rVal, idx)})
+	}
+	if upIdx := sm.UpdatedIndex(); upIdx >= 0 {
+		doc = append(doc, bson.E{Key: cls[upIdx], Value: sm.ValueByIndex(rVal, upIdx)})
+	}
+	return doc
+}
+
+// 文档解析到结构体中，结构体中没有的字段忽略
+// 目标结构体可能是复用的，文档中没有的字段（比如只查询了部分字段）要先重置成零值
+func fromDoc(sm *orm.ModelSchema, raw bson.Raw, rVal *reflect.Value) error {
+	elems, err := raw.Elements()
+	if err != nil {
+		return err
+	}
+	for i := range sm.Columns() {
+		fVal := rVal.FieldByIndex(sm.FieldIndex(int8(i)))
+		fVal.Set(reflect.Zero(fVal.Type()))
+	}
+	clsKV := sm.ColumnsKV()
+	for _, elem := range elems {
+		key := elem.Key()
+		idx, ok := clsKV[key]
+		if key == mgoIdKey {
+			idx, ok = sm.PrimaryIndex(), true
+		}
+		if !ok {
+			continue
+		}
+		if err = elem.Value().Unmarshal(sm.AddrByIndex(rVal, idx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 查询时只返回部分字段
+func projection(sm *orm.ModelSchema, columns []string) bson.D {
+	if len(columns) == 0 {
+		return nil
+	}
+	priCol := sm.Columns()[sm.PrimaryIndex()]
+	doc := make(bson.D, 0, len(columns))
+	for _, col := range columns {
+		if col == priCol {
+			col = mgoIdKey
+		}
+		doc = append(doc, bson.E{Key: col, Value: 1})
+	}
+	return doc
+}
+
+// 目标切片的元素类型，支持 *[]T 和 *[]*T
+func sliceDest(dest any) (*orm.ModelSchema, reflect.Value, reflect.Type, bool) {
+	dVal := reflect.ValueOf(dest)
+	if dVal.Kind() != reflect.Ptr || dVal.Elem().Kind() != reflect.Slice {
+		panic(ErrNotPointer)
+	}
+	recordType := dVal.Elem().Type().Elem()
+	isPtr := recordType.Kind() == reflect.Ptr
+	if isPtr {
+		recordType = recordType.Elem()
+	}
+	return orm.SchemaOfType(reflect.PtrTo(recordType)), dVal.Elem(), recordType, isPtr
+}
+
+// 插入时反写自增主键
+type insertResult struct {
+	id int64
+}
+
+func (r insertResult) LastInsertId() (int64, error) {
+	return r.id, nil
+}
+
+func (r insertResult) RowsAffected() (int64, error) {
+	return 1, nil
+}
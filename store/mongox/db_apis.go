@@ -0,0 +1,220 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package mongox
+
+import (
+	"errors"
+	"github.com/qinchende/gofast/skill/timex"
+	"github.com/qinchende/gofast/store/orm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+)
+
+func (conn *MgoDB) coll(sm *orm.ModelSchema) *mongo.Collection {
+	return conn.DB.Collection(sm.TableName())
+}
+
+// 主键为零值时自动生成：整数主键用 gf_counters 集合自增，字符串主键用 ObjectID 的十六进制，ObjectID 主键直接生成
+func (conn *MgoDB) Insert(obj orm.OrmStruct) int64 {
+	obj.BeforeSave()
+	sm := orm.Schema(obj)
+	rVal := reflect.Indirect(reflect.ValueOf(obj))
+	pkVal := rVal.FieldByIndex(sm.FieldIndex(sm.PrimaryIndex()))
+	if pkVal.IsZero() {
+		switch {
+		case pkVal.Type() == reflect.TypeOf(primitive.ObjectID{}):
+			pkVal.Set(reflect.ValueOf(primitive.NewObjectID()))
+		case pkVal.Kind() == reflect.String:
+			pkVal.SetString(primitive.NewObjectID().Hex())
+		case pkVal.CanInt():
+			pkVal.SetInt(conn.nextSeq(sm))
+		case pkVal.CanUint():
+			pkVal.SetUint(uint64(conn.nextSeq(sm)))
+		}
+	}
+
+	doc := toDoc(sm, obj)
+	startTime := timex.Now()
+	_, err := conn.coll(sm).InsertOne(conn.Ctx, doc)
+	conn.logSlow("insert", sm.TableName(), nil, startTime)
+	errPanic("insert", err)
+	if pkVal.CanInt() {
+		obj.AfterInsert(insertResult{id: pkVal.Int()})
+	} else if pkVal.CanUint() {
+		obj.AfterInsert(insertResult{id: int64(pkVal.Uint())})
+	}
+	conn.delNullCache(sm, sm.PrimaryValue(obj))
+	return 1
+}
+
+// 整个文档替换成对象的当前值
+func (conn *MgoDB) Update(obj orm.OrmStruct) int64 {
+	obj.BeforeSave()
+	sm := orm.Schema(obj)
+	pk := sm.PrimaryValue(obj)
+	filter := bson.D{{Key: mgoIdKey, Value: pk}}
+
+	startTime := timex.Now()
+	ret, err := conn.coll(sm).ReplaceOne(conn.Ctx, filter, toDoc(sm, obj))
+	conn.logSlow("replace", sm.TableName(), filter, startTime)
+	errPanic("replace", err)
+	return conn.afterChange(sm, ret.MatchedCount, pk)
+}
+
+// 只更新指定的结构体字段，有更新时间字段时同时更新
+func (conn *MgoDB) UpdateFields(obj orm.OrmStruct, fNames ...string) int64 {
+	if len(fNames) == 0 {
+		panic("mongox: UpdateFields args [fNames] is empty")
+	}
+	obj.BeforeSave()
+	sm := orm.Schema(obj)
+	rVal := reflect.Indirect(reflect.ValueOf(obj))
+	pk := sm.PrimaryValue(obj)
+	filter := bson.D{{Key: mgoIdKey, Value: pk}}
+
+	startTime := timex.Now()
+	ret, err := conn.coll(sm).UpdateOne(conn.Ctx, filter, bson.D{{Key: "$set", Value: toSetDoc(sm, &rVal, fNames)}})
+	conn.logSlow("update", sm.TableName(), filter, startTime)
+	errPanic("update", err)
+	return conn.afterChange(sm, ret.MatchedCount, pk)
+}
+
+func (conn *MgoDB) Delete(obj any) int64 {
+	sm := orm.Schema(obj)
+	pk := sm.PrimaryValue(obj)
+	filter := bson.D{{Key: mgoIdKey, Value: pk}}
+
+	startTime := timex.Now()
+	ret, err := conn.coll(sm).DeleteOne(conn.Ctx, filter)
+	conn.logSlow("delete", sm.TableName(), filter, startTime)
+	errPanic("delete", err)
+	return conn.afterChange(sm, ret.DeletedCount, pk)
+}
+
+func (conn *MgoDB) afterChange(sm *orm.ModelSchema, ct int64, pk any) int64 {
+	if ct > 0 {
+		conn.delLineCache(sm, pk)
+	}
+	return ct
+}
+
+// 整数主键的下一个序号
+func (conn *MgoDB) nextSeq(sm *orm.ModelSchema) int64 {
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	ret := conn.DB.Collection(mgoCounterColl).FindOneAndUpdate(conn.Ctx,
+		bson.D{{Key: mgoIdKey, Value: sm.TableName()}}, bson.D{{Key: "$inc", Value: bson.D{{Key: "seq", Value: int64(1)}}}}, opts)
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	errPanic("sequence", ret.Decode(&counter))
+	return counter.Seq
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 对应主键的一条记录
+func (conn *MgoDB) QueryPrimary(dest any, id any) int64 {
+	return conn.QueryRow(dest, bson.D{{Key: mgoIdKey, Value: id}})
+}
+
+// 查询一条记录，filter 是 bson.M 或者 bson.D
+func (conn *MgoDB) QueryRow(dest any, filter any) int64 {
+	sm := orm.Schema(dest)
+	raw := conn.findOneRaw(sm, filter)
+	if raw == nil {
+		return 0
+	}
+	rVal := reflect.Indirect(reflect.ValueOf(dest))
+	errPanic("decode", fromDoc(sm, raw, &rVal))
+	return 1
+}
+
+func (conn *MgoDB) findOneRaw(sm *orm.ModelSchema, filter any) bson.Raw {
+	startTime := timex.Now()
+	raw, err := conn.coll(sm).FindOne(conn.Ctx, filter).DecodeBytes()
+	conn.logSlow("findOne", sm.TableName(), filter, startTime)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	errPanic("findOne", err)
+	return raw
+}
+
+// 查询多条记录，dest 是 *[]T 或者 *[]*T，最多返回1万条
+func (conn *MgoDB) QueryRows(dest any, filter any) int64 {
+	return conn.QueryPet(&SelectPet{Target: dest, Filter: filter, Limit: 10000})
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 高级查询，可以指定排序、字段、偏移量和数量
+func (conn *MgoDB) QueryPet(pet *SelectPet) int64 {
+	if pet.Limit <= 0 {
+		pet.Limit = 100
+	}
+	return conn.findRows(pet, pet.Skip, pet.Limit)
+}
+
+// 分页查询，返回本页的记录数和总记录数
+func (conn *MgoDB) QueryPetPaging(pet *SelectPet) (int64, int64) {
+	if pet.Page <= 0 {
+		pet.Page = 1
+	}
+	if pet.PageSize <= 0 {
+		pet.PageSize = 100
+	}
+	sm, _, _, _ := sliceDest(pet.Target)
+	filter := petFilter(pet)
+
+	startTime := timex.Now()
+	total, err := conn.coll(sm).CountDocuments(conn.Ctx, filter)
+	conn.logSlow("count", sm.TableName(), filter, startTime)
+	errPanic("count", err)
+	if total == 0 {
+		reflect.ValueOf(pet.Target).Elem().SetLen(0)
+		return 0, 0
+	}
+	return conn.findRows(pet, (pet.Page-1)*pet.PageSize, pet.PageSize), total
+}
+
+func (conn *MgoDB) findRows(pet *SelectPet, skip, limit int64) int64 {
+	sm, records, recordType, isPtr := sliceDest(pet.Target)
+	filter := petFilter(pet)
+	opts := options.Find().SetSkip(skip).SetLimit(limit)
+	if len(pet.Sort) > 0 {
+		opts.SetSort(pet.Sort)
+	}
+	if proj := projection(sm, pet.Columns); proj != nil {
+		opts.SetProjection(proj)
+	}
+
+	startTime := timex.Now()
+	cur, err := conn.coll(sm).Find(conn.Ctx, filter, opts)
+	errPanic("find", err)
+	defer func() { errLog(cur.Close(conn.Ctx)) }()
+
+	list := reflect.MakeSlice(records.Type(), 0, 25)
+	for cur.Next(conn.Ctx) {
+		recordPtr := reflect.New(recordType)
+		recordVal := recordPtr.Elem()
+		errPanic("decode", fromDoc(sm, cur.Current, &recordVal))
+		if isPtr {
+			list = reflect.Append(list, recordPtr)
+		} else {
+			list = reflect.Append(list, recordVal)
+		}
+	}
+	conn.logSlow("find", sm.TableName(), filter, startTime)
+	errPanic("find", cur.Err())
+	records.Set(list)
+	return int64(list.Len())
+}
+
+func petFilter(pet *SelectPet) any {
+	if pet.Filter == nil {
+		return bson.D{}
+	}
+	return pet.Filter
+}
@@ -0,0 +1,135 @@
+package mongox
+
+import (
+	"context"
+	"database/sql"
+	"github.com/qinchende/gofast/store/orm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 本地 mongod 的测试，连不上时跳过，MONGO_URI 可以指定其它地址
+func testConn(t *testing.T) *MgoDB {
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		uri = "mongodb://127.0.0.1:27017"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	cli, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetServerSelectionTimeout(time.Second))
+	if err == nil {
+		err = cli.Ping(ctx, nil)
+	}
+	if err != nil {
+		t.Skipf("mongod not reachable at %s: %v", uri, err)
+	}
+	t.Cleanup(func() { _ = cli.Disconnect(context.Background()) })
+
+	conn := NewMgoDB(cli.Database("gf_mongox_test"))
+	_ = conn.DB.Drop(context.Background())
+	t.Cleanup(func() { _ = conn.DB.Drop(context.Background()) })
+	return conn
+}
+
+type uintUser struct {
+	ID   uint32 `dbc:"primary_field"`
+	Name string
+	Age  int
+}
+
+func (u *uintUser) GfAttrs(orm.OrmStruct) *orm.ModelAttrs {
+	return &orm.ModelAttrs{TableName: "uint_users"}
+}
+func (u *uintUser) BeforeSave() {}
+func (u *uintUser) AfterInsert(ret sql.Result) {
+	if id, err := ret.LastInsertId(); err == nil {
+		u.ID = uint32(id)
+	}
+}
+
+type strUser struct {
+	ID   string `dbc:"primary_field"`
+	Name string
+}
+
+func (u *strUser) GfAttrs(orm.OrmStruct) *orm.ModelAttrs {
+	return &orm.ModelAttrs{TableName: "str_users"}
+}
+func (u *strUser) BeforeSave()            {}
+func (u *strUser) AfterInsert(sql.Result) {}
+
+// 复用的目标结构体，文档中没有的字段要重置
+func TestFromDocResetsMissingFields(t *testing.T) {
+	sm := orm.Schema(&uintUser{})
+	raw, err := bson.Marshal(bson.D{{Key: mgoIdKey, Value: int64(2)}, {Key: "name", Value: "bob"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := uintUser{ID: 1, Name: "amy", Age: 30}
+	rVal := reflect.ValueOf(&dst).Elem()
+	if err = fromDoc(sm, raw, &rVal); err != nil {
+		t.Fatal(err)
+	}
+	if want := (uintUser{ID: 2, Name: "bob"}); dst != want {
+		t.Fatalf("got %+v, want %+v", dst, want)
+	}
+}
+
+func TestInsertUintPrimary(t *testing.T) {
+	conn := testConn(t)
+
+	u1, u2 := &uintUser{Name: "amy", Age: 30}, &uintUser{Name: "bob", Age: 20}
+	conn.Insert(u1)
+	conn.Insert(u2)
+	if u1.ID == 0 || u2.ID != u1.ID+1 {
+		t.Fatalf("uint primary not generated: %d, %d", u1.ID, u2.ID)
+	}
+
+	var got uintUser
+	if ct := conn.QueryPrimary(&got, u2.ID); ct != 1 || got != *u2 {
+		t.Fatalf("QueryPrimary got %d %+v, want %+v", ct, got, *u2)
+	}
+}
+
+func TestInsertStringPrimary(t *testing.T) {
+	conn := testConn(t)
+
+	u := &strUser{Name: "amy"}
+	conn.Insert(u)
+	if len(u.ID) != 24 {
+		t.Fatalf("string primary should be ObjectID hex, got %q", u.ID)
+	}
+	var got strUser
+	if ct := conn.QueryPrimary(&got, u.ID); ct != 1 || got != *u {
+		t.Fatalf("QueryPrimary got %d %+v, want %+v", ct, got, *u)
+	}
+}
+
+func TestQueryReusedDest(t *testing.T) {
+	conn := testConn(t)
+
+	u := &uintUser{Name: "amy", Age: 30}
+	conn.Insert(u)
+
+	// 只查询部分字段，复用的目标结构体中其它字段不能残留旧值
+	list := []uintUser{{ID: 99, Name: "old", Age: 99}}
+	ct := conn.QueryPet(&SelectPet{Target: &list, Columns: []string{"name"}})
+	if ct != 1 || list[0] != (uintUser{ID: u.ID, Name: "amy"}) {
+		t.Fatalf("got %d %+v", ct, list)
+	}
+
+	dst := uintUser{Age: 99}
+	if ct = conn.QueryRow(&dst, bson.D{{Key: "name", Value: "amy"}}); ct != 1 || dst != *u {
+		t.Fatalf("QueryRow got %d %+v, want %+v", ct, dst, *u)
+	}
+	if ct = conn.QueryRow(&dst, bson.D{{Key: "name", Value: "nobody"}}); ct != 0 {
+		t.Fatalf("QueryRow missing got %d", ct)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package mongox
+
+import (
+	"github.com/qinchende/gofast/skill/lang"
+	"github.com/qinchende/gofast/skill/syncx"
+	"github.com/qinchende/gofast/store/orm"
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"time"
+)
+
+const (
+	cacheNullVal  = "#null#"         // 记录不存在时缓存的占位值，防止缓存穿透
+	cacheNullMaxS = 60 * time.Second // 空值缓存的最长时间
+)
+
+// 缓存失效时，相同Key的并发请求只查一次数据库，防止缓存击穿
+var cacheBarrier = syncx.NewSharedCalls()
+
+// 对应主键的一条记录，支持行记录缓存（Model 需要开启 CacheAll），缓存的是文档的 Extended JSON
+func (conn *MgoDB) QueryPrimaryCache(dest any, id any) int64 {
+	sm := orm.Schema(dest)
+	if conn.cache == nil || !sm.CacheAll() {
+		return conn.QueryPrimary(dest, id)
+	}
+
+	key := sm.CacheLineKey(conn.DB.Name(), id)
+	var cacheStr string
+	if err := conn.cache.Get(key, &cacheStr); err != nil || cacheStr == "" {
+		val, _ := cacheBarrier.Do(key, func() (any, error) {
+			return conn.queryPrimaryAndCache(sm, id, key), nil
+		})
+		// 共享的查询发生了异常，自己再查一次
+		if cacheStr, _ = val.(string); cacheStr == "" {
+			return conn.QueryPrimary(dest, id)
+		}
+	}
+	if cacheStr == cacheNullVal {
+		return 0
+	}
+
+	var raw bson.Raw
+	errPanic("cache", bson.UnmarshalExtJSON(lang.StringToBytes(cacheStr), true, &raw))
+	rVal := reflect.Indirect(reflect.ValueOf(dest))
+	errPanic("decode", fromDoc(sm, raw, &rVal))
+	return 1
+}
+
+func (conn *MgoDB) queryPrimaryAndCache(sm *orm.ModelSchema, id any, key string) string {
+	raw := conn.findOneRaw(sm, bson.D{{Key: mgoIdKey, Value: id}})
+	if raw == nil {
+		expire := sm.ExpireDuration()
+		if expire > cacheNullMaxS {
+			expire = cacheNullMaxS
+		}
+		_ = conn.cache.SetExpire(key, cacheNullVal, expire)
+		return cacheNullVal
+	}
+
+	bs, err := bson.MarshalExtJSON(raw, true, false)
+	errPanic("cache", err)
+	cacheStr := string(bs)
+	_ = conn.cache.SetExpire(key, cacheStr, sm.ExpireDuration())
+	return cacheStr
+}
+
+// 记录修改之后删除行记录缓存
+func (conn *MgoDB) delLineCache(sm *orm.ModelSchema, ids ...any) {
+	if !sm.CacheAll() || conn.cache == nil {
+		return
+	}
+	for _, id := range ids {
+		_ = conn.cache.Del(sm.CacheLineKey(conn.DB.Name(), id))
+	}
+}
+
+// 新插入的记录之前可能缓存了空值，需要清除
+func (conn *MgoDB) delNullCache(sm *orm.ModelSchema, ids ...any) {
+	conn.delLineCache(sm, ids...)
+}
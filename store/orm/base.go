@@ -6,10 +6,15 @@ const (
 	dbDefAutoIncKeyName = "ID"            // 默认数据库自增键字段名
 	dbDefPrimaryKeyName = "ID"            // 默认主键的字段名
 	dbDefUpdatedKeyName = "UpdatedAt"     // 默认主键的字段名
+	dbDefCreatedKeyName = "CreatedAt"     // 默认创建时间的字段名
 	dbConfigTag         = "dbc"           // 数据库字段配置tag头
 	dbAutoIncKeyFlag    = "auto_field"    // 数据库自增字段tag标记
 	dbPrimaryKeyFlag    = "primary_field" // 数据库主键tag标记
 	dbUpdatedKeyFlag    = "updated_field" // 更新时间
+	dbCreatedKeyFlag    = "created_field" // 创建时间
+	dbDeletedKeyFlag    = "deleted_field" // 软删除标记
+	dbVersionKeyFlag    = "version_field" // 乐观锁版本号
+	dbRelationTag       = "rel"           // 关联关系tag头
 )
 
 type OrmStruct interface {
@@ -25,13 +30,19 @@ type ModelSchema struct {
 	name     string
 	attrs    ModelAttrs // 实体类型的相关控制属性
 
-	columns      []string        // column_name
-	fieldsKV     map[string]int8 // field_name index
-	columnsKV    map[string]int8 // column_name index
-	fieldsIndex  [][]int         // reflect fields index
-	autoIndex    int8            // 自增字段原始索引位置
-	primaryIndex int8            // 主键字段原始索引位置
-	updatedIndex int8            // 更新字段原始索引位置，没有则为-1
+	columns      []string             // column_name
+	fieldsKV     map[string]int8      // field_name index
+	columnsKV    map[string]int8      // column_name index
+	fieldsIndex  [][]int              // reflect fields index
+	autoIndex    int8                 // 自增字段原始索引位置
+	primaryIndex int8                 // 主键字段原始索引位置
+	updatedIndex int8                 // 更新字段原始索引位置，没有则为-1
+	createdIndex int8                 // 创建时间字段原始索引位置，没有则为-1
+	deletedIndex int8                 // 软删除字段原始索引位置，没有则为-1
+	versionIndex int8                 // 版本号字段原始索引位置，没有则为-1
+	deletedCond  string               // 未删除记录的过滤条件，比如 deleted_at IS NULL
+	deletedUnix  bool                 // 软删除字段是整数，删除时记录时间戳
+	relations    map[string]*Relation // 关联关系，字段名 -> 关联定义
 
 	insertSQL string // 全字段insert（将来会建立通用缓存中心，这里暂时这样用）
 	updateSQL string // 全字段update
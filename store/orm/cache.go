@@ -3,11 +3,14 @@ package orm
 import (
 	"fmt"
 	"github.com/qinchende/gofast/skill/hash"
+	"github.com/qinchende/gofast/skill/mathx"
 	"reflect"
 	"sync"
 	"time"
 )
 
+var expireUnstable = mathx.NewUnstable(0.05)
+
 func (ms *ModelSchema) TableName() string {
 	return ms.attrs.TableName
 }
@@ -24,17 +27,23 @@ func (ms *ModelSchema) CacheLineKey(dbName, id any) string {
 	return fmt.Sprintf(ms.attrs.cacheKeyFmt, dbName, id)
 }
 
+// Key 中不带库名，多个库执行相同的SQL会共用缓存，这种情况用 CacheSqlKeyDB
 func (ms *ModelSchema) CacheSqlKey(sql string) string {
 	return "Gf#Pet#" + hash.Md5HexString(sql)
 }
 
+// 不同库中相同的SQL查询结果不一样，Key 中要带上库名
+func (ms *ModelSchema) CacheSqlKeyDB(dbName, sql string) string {
+	return "Gf#Pet#" + dbName + "#" + hash.Md5HexString(sql)
+}
+
 func (ms *ModelSchema) ExpireS() uint32 {
 	return ms.attrs.ExpireS
 }
 
-// 可以考虑加上随机 5% 左右的偏差，防止将来缓存统一过期导致缓存雪崩
+// 加上随机 5% 左右的偏差，防止缓存统一过期导致缓存雪崩
 func (ms *ModelSchema) ExpireDuration() time.Duration {
-	return time.Duration(ms.attrs.ExpireS) * time.Second
+	return expireUnstable.AroundDuration(time.Duration(ms.attrs.ExpireS) * time.Second)
 }
 
 func (ms *ModelSchema) FieldsKV() map[string]int8 {
@@ -53,6 +62,10 @@ func (ms *ModelSchema) UpdatedIndex() int8 {
 	return ms.updatedIndex
 }
 
+func (ms *ModelSchema) CreatedIndex() int8 {
+	return ms.createdIndex
+}
+
 func (ms *ModelSchema) PrimaryIndex() int8 {
 	return ms.primaryIndex
 }
@@ -94,6 +107,11 @@ func (ms *ModelSchema) PrimaryValue(obj any) any {
 	return rVal.FieldByIndex(ms.fieldsIndex[ms.primaryIndex]).Interface()
 }
 
+// 字段在结构体中的反射索引，嵌入结构体的字段有多级
+func (ms *ModelSchema) FieldIndex(index int8) []int {
+	return ms.fieldsIndex[index]
+}
+
 func (ms *ModelSchema) ValueByIndex(rVal *reflect.Value, index int8) any {
 	return rVal.FieldByIndex(ms.fieldsIndex[index]).Interface()
 }
@@ -25,6 +25,16 @@ type CommonFields struct {
 	UpdatedAt time.Time // `dbc:"updated_field"`
 }
 
+// 需要软删除的Model再嵌入这个结构体，Delete 只是标记删除，查询自动过滤已删除的记录
+type DeletedFields struct {
+	DeletedAt *time.Time `dbc:"deleted_field"`
+}
+
+// 需要乐观锁的Model再嵌入这个结构体，Update 版本号不匹配时报 sqlx.ErrVersionConflict
+type VersionFields struct {
+	Version int64 `dbc:"version_field"`
+}
+
 func (cf *CommonFields) GfAttrs(parent OrmStruct) *ModelAttrs {
 	if modelAttrsList != nil {
 		fullName := ""
@@ -0,0 +1,88 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package orm
+
+import (
+	"fmt"
+	"github.com/qinchende/gofast/skill/lang"
+	"reflect"
+	"strings"
+)
+
+// 关联关系，在字段上用 rel 标记声明，比如：
+// Items []*OrderItem `rel:"has_many,fk=order_id"`   子表 order_item.order_id = 本表主键
+// Extra *OrderExtra  `rel:"has_one"`                子表 order_extra.order_id = 本表主键（fk默认：本Model名_id）
+// User  *User        `rel:"belongs_to,fk=user_id"`  本表 user_id = user 表主键
+// ref 可以指定被引用的列，默认是主键
+const (
+	RelHasOne    = "has_one"
+	RelHasMany   = "has_many"
+	RelBelongsTo = "belongs_to"
+)
+
+type Relation struct {
+	Name     string       // 结构体字段名称
+	Kind     string       // has_one | has_many | belongs_to
+	FK       string       // 外键列名：has_xxx 时在关联表中，belongs_to 时在本表中
+	Ref      string       // 被外键引用的列名：has_xxx 时在本表中，belongs_to 时在关联表中，空代表主键
+	Index    []int        // 字段的反射索引
+	ElemType reflect.Type // 关联 Model 的结构体类型
+	IsPtr    bool         // 字段（has_many时是切片元素）是否为指针
+}
+
+func parseRelation(fi *reflect.StructField, idx []int, tag string) *Relation {
+	rel := &Relation{Name: fi.Name, Index: idx}
+	for i, item := range strings.Split(tag, ",") {
+		item = strings.TrimSpace(item)
+		if i == 0 {
+			rel.Kind = item
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			panic(fmt.Errorf("orm: field %s has wrong rel setting", fi.Name))
+		}
+		switch kv[0] {
+		case "fk":
+			rel.FK = strings.TrimSpace(kv[1])
+		case "ref":
+			rel.Ref = strings.TrimSpace(kv[1])
+		}
+	}
+
+	typ := fi.Type
+	switch rel.Kind {
+	case RelHasMany:
+		if typ.Kind() != reflect.Slice {
+			panic(fmt.Errorf("orm: has_many field %s must be a slice", fi.Name))
+		}
+		typ = typ.Elem()
+	case RelHasOne, RelBelongsTo:
+	default:
+		panic(fmt.Errorf("orm: field %s has unknown relation %s", fi.Name, rel.Kind))
+	}
+	if typ.Kind() == reflect.Ptr {
+		rel.IsPtr = true
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		panic(fmt.Errorf("orm: relation field %s must be struct", fi.Name))
+	}
+	rel.ElemType = typ
+	return rel
+}
+
+func (rel *Relation) fillDefault(modelName string) {
+	if rel.FK != "" {
+		return
+	}
+	if rel.Kind == RelBelongsTo {
+		rel.FK = lang.Camel2Snake(rel.Name) + "_id"
+	} else {
+		rel.FK = lang.Camel2Snake(modelName) + "_id"
+	}
+}
+
+func (ms *ModelSchema) Relation(name string) *Relation {
+	return ms.relations[name]
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package orm
+
+import (
+	"database/sql"
+	"reflect"
+	"time"
+)
+
+// 软删除和乐观锁，在字段上用 dbc 标记声明，比如：
+// DeletedAt *time.Time `dbc:"deleted_field"` 删除时设置为当前时间，查询时过滤 deleted_at IS NULL
+// Deleted   int64      `dbc:"deleted_field"` 删除时设置为当前时间戳，查询时过滤 deleted=0
+// Version   int64      `dbc:"version_field"` 更新时带上 version=? 的条件并加1
+var nullTimeType = reflect.TypeOf(sql.NullTime{})
+
+func (ms *ModelSchema) DeletedIndex() int8 {
+	return ms.deletedIndex
+}
+
+func (ms *ModelSchema) VersionIndex() int8 {
+	return ms.versionIndex
+}
+
+func (ms *ModelSchema) SoftDelete() bool {
+	return ms.deletedIndex >= 0
+}
+
+// 未删除记录的过滤条件，没有软删除字段时为空
+func (ms *ModelSchema) NotDeletedCond() string {
+	return ms.deletedCond
+}
+
+// 软删除时软删除字段要设置的值
+func (ms *ModelSchema) DeletedValue() any {
+	if ms.deletedUnix {
+		return time.Now().Unix()
+	}
+	return time.Now()
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+func fieldIndexOf(fields []string, name string) int {
+	for idx, f := range fields {
+		if f == name {
+			return idx
+		}
+	}
+	return -1
+}
+
+// 可为空的时间类型用 IS NULL 判断，整数类型用 =0 判断
+func notDeletedCond(column string, typ reflect.Type) (string, bool) {
+	if typ == nullTimeType || (typ.Kind() == reflect.Ptr && typ.Elem() == reflect.TypeOf(time.Time{})) {
+		return column + " IS NULL", false
+	}
+	if isIntType(typ) {
+		return column + "=0", true
+	}
+	panic("orm: deleted field must be *time.Time, sql.NullTime or integer")
+}
+
+func isIntType(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
@@ -33,8 +33,13 @@ func SchemaValues(obj any) (*ModelSchema, []any) {
 // 反射提取结构体的值（支持内联递归）
 func structValues(values *[]any, nextIndex *int8, obj any) {
 	rVal := reflect.Indirect(reflect.ValueOf(obj))
+	rTyp := rVal.Type()
 
 	for i := 0; i < rVal.NumField(); i++ {
+		// 关联字段不是数据库列
+		if rTyp.Field(i).Tag.Get(dbRelationTag) != "" {
+			continue
+		}
 		va := rVal.Field(i)
 		vaI := va.Interface()
 
@@ -77,10 +82,11 @@ func fetchSchema(rTyp reflect.Type) *ModelSchema {
 			panic(fmt.Errorf("target item type must be structs; but got %T", rTyp))
 		}
 
-		// primary, updated
-		mFields := [3]string{}
+		// auto, primary, updated, deleted, version, created
+		mFields := [6]string{}
 		rootIdx := make([]int, 0)
-		fDB, fStruct, fIndexes := structFields(rTyp, rootIdx, &mFields)
+		rels := make([]*Relation, 0)
+		fDB, fStruct, fIndexes := structFields(rTyp, rootIdx, &mFields, &rels)
 		if mFields[0] == "" {
 			mFields[0] = dbDefAutoIncKeyName
 		}
@@ -90,6 +96,9 @@ func fetchSchema(rTyp reflect.Type) *ModelSchema {
 		if mFields[2] == "" {
 			mFields[2] = dbDefUpdatedKeyName
 		}
+		if mFields[5] == "" {
+			mFields[5] = dbDefCreatedKeyName
+		}
 
 		// 0. 自增的索引位置 ++++++++++
 		var autoIndex = -1
@@ -119,6 +128,18 @@ func fetchSchema(rTyp reflect.Type) *ModelSchema {
 			}
 		}
 
+		createdIndex := fieldIndexOf(fStruct, mFields[5])
+
+		// 3. 软删除和版本号的索引位置，没有设置就不启用
+		deletedIndex, versionIndex := fieldIndexOf(fStruct, mFields[3]), fieldIndexOf(fStruct, mFields[4])
+		deletedCond, deletedUnix := "", false
+		if deletedIndex >= 0 {
+			deletedCond, deletedUnix = notDeletedCond(fDB[deletedIndex], rTyp.FieldByIndex(fIndexes[deletedIndex]).Type)
+		}
+		if versionIndex >= 0 && !isIntType(rTyp.FieldByIndex(fIndexes[versionIndex]).Type) {
+			panic(fmt.Errorf("%T, version field must be integer", rTyp))
+		}
+
 		// 获取 Model的所有控制属性
 		rTypVal := reflect.ValueOf(reflect.New(rTyp).Interface())
 		attrsFunc := rTypVal.MethodByName("GfAttrs")
@@ -156,6 +177,11 @@ func fetchSchema(rTyp reflect.Type) *ModelSchema {
 			autoIndex:    int8(autoIndex),
 			primaryIndex: int8(priIndex),
 			updatedIndex: int8(updateIndex),
+			createdIndex: int8(createdIndex),
+			deletedIndex: int8(deletedIndex),
+			versionIndex: int8(versionIndex),
+			deletedCond:  deletedCond,
+			deletedUnix:  deletedUnix,
 		}
 		for idx, name := range fStruct {
 			mSchema.fieldsKV[name] = int8(idx)
@@ -163,6 +189,13 @@ func fetchSchema(rTyp reflect.Type) *ModelSchema {
 		for idx, name := range fDBNew {
 			mSchema.columnsKV[name] = int8(idx)
 		}
+		if len(rels) > 0 {
+			mSchema.relations = make(map[string]*Relation, len(rels))
+			for _, rel := range rels {
+				rel.fillDefault(mName)
+				mSchema.relations[rel.Name] = rel
+			}
+		}
 		cacheSetSchema(rTyp, mSchema)
 	}
 
@@ -170,7 +203,7 @@ func fetchSchema(rTyp reflect.Type) *ModelSchema {
 }
 
 // 反射提取结构体的字段（支持嵌套递归）
-func structFields(rTyp reflect.Type, parentIdx []int, mFields *[3]string) ([]string, []string, [][]int) {
+func structFields(rTyp reflect.Type, parentIdx []int, mFields *[6]string, rels *[]*Relation) ([]string, []string, [][]int) {
 	if rTyp.Kind() != reflect.Struct {
 		panic(fmt.Errorf("%T is not like struct", rTyp))
 	}
@@ -191,13 +224,22 @@ func structFields(rTyp reflect.Type, parentIdx []int, mFields *[3]string) ([]str
 			newPIdx = append(newPIdx, parentIdx...)
 			newPIdx = append(newPIdx, i)
 
-			c, f, x := structFields(fiType, newPIdx, mFields)
+			c, f, x := structFields(fiType, newPIdx, mFields, rels)
 			fColumns = append(fColumns, c...)
 			fFields = append(fFields, f...)
 			fIndexes = append(fIndexes, x...)
 			continue
 		}
 
+		// 0. 关联字段不是数据库列
+		if tag := fi.Tag.Get(dbRelationTag); tag != "" {
+			rIdx := make([]int, 0, len(parentIdx)+1)
+			rIdx = append(rIdx, parentIdx...)
+			rIdx = append(rIdx, i)
+			*rels = append(*rels, parseRelation(&fi, rIdx, tag))
+			continue
+		}
+
 		// 1. 查找tag，确定数据库列名称
 		dbf := fi.Tag.Get(cst.FieldTagDB)
 		if dbf == "" {
@@ -231,6 +273,27 @@ func structFields(rTyp reflect.Type, parentIdx []int, mFields *[3]string) ([]str
 				mFields[2] = fi.Name
 			}
 		}
+		// 查找 deleted
+		if mFields[3] == "" {
+			dbc := fi.Tag.Get(dbConfigTag)
+			if strings.HasSuffix(dbc, dbDeletedKeyFlag) {
+				mFields[3] = fi.Name
+			}
+		}
+		// 查找 version
+		if mFields[4] == "" {
+			dbc := fi.Tag.Get(dbConfigTag)
+			if strings.HasSuffix(dbc, dbVersionKeyFlag) {
+				mFields[4] = fi.Name
+			}
+		}
+		// 查找 created
+		if mFields[5] == "" {
+			dbc := fi.Tag.Get(dbConfigTag)
+			if strings.HasSuffix(dbc, dbCreatedKeyFlag) {
+				mFields[5] = fi.Name
+			}
+		}
 
 		// 3. index
 		cIdx := make([]int, 0)
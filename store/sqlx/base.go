@@ -4,7 +4,8 @@ import (
 	"context"
 	"database/sql"
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/qinchende/gofast/connx/gfrds"
+	"github.com/qinchende/gofast/store/cache"
+	"time"
 )
 
 const (
@@ -16,19 +17,25 @@ const (
 type OrmDB struct {
 	Attrs    *DBAttrs
 	Ctx      context.Context
-	Reader   *sql.DB          // 只读连接（从库）
-	Writer   *sql.DB          // 只写连接（主库）
-	tx       *sql.Tx          // 读写皆可（主库）单独用于处理事务的连接
-	rdsNodes *[]gfrds.GfRedis // redis集群用来做缓存的
+	Reader   *sql.DB      // 只读连接（从库）
+	Writer   *sql.DB      // 只写连接（主库）
+	replicas *ReplicaPool // 多个从库时的读连接池，设置之后代替 Reader
+	tx       *sql.Tx      // 读写皆可（主库）单独用于处理事务的连接
+	spName   string       // 嵌套事务的保存点名称，为空代表最外层事务
+	spSeq    *int32       // 同一个事务中保存点的编号
+	preloads []string     // 查询之后需要预加载的关联关系
+	cache    cache.Cache  // 行记录和查询结果的缓存，可以是内存、redis或者两级缓存
 }
 
 type DBAttrs struct {
-	DriverName string // 数据库类型名称
-	DbName     string // 数据库名
+	DriverName    string        // 数据库类型名称
+	DbName        string        // 数据库名
+	SlowThreshold time.Duration // 慢日志阈值，0代表默认500ms
 }
 
 type StmtConn struct {
 	ctx      context.Context // 传递上下文
+	attrs    *DBAttrs        // 所属连接的属性
 	stmt     *sql.Stmt       // 标准库Stmt对象
 	sqlStr   string          // 预执行SQL语句
 	readonly bool            // 是否连接只读库
@@ -52,6 +59,7 @@ type SelectPet struct {
 	GroupBy  string     // group by
 	groupByT string     // group by inner temp
 	Args     []any      // SQL语句参数，防注入
+	Query    *Query     // 类型安全的查询条件，设置之后 Table、Where、GroupBy、OrderBy、Args 由它生成
 	PageSize uint32     // 分页大小
 	Page     uint32     // 当前页
 	Offset   uint32     // 查询偏移量
@@ -64,6 +72,7 @@ type SelectPet struct {
 type PetCache struct {
 	sqlHash   string
 	ExpireS   uint32 // 过期时间（秒）
+	RefreshS  uint32 // 提前刷新：缓存写入这么多秒后再被命中，就异步刷新缓存。0代表不启用，需小于ExpireS
 	CacheType uint8  // 缓存类型
 }
 
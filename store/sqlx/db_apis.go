@@ -1,12 +1,10 @@
 package sqlx
 
 import (
-	"github.com/qinchende/gofast/skill/jsonx"
-	"github.com/qinchende/gofast/skill/lang"
+	"database/sql"
 	"github.com/qinchende/gofast/store/orm"
 	"reflect"
 	"strings"
-	"time"
 )
 
 func (conn *OrmDB) Insert(obj orm.OrmStruct) int64 {
@@ -18,32 +16,59 @@ func (conn *OrmDB) Insert(obj orm.OrmStruct) int64 {
 		values[autoIdx] = values[0]
 	}
 
-	ret := conn.ExecSql(insertSql(sm), values[1:]...)
+	var ret sql.Result
+	if returning := conn.Dialect().Returning(autoColumn(sm)); returning != "" {
+		ret = conn.execReturningCtx(conn.Ctx, strings.TrimSuffix(insertSql(sm), ";")+returning+";", values[1:]...)
+	} else {
+		ret = conn.ExecSql(insertSql(sm), values[1:]...)
+	}
 	obj.AfterInsert(ret) // 反写值，比如主键ID
 	ct, err := ret.RowsAffected()
 	ErrLog(err)
+	if ct > 0 {
+		conn.delNullCache(sm, sm.PrimaryValue(obj))
+	}
 	return ct
 }
 
+// 有软删除字段时只是标记删除，物理删除用 ForceDelete
 func (conn *OrmDB) Delete(obj any) int64 {
 	sm := orm.Schema(obj)
 	val := sm.PrimaryValue(obj)
-	ret := conn.ExecSql(deleteSql(sm), val)
+	var ret sql.Result
+	if sm.SoftDelete() {
+		ret = conn.ExecSql(deleteSql(sm), sm.DeletedValue(), val)
+	} else {
+		ret = conn.ExecSql(deleteSql(sm), val)
+	}
 	return parseSqlResult(ret, val, conn, sm)
 }
 
 func (conn *OrmDB) Update(obj orm.OrmStruct) int64 {
 	obj.BeforeSave()
 	sm, values := orm.SchemaValues(obj)
+	rVal := reflect.Indirect(reflect.ValueOf(obj))
+
+	// 版本号：SET 新版本号，WHERE 老版本号
+	var curVer, nextVer any
+	if verIdx := sm.VersionIndex(); verIdx >= 0 {
+		curVer, nextVer = versionValues(sm, &rVal)
+		values[verIdx] = nextVer
+	}
 
 	fLen := len(values)
 	priIdx := sm.PrimaryIndex()
 	tVal := values[priIdx]
 	values[priIdx] = values[fLen-1]
 	values[fLen-1] = tVal
+	if curVer != nil {
+		values = append(values, curVer)
+	}
 
 	ret := conn.ExecSql(updateSql(sm), values...)
-	return parseSqlResult(ret, tVal, conn, sm)
+	ct := parseSqlResult(ret, tVal, conn, sm)
+	conn.checkVersion(sm, &rVal, ct, tVal, nextVer)
+	return ct
 }
 
 // 通过给定的结构体字段更新数据
@@ -54,12 +79,24 @@ func (conn *OrmDB) UpdateFields(obj orm.OrmStruct, fNames ...string) int64 {
 	obj.BeforeSave()
 	upSQL, tValues := updateSqlByFields(sm, &dstVal, fNames...)
 	ret := conn.ExecSql(upSQL, tValues...)
-	return parseSqlResult(ret, tValues[len(tValues)-1], conn, sm)
+	pk := sm.PrimaryValue(obj)
+	ct := parseSqlResult(ret, pk, conn, sm)
+	if sm.VersionIndex() >= 0 {
+		// tValues 的末尾依次是：新版本号，主键，老版本号
+		conn.checkVersion(sm, &dstVal, ct, pk, tValues[len(tValues)-3])
+	}
+	return ct
 }
 
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 // 对应ID值的一行记录
 func (conn *OrmDB) QueryPrimary(dest any, id any) int64 {
+	ct := conn.queryPrimary(dest, id)
+	conn.loadPreloads(dest, ct)
+	return ct
+}
+
+func (conn *OrmDB) queryPrimary(dest any, id any) int64 {
 	sm := orm.Schema(dest)
 	sqlRows := conn.QuerySql(selectSqlForPrimary(sm), id)
 	defer CloseSqlRows(sqlRows)
@@ -68,7 +105,9 @@ func (conn *OrmDB) QueryPrimary(dest any, id any) int64 {
 
 // 对应ID值的一行记录，支持行记录缓存
 func (conn *OrmDB) QueryPrimaryCache(dest any, id any) int64 {
-	return queryByPrimaryWithCache(conn, dest, id)
+	ct := queryByPrimaryWithCache(conn, dest, id)
+	conn.loadPreloads(dest, ct)
+	return ct
 }
 
 // 查询一行记录，查询条件自定义
@@ -80,7 +119,9 @@ func (conn *OrmDB) QueryRow2(dest any, fields string, where string, args ...any)
 	sm := orm.Schema(dest)
 	sqlRows := conn.QuerySql(selectSqlForOne(sm, fields, where), args...)
 	defer CloseSqlRows(sqlRows)
-	return scanSqlRowsOne(dest, sqlRows, sm, nil)
+	ct := scanSqlRowsOne(dest, sqlRows, sm, nil)
+	conn.loadPreloads(dest, ct)
+	return ct
 }
 
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
@@ -94,7 +135,9 @@ func (conn *OrmDB) QueryRows2(dest any, fields string, where string, args ...any
 	sqlRows := conn.QuerySql(selectSqlForSome(sm, fields, where), args...)
 	defer CloseSqlRows(sqlRows)
 
-	return scanSqlRowsSlice(dest, sqlRows, nil)
+	ct := scanSqlRowsSlice(dest, sqlRows, nil)
+	conn.loadPreloads(dest, ct)
+	return ct
 }
 
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
@@ -110,78 +153,63 @@ func (conn *OrmDB) QueryPet(pet *SelectPet) int64 {
 	}
 
 	ct, _ := conn.innerQueryPet(sql, "", pet, sm)
+	if pet.Result == nil || !pet.Result.GsonStr {
+		conn.loadPreloads(pet.Target, ct)
+	}
 	return ct
 }
 
 func (conn *OrmDB) innerQueryPet(sql, sqlCount string, pet *SelectPet, sm *orm.ModelSchema) (int64, int64) {
-	withCache := pet.Cache != nil && pet.Cache.ExpireS > 0
+	// 事务中读到的可能是还没提交的数据，不能走缓存，也不能和事务外的请求共享查询结果
+	withCache := pet.Cache != nil && pet.Cache.ExpireS > 0 && conn.cache != nil && conn.tx == nil
 	gsonStr := pet.Result != nil && pet.Result.GsonStr == true
 
-	var gr *gsonResult
-	rds := (*conn.rdsNodes)[0]
-	// 1. 需要走缓存版本
-	if withCache {
-		pet.Args = formatArgs(pet.Args)
-		pet.Cache.sqlHash = sm.CacheSqlKey(realSql(sql, pet.Args...))
-
-		cacheStr, err := rds.Get(pet.Cache.sqlHash)
-		if err == nil && cacheStr != "" {
-			if gsonStr {
-				pet.Result.Target = cacheStr
-				return 1, 0
-			}
-
-			gr = new(gsonResult)
-			err := loadRecordsFromGsonString(pet.Target, cacheStr, gr)
-			ErrPanic(err)
-			return gr.Ct, gr.Tt
+	// 1. 不走缓存，直接查询
+	if !withCache {
+		var gr *gsonResult
+		if gsonStr {
+			gr = &gsonResult{onlyGson: true}
 		}
-		gr = new(gsonResult)
-	}
-	if gsonStr {
-		if gr == nil {
-			gr = new(gsonResult)
+		ct, tt := conn.queryPetRows(sql, sqlCount, pet, sm, gr)
+		if gsonStr {
+			pet.Result.Target = gsonToString(gr)
 		}
-		gr.onlyGson = true
+		return ct, tt
 	}
 
-	// 2. 执行SQL查询并设置缓存
-	var tt int64
-	if sqlCount != "" {
-		// 此条件下一共多少条
-		sqlRows1 := conn.QuerySql(sqlCount, pet.Args...)
-		defer CloseSqlRows(sqlRows1)
-		scanSqlRowsOne(&tt, sqlRows1, sm, nil)
+	// 2. 需要走缓存版本
+	pet.Args = formatArgs(pet.Args)
+	pet.Cache.sqlHash = sm.CacheSqlKeyDB(conn.Attrs.DbName, realSql(sql, pet.Args...))
 
-		if tt <= 0 {
-			return 0, 0
-		}
-		if gr != nil {
-			gr.Tt = tt
+	var cacheStr string
+	err := conn.cache.Get(pet.Cache.sqlHash, &cacheStr)
+	if err == nil && cacheStr != "" {
+		conn.tryRefreshPet(sql, sqlCount, pet, sm)
+		if gsonStr {
+			pet.Result.Target = cacheStr
+			return 1, 0
 		}
+		return loadPetFromGson(pet, cacheStr)
 	}
 
-	sqlRows := conn.QuerySql(sql, pet.Args...)
-	defer CloseSqlRows(sqlRows)
-	ct := scanSqlRowsSlice(pet.Target, sqlRows, gr)
-
-	var err error
-	if gsonStr {
-		ret, err := jsonx.Marshal(gr.Gson)
-		ErrPanic(err)
-		pet.Result.Target = lang.BytesToString(ret)
+	// 3. 相同SQL的并发请求只有一个去查数据库并设置缓存，其它请求共享查询结果
+	val, fresh, _ := cacheBarrier.DoExt(pet.Cache.sqlHash, func() (any, error) {
+		return conn.queryPetAndCache(sql, sqlCount, pet, sm, gsonStr), nil
+	})
+	ret, _ := val.(*petLoaded)
+	if fresh {
+		return ret.ct, ret.tt
 	}
-	if ct > 0 && withCache {
-		cacheStr := new(any)
-		if gsonStr {
-			*cacheStr = pet.Result.Target
-		} else {
-			*cacheStr, err = jsonx.Marshal(gr.Gson)
-			ErrPanic(err)
-		}
-		_, _ = rds.Set(pet.Cache.sqlHash, *cacheStr, time.Duration(pet.Cache.ExpireS)*time.Second)
+	// 共享的查询发生了异常，自己再查一次
+	if ret == nil {
+		ret = conn.queryPetAndCache(sql, sqlCount, pet, sm, gsonStr)
+		return ret.ct, ret.tt
 	}
-	return ct, tt
+	if gsonStr {
+		pet.Result.Target = ret.gson
+		return ret.ct, ret.tt
+	}
+	return loadPetFromGson(pet, ret.gson)
 }
 
 func (conn *OrmDB) QueryPetPaging(pet *SelectPet) (int64, int64) {
@@ -199,7 +227,11 @@ func (conn *OrmDB) QueryPetPaging(pet *SelectPet) (int64, int64) {
 		sql = selectPagingSqlForPet(sm, pet)
 	}
 
-	return conn.innerQueryPet(sql, sqlCt, pet, sm)
+	ct, tt := conn.innerQueryPet(sql, sqlCt, pet, sm)
+	if pet.Result == nil || !pet.Result.GsonStr {
+		conn.loadPreloads(pet.Target, ct)
+	}
+	return ct, tt
 }
 
 func (conn *OrmDB) DeletePetCache(pet *SelectPet) (err error) {
@@ -213,10 +245,10 @@ func (conn *OrmDB) DeletePetCache(pet *SelectPet) (err error) {
 	}
 
 	pet.Args = formatArgs(pet.Args)
-	pet.Cache.sqlHash = sm.CacheSqlKey(realSql(sql, pet.Args...))
+	pet.Cache.sqlHash = sm.CacheSqlKeyDB(conn.Attrs.DbName, realSql(sql, pet.Args...))
 
-	key := pet.Cache.sqlHash
-	rds := (*conn.rdsNodes)[0]
-	_, err = rds.Del(key)
+	if conn.cache != nil {
+		err = conn.cache.Del(pet.Cache.sqlHash)
+	}
 	return
 }
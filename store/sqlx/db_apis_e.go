@@ -0,0 +1,115 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package sqlx
+
+import (
+	"database/sql"
+	"github.com/qinchende/gofast/cst"
+	"github.com/qinchende/gofast/store/orm"
+)
+
+// 下面是返回 error 的版本，数据库错误不会 panic，适合在 HTTP 请求之外的后台任务中使用
+// 返回的错误是 *DBError，可以用 errors.Is(err, sqlx.ErrDuplicate) 等判断类型
+// 查询单条记录没有结果时返回 ErrNotFound；参数错误、runtime.Error 等程序 bug 仍然会 panic
+
+func (conn *OrmDB) ExecSqlE(sqlStr string, args ...any) (ret sql.Result, err error) {
+	defer catchErr(&err)
+	return conn.ExecSql(sqlStr, args...), nil
+}
+
+func (conn *OrmDB) InsertE(obj orm.OrmStruct) (ct int64, err error) {
+	defer catchErr(&err)
+	return conn.Insert(obj), nil
+}
+
+func (conn *OrmDB) DeleteE(obj any) (ct int64, err error) {
+	defer catchErr(&err)
+	return conn.Delete(obj), nil
+}
+
+func (conn *OrmDB) UpdateE(obj orm.OrmStruct) (ct int64, err error) {
+	defer catchErr(&err)
+	return conn.Update(obj), nil
+}
+
+func (conn *OrmDB) UpdateFieldsE(obj orm.OrmStruct, fNames ...string) (ct int64, err error) {
+	defer catchErr(&err)
+	return conn.UpdateFields(obj, fNames...), nil
+}
+
+func (conn *OrmDB) InsertBatchE(objs any) (ct int64, err error) {
+	defer catchErr(&err)
+	return conn.InsertBatch(objs), nil
+}
+
+func (conn *OrmDB) UpsertE(obj orm.OrmStruct, conflictCls ...string) (ct int64, err error) {
+	defer catchErr(&err)
+	return conn.Upsert(obj, conflictCls...), nil
+}
+
+func (conn *OrmDB) DeleteByIDsE(obj any, ids ...any) (ct int64, err error) {
+	defer catchErr(&err)
+	return conn.DeleteByIDs(obj, ids...), nil
+}
+
+func (conn *OrmDB) UpdateWhereE(obj any, kv cst.KV, where string, args ...any) (ct int64, err error) {
+	defer catchErr(&err)
+	return conn.UpdateWhere(obj, kv, where, args...), nil
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+func (conn *OrmDB) QueryPrimaryE(dest any, id any) error {
+	return notFoundE(func() int64 { return conn.QueryPrimary(dest, id) })
+}
+
+func (conn *OrmDB) QueryPrimaryCacheE(dest any, id any) error {
+	return notFoundE(func() int64 { return conn.QueryPrimaryCache(dest, id) })
+}
+
+func (conn *OrmDB) QueryRowE(dest any, where string, args ...any) error {
+	return notFoundE(func() int64 { return conn.QueryRow(dest, where, args...) })
+}
+
+func (conn *OrmDB) QueryRow2E(dest any, fields string, where string, args ...any) error {
+	return notFoundE(func() int64 { return conn.QueryRow2(dest, fields, where, args...) })
+}
+
+// 多条记录的查询，没有结果不算错误
+func (conn *OrmDB) QueryRowsE(dest any, where string, args ...any) (ct int64, err error) {
+	defer catchErr(&err)
+	return conn.QueryRows(dest, where, args...), nil
+}
+
+func (conn *OrmDB) QueryRows2E(dest any, fields string, where string, args ...any) (ct int64, err error) {
+	defer catchErr(&err)
+	return conn.QueryRows2(dest, fields, where, args...), nil
+}
+
+func (conn *OrmDB) QueryPetE(pet *SelectPet) (ct int64, err error) {
+	defer catchErr(&err)
+	return conn.QueryPet(pet), nil
+}
+
+func (conn *OrmDB) QueryPetPagingE(pet *SelectPet) (ct int64, tt int64, err error) {
+	defer catchErr(&err)
+	ct, tt = conn.QueryPetPaging(pet)
+	return
+}
+
+func notFoundE(fn func() int64) (err error) {
+	defer catchErr(&err)
+	if fn() == 0 {
+		return &DBError{Kind: ErrNotFound, Err: sql.ErrNoRows}
+	}
+	return nil
+}
+
+func (conn *OrmDB) QueryEachE(dest any, fn func() bool, where string, args ...any) (ct int64, err error) {
+	defer catchErr(&err)
+	return conn.QueryEach(dest, fn, where, args...), nil
+}
+
+func (conn *OrmDB) QueryChunksE(dest any, size int, fn func() bool, where string, args ...any) (ct int64, err error) {
+	defer catchErr(&err)
+	return conn.QueryChunks(dest, size, fn, where, args...), nil
+}
@@ -6,20 +6,61 @@ import (
 	"github.com/qinchende/gofast/connx/gfrds"
 	"github.com/qinchende/gofast/logx"
 	"github.com/qinchende/gofast/skill/timex"
+	"github.com/qinchende/gofast/store/cache"
 	"time"
 )
 
-// 执行超过500ms的语句需要优化分析，我们先打印出慢日志
+// 执行超过500ms的语句需要优化分析，我们先打印出慢日志。可以用 DBAttrs.SlowThreshold 单独设置
 const slowThreshold = time.Millisecond * 500
 
+// 多个redis节点时，缓存Key按一致性hash分布到各个节点
 func (conn *OrmDB) SetRdsNodes(nodes *[]gfrds.GfRedis) {
-	if len(*nodes) > 0 {
-		conn.rdsNodes = nodes
-	} else {
-		conn.rdsNodes = nil
+	switch len(*nodes) {
+	case 0:
+		conn.cache = nil
+	case 1:
+		conn.cache = cache.NewRedisCache(&(*nodes)[0], 0)
+	default:
+		rdsNodes := make([]*gfrds.GfRedis, len(*nodes))
+		for i := range *nodes {
+			rdsNodes[i] = &(*nodes)[i]
+		}
+		conn.cache = cache.NewShardRedisCache(rdsNodes, 0)
 	}
 }
 
+// 任意实现了 cache.Cache 的缓存，传nil代表不用缓存
+func (conn *OrmDB) SetCache(c cache.Cache) {
+	conn.cache = c
+}
+
+func (conn *OrmDB) Cache() cache.Cache {
+	return conn.cache
+}
+
+// 设置多个从库，查询按权重分配到健康的从库上，传nil代表只用 Reader
+func (conn *OrmDB) SetReplicas(rp *ReplicaPool) {
+	conn.replicas = rp
+}
+
+func (conn *OrmDB) Replicas() *ReplicaPool {
+	return conn.replicas
+}
+
+// 选择执行查询的连接：读己之写的 ctx 写过之后走主库，否则优先从库池，从库全部不可用时走主库
+func (conn *OrmDB) readerDB(ctx context.Context) *sql.DB {
+	if mustWriter(ctx) {
+		return conn.Writer
+	}
+	if conn.replicas != nil {
+		if db := conn.replicas.pick(); db != nil {
+			return db
+		}
+		return conn.Writer
+	}
+	return conn.Reader
+}
+
 func (conn *OrmDB) CloneWithCtx(ctx context.Context) *OrmDB {
 	newConn := *conn
 	newConn.Ctx = ctx
@@ -39,20 +80,45 @@ func (conn *OrmDB) ExecSqlCtx(ctx context.Context, sqlStr string, args ...any) s
 
 	var result sql.Result
 	var err error
+	sCtx, span := startSqlSpan(ctx, conn.Attrs, sqlStr)
 	startTime := timex.Now()
+	dbSql := rebind(conn.Dialect(), sqlStr)
 	if conn.tx == nil {
-		result, err = conn.Writer.ExecContext(ctx, sqlStr, args...)
+		result, err = conn.Writer.ExecContext(sCtx, dbSql, args...)
 	} else {
-		result, err = conn.tx.ExecContext(ctx, sqlStr, args...)
-	}
-	dur := timex.NowDiff(startTime)
-	if dur > slowThreshold {
-		logx.SlowF("[SQL][%dms] exec: slow-call - %s", dur/time.Millisecond, realSql(sqlStr, args...))
+		result, err = conn.tx.ExecContext(sCtx, dbSql, args...)
 	}
+	markWritten(ctx)
+	endSqlSpan(span, conn.Attrs, "exec", sqlStr, args, startTime, result, err)
 	ErrPanic(err)
 	return result
 }
 
+// 不支持 LastInsertId 的数据库，insert 语句带上 RETURNING 在主库上执行，拿到自增主键
+func (conn *OrmDB) execReturningCtx(ctx context.Context, sqlStr string, args ...any) sql.Result {
+	args = formatArgs(args)
+	if logx.ShowDebug() {
+		logx.Debug(realSql(sqlStr, args...))
+	}
+
+	var row *sql.Row
+	sCtx, span := startSqlSpan(ctx, conn.Attrs, sqlStr)
+	startTime := timex.Now()
+	dbSql := rebind(conn.Dialect(), sqlStr)
+	if conn.tx == nil {
+		row = conn.Writer.QueryRowContext(sCtx, dbSql, args...)
+	} else {
+		row = conn.tx.QueryRowContext(sCtx, dbSql, args...)
+	}
+	markWritten(ctx)
+	var id int64
+	err := row.Scan(&id)
+	ret := returningResult{id: id, ct: 1}
+	endSqlSpan(span, conn.Attrs, "exec", sqlStr, args, startTime, ret, err)
+	ErrPanic(err)
+	return ret
+}
+
 func (conn *OrmDB) QuerySql(sqlStr string, args ...any) *sql.Rows {
 	return conn.QuerySqlCtx(conn.Ctx, sqlStr, args...)
 }
@@ -65,16 +131,15 @@ func (conn *OrmDB) QuerySqlCtx(ctx context.Context, sqlStr string, args ...any)
 
 	var rows *sql.Rows
 	var err error
+	sCtx, span := startSqlSpan(ctx, conn.Attrs, sqlStr)
 	startTime := timex.Now()
+	dbSql := rebind(conn.Dialect(), sqlStr)
 	if conn.tx == nil {
-		rows, err = conn.Reader.QueryContext(ctx, sqlStr, args...)
+		rows, err = conn.readerDB(ctx).QueryContext(sCtx, dbSql, args...)
 	} else {
-		rows, err = conn.tx.QueryContext(ctx, sqlStr, args...)
-	}
-	dur := timex.NowDiff(startTime)
-	if dur > slowThreshold {
-		logx.SlowF("[SQL][%dms] query: slow-call - %s", dur/time.Millisecond, realSql(sqlStr, args...))
+		rows, err = conn.tx.QueryContext(sCtx, dbSql, args...)
 	}
+	endSqlSpan(span, conn.Attrs, "query", sqlStr, args, startTime, nil, err)
 	ErrPanic(err)
 	return rows
 }
@@ -85,9 +150,18 @@ func (conn *OrmDB) TransBegin() *OrmDB {
 }
 
 func (conn *OrmDB) TransCtx(ctx context.Context) *OrmDB {
-	tx, err := conn.Writer.BeginTx(ctx, nil)
+	return conn.TransCtxOpts(ctx, nil)
+}
+
+// 可以指定事务的隔离级别。已经在事务中时，开启的是嵌套事务（SAVEPOINT），opts 被忽略
+func (conn *OrmDB) TransCtxOpts(ctx context.Context, opts *sql.TxOptions) *OrmDB {
+	if conn.tx != nil {
+		return conn.savepoint(ctx)
+	}
+
+	tx, err := conn.Writer.BeginTx(ctx, opts)
 	ErrPanic(err)
-	return &OrmDB{Attrs: conn.Attrs, Ctx: ctx, tx: tx, rdsNodes: conn.rdsNodes}
+	return &OrmDB{Attrs: conn.Attrs, Ctx: ctx, Reader: conn.Reader, Writer: conn.Writer, replicas: conn.replicas, tx: tx, spSeq: new(int32), cache: conn.cache}
 }
 
 func (conn *OrmDB) TransFunc(fn func(newConn *OrmDB)) {
@@ -95,29 +169,46 @@ func (conn *OrmDB) TransFunc(fn func(newConn *OrmDB)) {
 }
 
 func (conn *OrmDB) TransFuncCtx(ctx context.Context, fn func(newConn *OrmDB)) {
-	tx, err := conn.Writer.BeginTx(ctx, nil)
-	ErrPanic(err)
+	conn.TransFuncOpts(ctx, nil, fn)
+}
 
-	nConn := OrmDB{Attrs: conn.Attrs, Ctx: ctx, tx: tx, rdsNodes: conn.rdsNodes}
+func (conn *OrmDB) TransFuncOpts(ctx context.Context, opts *sql.TxOptions, fn func(newConn *OrmDB)) {
+	nConn := conn.TransCtxOpts(ctx, opts)
 	defer nConn.TransEnd()
-	fn(&nConn)
+	fn(nConn)
 }
 
+// 嵌套事务中 Commit 只是释放保存点，真正提交要等最外层的事务
 func (conn *OrmDB) Commit() error {
+	if conn.spName != "" {
+		_, err := conn.tx.ExecContext(conn.Ctx, "RELEASE SAVEPOINT "+conn.spName)
+		return err
+	}
 	return conn.tx.Commit()
 }
 
+// 嵌套事务中 Rollback 只回滚到保存点，外层事务可以继续
 func (conn *OrmDB) Rollback() error {
+	if conn.spName != "" {
+		_, err := conn.tx.ExecContext(conn.Ctx, "ROLLBACK TO SAVEPOINT "+conn.spName)
+		return err
+	}
 	return conn.tx.Rollback()
 }
 
 func (conn *OrmDB) TransEnd() {
 	var err error
 
-	if pic := recover(); pic != nil {
+	pic := recover()
+	if pic != nil {
 		err = conn.Rollback()
 	} else {
 		err = conn.Commit()
 	}
 	ErrLog(err) // 出现了非常严重的错误，可能连接没有释放
+
+	// 嵌套事务的异常要继续抛给外层事务，不能吞掉。比如死锁之后整个事务已经被数据库回滚，外层不能当作成功提交
+	if pic != nil && conn.spName != "" {
+		panic(pic)
+	}
 }
@@ -0,0 +1,256 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package sqlx
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/qinchende/gofast/cst"
+	"github.com/qinchende/gofast/store/orm"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 批量插入，objs 是 OrmStruct 的切片，比如 []*User
+// 按方言的最大参数个数拆成多条 INSERT ... VALUES (...),(...) 执行。注意：不会反写自增主键
+func (conn *OrmDB) InsertBatch(objs any) int64 {
+	rVal := reflect.Indirect(reflect.ValueOf(objs))
+	if rVal.Kind() != reflect.Slice {
+		panic("sqlx: InsertBatch args [objs] must be a slice")
+	}
+	if rVal.Len() == 0 {
+		return 0
+	}
+
+	var sm *orm.ModelSchema
+	var rows [][]any
+	var keys []any
+	for i := 0; i < rVal.Len(); i++ {
+		obj, ok := rVal.Index(i).Interface().(orm.OrmStruct)
+		if !ok {
+			panic(fmt.Errorf("sqlx: InsertBatch item %T is not orm.OrmStruct", rVal.Index(i).Interface()))
+		}
+		obj.BeforeSave()
+		var values []any
+		sm, values = orm.SchemaValues(obj)
+		if autoIdx := sm.AutoIndex(); autoIdx > 0 {
+			values[autoIdx] = values[0]
+		}
+		rows = append(rows, values[1:])
+		if pk := sm.PrimaryValue(obj); !reflect.ValueOf(pk).IsZero() {
+			keys = append(keys, pk)
+		}
+	}
+
+	base := strings.TrimSuffix(insertSql(sm), ";")
+	tuple := base[strings.LastIndexByte(base, '('):]
+	step := conn.Dialect().MaxParams() / len(rows[0])
+	if step < 1 {
+		panic(fmt.Errorf("sqlx: InsertBatch %s has %d columns, more than the dialect max params %d", sm.TableName(), len(rows[0]), conn.Dialect().MaxParams()))
+	}
+
+	var ct int64
+	for start := 0; start < len(rows); start += step {
+		end := start + step
+		if end > len(rows) {
+			end = len(rows)
+		}
+		args := make([]any, 0, (end-start)*len(rows[0]))
+		for _, row := range rows[start:end] {
+			args = append(args, row...)
+		}
+		sqlStr := base + strings.Repeat(","+tuple, end-start-1) + ";"
+		n, err := conn.ExecSql(sqlStr, args...).RowsAffected()
+		ErrLog(err)
+		ct += n
+	}
+	conn.delNullCache(sm, keys...)
+	return ct
+}
+
+// 插入记录，冲突时更新除主键和创建时间之外的所有字段
+// 主键为零值时和 Insert 一样不给自增字段赋值，并反写自增主键（冲突更新时反写的是已有记录的主键）
+// MySQL 冲突但是字段值没有变化时影响行数为0，这时不反写主键
+// conflictCls 是判断冲突的列（需要有唯一索引），默认是主键。注意方言差异：
+// PostgreSQL|SQLite 只有 conflictCls 上的冲突才会更新，其它唯一索引冲突直接报错；
+// MySQL 不能指定冲突列，忽略 conflictCls，任何一个唯一索引冲突都会更新
+func (conn *OrmDB) Upsert(obj orm.OrmStruct, conflictCls ...string) int64 {
+	obj.BeforeSave()
+	sm, values := orm.SchemaValues(obj)
+	cls := sm.Columns()
+	priIdx := sm.PrimaryIndex()
+	pk := values[priIdx]
+	withPk := !reflect.ValueOf(pk).IsZero()
+
+	crtIdx := sm.CreatedIndex()
+	upCls := make([]string, 0, len(cls))
+	for i, c := range cls {
+		if i != int(priIdx) && i != int(crtIdx) && c != autoColumn(sm) {
+			upCls = append(upCls, c)
+		}
+	}
+	if len(conflictCls) == 0 {
+		conflictCls = []string{cls[priIdx]}
+	}
+
+	dl := conn.Dialect()
+	var sqlStr string
+	if withPk {
+		sqlStr = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", sm.TableName(), strings.Join(cls, ","), strings.TrimSuffix(strings.Repeat("?,", len(cls)), ","))
+	} else {
+		sqlStr = strings.TrimSuffix(insertSql(sm), ";")
+		if autoIdx := sm.AutoIndex(); autoIdx > 0 {
+			values[autoIdx] = values[0]
+		}
+		values = values[1:]
+	}
+	sqlStr += dl.Upsert(conflictCls, upCls)
+
+	var returning string
+	if !withPk {
+		ac := autoColumn(sm)
+		switch dl.Name() {
+		case DriverMysql:
+			// 更新已有记录时 LastInsertId 不是这条记录的主键，用 LAST_INSERT_ID(id) 让它返回已有记录的主键
+			if len(upCls) > 0 {
+				sqlStr += ","
+			}
+			sqlStr += ac + "=LAST_INSERT_ID(" + ac + ")"
+		case DriverSqlite:
+			// SQLite 更新已有记录时 last_insert_rowid 不变，只能用 RETURNING 拿主键（3.35 之后支持）
+			returning = " RETURNING " + ac
+		default:
+			returning = dl.Returning(ac)
+		}
+	}
+
+	var ret sql.Result
+	if returning != "" {
+		ret = conn.execReturningCtx(conn.Ctx, sqlStr+returning+";", values...)
+	} else {
+		ret = conn.ExecSql(sqlStr+";", values...)
+	}
+
+	ct, err := ret.RowsAffected()
+	ErrLog(err)
+	if ct > 0 {
+		if !withPk {
+			obj.AfterInsert(ret)
+			pk = sm.PrimaryValue(obj)
+		}
+		conn.delLineCache(sm, pk)
+	}
+	return ct
+}
+
+// 按主键批量删除，obj 只用来确定表结构，比如 &User{}。有软删除字段时只是标记删除
+func (conn *OrmDB) DeleteByIDs(obj any, ids ...any) int64 {
+	if len(ids) == 0 {
+		return 0
+	}
+	sm := orm.Schema(obj)
+	step := conn.Dialect().MaxParams()
+	if sm.SoftDelete() {
+		step-- // 软删除多一个删除标记的参数
+	}
+
+	var ct int64
+	for start := 0; start < len(ids); start += step {
+		end := start + step
+		if end > len(ids) {
+			end = len(ids)
+		}
+		marks := strings.TrimSuffix(strings.Repeat("?,", end-start), ",")
+		var n int64
+		var err error
+		if sm.SoftDelete() {
+			sqlStr := fmt.Sprintf("UPDATE %s SET %s=? WHERE %s IN (%s) AND %s;", sm.TableName(), sm.Columns()[sm.DeletedIndex()],
+				sm.Columns()[sm.PrimaryIndex()], marks, sm.NotDeletedCond())
+			n, err = conn.ExecSql(sqlStr, append([]any{sm.DeletedValue()}, ids[start:end]...)...).RowsAffected()
+		} else {
+			sqlStr := fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s);", sm.TableName(), sm.Columns()[sm.PrimaryIndex()], marks)
+			n, err = conn.ExecSql(sqlStr, ids[start:end]...).RowsAffected()
+		}
+		ErrLog(err)
+		ct += n
+	}
+	if ct > 0 {
+		conn.delLineCache(sm, ids...)
+	}
+	return ct
+}
+
+// 按条件批量更新，kv 的 key 可以是字段名或者数据库列名，obj 只用来确定表结构
+// 如果有更新时间字段并且 kv 中没有指定，自动设置为当前时间
+// 开启了行记录缓存时，会先查出受影响记录的主键，更新之后删除这些缓存
+func (conn *OrmDB) UpdateWhere(obj any, kv cst.KV, where string, args ...any) int64 {
+	if len(kv) == 0 {
+		panic("sqlx: UpdateWhere args [kv] is empty")
+	}
+	if strings.TrimSpace(where) == "" {
+		panic("sqlx: UpdateWhere args [where] is empty")
+	}
+
+	sm := orm.Schema(obj)
+	cls := sm.Columns()
+	sets := make(map[string]any, len(kv)+1)
+	for k, v := range kv {
+		if _, ok := sm.ColumnsKV()[k]; ok {
+			sets[k] = v
+		} else if idx, ok := sm.FieldsKV()[k]; ok {
+			sets[cls[idx]] = v
+		} else {
+			panic(fmt.Errorf("sqlx: UpdateWhere field %s not exist", k))
+		}
+	}
+	if upIdx := sm.UpdatedIndex(); upIdx >= 0 {
+		if _, ok := sets[cls[upIdx]]; !ok {
+			sets[cls[upIdx]] = time.Now()
+		}
+	}
+
+	// 字段排序，保证相同的更新生成相同的SQL
+	names := make([]string, 0, len(sets))
+	for k := range sets {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	values := make([]any, 0, len(names)+len(args))
+	for _, k := range names {
+		values = append(values, sets[k])
+	}
+	values = append(values, args...)
+
+	var ids []any
+	if sm.CacheAll() && conn.cache != nil {
+		ids = conn.queryPrimaryIDs(sm, where, args...)
+	}
+
+	sqlStr := fmt.Sprintf("UPDATE %s SET %s=? WHERE %s;", sm.TableName(), strings.Join(names, "=?,"), where)
+	ct, err := conn.ExecSql(sqlStr, values...).RowsAffected()
+	ErrLog(err)
+	if ct > 0 {
+		conn.delLineCache(sm, ids...)
+	}
+	return ct
+}
+
+func (conn *OrmDB) queryPrimaryIDs(sm *orm.ModelSchema, where string, args ...any) []any {
+	sqlStr := fmt.Sprintf("SELECT %s FROM %s WHERE %s;", sm.Columns()[sm.PrimaryIndex()], sm.TableName(), where)
+	sqlRows := conn.QuerySql(sqlStr, args...)
+	defer CloseSqlRows(sqlRows)
+
+	var ids []any
+	for sqlRows.Next() {
+		var id any
+		ErrPanic(sqlRows.Scan(&id))
+		if bs, ok := id.([]byte); ok {
+			id = string(bs)
+		}
+		ids = append(ids, id)
+	}
+	ErrLog(sqlRows.Err())
+	return ids
+}
@@ -0,0 +1,193 @@
+package sqlx
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"github.com/qinchende/gofast/cst"
+	"github.com/qinchende/gofast/store/orm"
+	"regexp"
+	"testing"
+	"time"
+)
+
+type bAccount struct {
+	ID        int64 `dbc:"primary_field"`
+	Name      string
+	Age       int
+	CreatedAt time.Time `dbc:"created_field"`
+}
+
+func (a *bAccount) GfAttrs(orm.OrmStruct) *orm.ModelAttrs {
+	return &orm.ModelAttrs{TableName: "accounts"}
+}
+func (a *bAccount) BeforeSave() {}
+func (a *bAccount) AfterInsert(ret sql.Result) {
+	if id, err := ret.LastInsertId(); err == nil {
+		a.ID = id
+	}
+}
+
+// 时间参数执行前会格式化成字符串
+var zeroTime = time.Time{}.Format(timeFormat)
+
+// 参数上限很小的方言，用来测试拆分批次
+type tinyDialect struct {
+	mysqlDialect
+}
+
+func (tinyDialect) MaxParams() int {
+	return 5
+}
+
+func init() {
+	RegisterDialect("tiny", tinyDialect{})
+}
+
+func TestInsertBatchSql(t *testing.T) {
+	m, conn := newMockDB(t, "tiny")
+	m.expectExec("^"+regexp.QuoteMeta("INSERT INTO accounts (name,age,created_at) VALUES (?,?,?);")+"$",
+		"a", 1, zeroTime).withResult(0, 1)
+	m.expectExec("^"+regexp.QuoteMeta("INSERT INTO accounts (name,age,created_at) VALUES (?,?,?),(?,?,?);")+"$",
+		"b", 2, zeroTime, "c", 3, zeroTime).withResult(0, 2)
+
+	// 5个参数只能放下1行，改成 mysql 之后能一次插完
+	objs := []*bAccount{{Name: "a", Age: 1}, {Name: "b", Age: 2}, {Name: "c", Age: 3}}
+	conn.Attrs.DriverName = "tiny"
+	if ct := conn.InsertBatch(objs[:1]); ct != 1 {
+		t.Fatalf("got %d", ct)
+	}
+	conn.Attrs.DriverName = DriverMysql
+	if ct := conn.InsertBatch(objs[1:]); ct != 2 {
+		t.Fatalf("got %d", ct)
+	}
+}
+
+func TestInsertBatchSplit(t *testing.T) {
+	m, conn := newMockDB(t, "tiny")
+	for i := 0; i < 3; i++ {
+		m.expectExec("^"+regexp.QuoteMeta("INSERT INTO accounts (name,age,created_at) VALUES (?,?,?);")+"$").withResult(0, 1)
+	}
+	objs := []*bAccount{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	if ct := conn.InsertBatch(&objs); ct != 3 {
+		t.Fatalf("got %d", ct)
+	}
+}
+
+type wideRow struct {
+	ID int64 `dbc:"primary_field"`
+	A  int
+	B  int
+	C  int
+	D  int
+	E  int
+	F  int
+}
+
+func (w *wideRow) GfAttrs(orm.OrmStruct) *orm.ModelAttrs { return &orm.ModelAttrs{TableName: "wides"} }
+func (w *wideRow) BeforeSave()                           {}
+func (w *wideRow) AfterInsert(sql.Result)                {}
+
+func TestInsertBatchTooManyColumns(t *testing.T) {
+	_, conn := newMockDB(t, "tiny")
+	defer func() {
+		if recover() == nil {
+			t.Error("more columns than max params should panic")
+		}
+	}()
+	conn.InsertBatch([]*wideRow{{}})
+}
+
+func TestUpsertSql(t *testing.T) {
+	cases := []struct {
+		driver string
+		obj    *bAccount
+		sql    string
+		args   []any
+		query  bool
+		id     int64
+	}{
+		{
+			DriverMysql, &bAccount{Name: "a", Age: 1},
+			"INSERT INTO accounts (name,age,created_at) VALUES (?,?,?) ON DUPLICATE KEY UPDATE name=VALUES(name),age=VALUES(age),id=LAST_INSERT_ID(id);",
+			[]any{"a", 1, zeroTime}, false, 7,
+		},
+		{
+			DriverMysql, &bAccount{ID: 3, Name: "a", Age: 1},
+			"INSERT INTO accounts (id,name,age,created_at) VALUES (?,?,?,?) ON DUPLICATE KEY UPDATE name=VALUES(name),age=VALUES(age);",
+			[]any{3, "a", 1, zeroTime}, false, 3,
+		},
+		{
+			DriverPostgres, &bAccount{Name: "a", Age: 1},
+			"INSERT INTO accounts (name,age,created_at) VALUES ($1,$2,$3) ON CONFLICT (id) DO UPDATE SET name=EXCLUDED.name,age=EXCLUDED.age RETURNING id;",
+			[]any{"a", 1, zeroTime}, true, 7,
+		},
+		{
+			DriverSqlite, &bAccount{Name: "a", Age: 1},
+			"INSERT INTO accounts (name,age,created_at) VALUES (?,?,?) ON CONFLICT (id) DO UPDATE SET name=EXCLUDED.name,age=EXCLUDED.age RETURNING id;",
+			[]any{"a", 1, zeroTime}, true, 7,
+		},
+	}
+	for _, c := range cases {
+		m, conn := newMockDB(t, c.driver)
+		if c.query {
+			m.expectQuery("^"+regexp.QuoteMeta(c.sql)+"$", c.args...).withRows([]string{"id"}, []driver.Value{c.id})
+		} else {
+			m.expectExec("^"+regexp.QuoteMeta(c.sql)+"$", c.args...).withResult(c.id, 2)
+		}
+		if ct := conn.Upsert(c.obj); ct < 1 || c.obj.ID != c.id {
+			t.Errorf("%s: got ct %d id %d, want id %d", c.driver, ct, c.obj.ID, c.id)
+		}
+	}
+}
+
+// 冲突但是没有变化时影响行数为0，不能把 LastInsertId 当作主键反写
+func TestUpsertNoChange(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	m.expectExec("^INSERT INTO accounts").withResult(9, 0)
+	obj := &bAccount{Name: "a"}
+	if ct := conn.Upsert(obj); ct != 0 || obj.ID != 0 {
+		t.Fatalf("got ct %d id %d", ct, obj.ID)
+	}
+}
+
+func TestDeleteByIDsSql(t *testing.T) {
+	m, conn := newMockDB(t, "tiny")
+	m.expectExec("^"+regexp.QuoteMeta("DELETE FROM accounts WHERE id IN (?,?,?,?,?);")+"$", 1, 2, 3, 4, 5).withResult(0, 5)
+	m.expectExec("^"+regexp.QuoteMeta("DELETE FROM accounts WHERE id IN (?);")+"$", 6).withResult(0, 1)
+	if ct := conn.DeleteByIDs(&bAccount{}, 1, 2, 3, 4, 5, 6); ct != 6 {
+		t.Fatalf("got %d", ct)
+	}
+
+	// 软删除多一个参数，每批少一个主键
+	m.expectExec(`^UPDATE users SET deleted_at=\? WHERE id IN \(\?,\?,\?,\?\) AND deleted_at IS NULL;$`).withResult(0, 4)
+	m.expectExec(`^UPDATE users SET deleted_at=\? WHERE id IN \(\?\) AND deleted_at IS NULL;$`).withResult(0, 1)
+	if ct := conn.DeleteByIDs(&qUser{}, 1, 2, 3, 4, 5); ct != 5 {
+		t.Fatalf("soft got %d", ct)
+	}
+	if ct := conn.DeleteByIDs(&qUser{}); ct != 0 {
+		t.Fatalf("empty got %d", ct)
+	}
+}
+
+func TestUpdateWhereSql(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	m.expectExec("^"+regexp.QuoteMeta("UPDATE accounts SET age=?,name=? WHERE age>?;")+"$", 2, "x", 1).withResult(0, 3)
+	if ct := conn.UpdateWhere(&bAccount{}, cst.KV{"Name": "x", "age": 2}, "age>?", 1); ct != 3 {
+		t.Fatalf("got %d", ct)
+	}
+
+	for _, fn := range []func(){
+		func() { conn.UpdateWhere(&bAccount{}, cst.KV{}, "id=1") },
+		func() { conn.UpdateWhere(&bAccount{}, cst.KV{"age": 1}, " ") },
+		func() { conn.UpdateWhere(&bAccount{}, cst.KV{"nope": 1}, "id=1") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("bad UpdateWhere args should panic")
+				}
+			}()
+			fn()
+		}()
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package sqlx
+
+import (
+	"context"
+	"github.com/qinchende/gofast/skill/gmp"
+	"github.com/qinchende/gofast/skill/jsonx"
+	"github.com/qinchende/gofast/skill/lang"
+	"github.com/qinchende/gofast/skill/mathx"
+	"github.com/qinchende/gofast/skill/syncx"
+	"github.com/qinchende/gofast/store/orm"
+	"reflect"
+	"time"
+)
+
+const (
+	cacheNullVal   = "#null#"         // 行记录不存在时缓存的占位值，防止缓存穿透
+	cacheNullMaxS  = 60 * time.Second // 空值缓存的最长时间
+	cacheRefreshEx = "_ref"           // 提前刷新标记的Key后缀
+)
+
+var (
+	// 缓存失效时，相同Key的并发请求只查一次数据库，防止缓存击穿
+	cacheBarrier = syncx.NewSharedCalls()
+	// 过期时间加上 5% 的随机偏差，防止大量缓存同时过期导致缓存雪崩
+	cacheExpireDev = mathx.NewUnstable(0.05)
+)
+
+// 空值缓存的时间比较短，新插入的记录最多这么久之后可见
+func nullExpire(expire time.Duration) time.Duration {
+	if expire > cacheNullMaxS {
+		return cacheNullMaxS
+	}
+	return expire
+}
+
+func petExpire(pc *PetCache) time.Duration {
+	return cacheExpireDev.AroundDuration(time.Duration(pc.ExpireS) * time.Second)
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 一次查询的结果，用于并发请求之间共享
+type petLoaded struct {
+	ct   int64
+	tt   int64
+	gson string
+}
+
+func gsonToString(gr *gsonResult) string {
+	ret, err := jsonx.Marshal(gr.Gson)
+	panicIfErr(err)
+	return lang.BytesToString(ret)
+}
+
+func loadPetFromGson(pet *SelectPet, str string) (int64, int64) {
+	gr := new(gsonResult)
+	panicIfErr(loadRecordsFromGsonString(pet.Target, str, gr))
+	return gr.Ct, gr.Tt
+}
+
+// 执行查询，sqlCount不为空时先查总数，总数为0就不再查记录了
+func (conn *OrmDB) queryPetRows(sql, sqlCount string, pet *SelectPet, sm *orm.ModelSchema, gr *gsonResult) (int64, int64) {
+	var tt int64
+	if sqlCount != "" {
+		// 此条件下一共多少条
+		sqlRows1 := conn.QuerySql(sqlCount, pet.Args...)
+		defer CloseSqlRows(sqlRows1)
+		scanSqlRowsOne(&tt, sqlRows1, sm, nil)
+
+		if tt <= 0 {
+			return 0, 0
+		}
+		if gr != nil {
+			gr.Tt = tt
+		}
+	}
+
+	sqlRows := conn.QuerySql(sql, pet.Args...)
+	defer CloseSqlRows(sqlRows)
+	return scanSqlRowsSlice(pet.Target, sqlRows, gr), tt
+}
+
+// 查询数据库并设置缓存。没有记录时也缓存空结果，只是过期时间比较短
+func (conn *OrmDB) queryPetAndCache(sql, sqlCount string, pet *SelectPet, sm *orm.ModelSchema, gsonStr bool) *petLoaded {
+	gr := &gsonResult{onlyGson: gsonStr}
+	ct, tt := conn.queryPetRows(sql, sqlCount, pet, sm, gr)
+	ret := &petLoaded{ct: ct, tt: tt, gson: gsonToString(gr)}
+	if gsonStr {
+		pet.Result.Target = ret.gson
+	}
+
+	key := pet.Cache.sqlHash
+	if ct > 0 {
+		_ = conn.cache.SetExpire(key, ret.gson, petExpire(pet.Cache))
+		if pet.Cache.RefreshS > 0 && pet.Cache.RefreshS < pet.Cache.ExpireS {
+			_ = conn.cache.SetExpire(key+cacheRefreshEx, "1", time.Duration(pet.Cache.RefreshS)*time.Second)
+		}
+	} else {
+		_ = conn.cache.SetExpire(key, ret.gson, nullExpire(petExpire(pet.Cache)))
+	}
+	return ret
+}
+
+// 缓存命中但提前刷新标记已经过期，就在后台重新查询并设置缓存，热点数据不会因为过期而集中回源
+func (conn *OrmDB) tryRefreshPet(sql, sqlCount string, pet *SelectPet, sm *orm.ModelSchema) {
+	if pet.Cache.RefreshS == 0 || pet.Cache.RefreshS >= pet.Cache.ExpireS {
+		return
+	}
+	key := pet.Cache.sqlHash + cacheRefreshEx
+	var flag string
+	if err := conn.cache.Get(key, &flag); err == nil && flag == "1" {
+		return
+	}
+
+	// 后台刷新不能用事务连接，也不能受当前请求上下文的影响
+	nConn := &OrmDB{Attrs: conn.Attrs, Ctx: context.Background(), Reader: conn.Reader, Writer: conn.Writer, replicas: conn.replicas, cache: conn.cache}
+	nPet := *pet
+	nPet.Target = reflect.New(reflect.TypeOf(pet.Target).Elem()).Interface()
+	nPet.Result = nil
+	nCache := *pet.Cache
+	nPet.Cache = &nCache
+
+	// 先设置标记再启动刷新，防止后台协程还没运行时其它请求重复刷新
+	_ = conn.cache.SetExpire(key, "1", time.Duration(nCache.RefreshS)*time.Second)
+	gmp.GoSafe(func() {
+		_, _ = cacheBarrier.Do(key, func() (any, error) {
+			nConn.queryPetAndCache(sql, sqlCount, &nPet, sm, false)
+			return nil, nil
+		})
+	})
+}
@@ -0,0 +1,127 @@
+package sqlx
+
+import (
+	"database/sql/driver"
+	"github.com/qinchende/gofast/skill/hash"
+	"github.com/qinchende/gofast/store/cache"
+	"github.com/qinchende/gofast/store/orm"
+	"sync"
+	"testing"
+	"time"
+)
+
+var accountCls = []string{"id", "name", "age", "created_at"}
+
+func newTestMemCache(t *testing.T) *cache.MemCache {
+	mc, err := cache.NewMemCache(&cache.MemCnf{Name: t.Name(), Limit: 100, ExpireS: 3600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mc
+}
+
+func TestCacheSqlKey(t *testing.T) {
+	sm := orm.Schema(&bAccount{})
+	sqlStr := "SELECT * FROM accounts WHERE id=1"
+	if got := sm.CacheSqlKey(sqlStr); got != "Gf#Pet#"+hash.Md5HexString(sqlStr) {
+		t.Fatalf("CacheSqlKey got %q", got)
+	}
+	if sm.CacheSqlKeyDB("db1", sqlStr) == sm.CacheSqlKeyDB("db2", sqlStr) {
+		t.Fatal("CacheSqlKeyDB should differ between databases")
+	}
+}
+
+// 缓存失效时相同SQL的并发请求只查一次数据库
+func TestQueryPetCacheSingleflight(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	conn.SetCache(newTestMemCache(t))
+	release := make(chan struct{})
+	m.expectQuery(`^SELECT \* FROM accounts WHERE age>\?`, 1).
+		withRows(accountCls, []driver.Value{int64(1), "a", int64(2), time.Time{}}, []driver.Value{int64(2), "b", int64(3), time.Time{}}).
+		withHook(func() { <-release })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if pic := recover(); pic != nil {
+					t.Errorf("query panic: %v", pic)
+				}
+			}()
+			var list []*bAccount
+			ct := conn.QueryPet(&SelectPet{Target: &list, Where: "age>?", Args: []any{1}, Cache: &PetCache{ExpireS: 60}})
+			if ct != 2 || len(list) != 2 || list[0].Name != "a" || list[1].Age != 3 {
+				t.Errorf("got %d %+v", ct, list)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+}
+
+// 没有记录时也缓存空结果，防止缓存穿透
+func TestQueryPetCacheNull(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	conn.SetCache(newTestMemCache(t))
+	m.expectQuery(`^SELECT \* FROM accounts WHERE age>\?`, 99).withRows(accountCls)
+
+	for i := 0; i < 3; i++ {
+		var list []*bAccount
+		if ct := conn.QueryPet(&SelectPet{Target: &list, Where: "age>?", Args: []any{99}, Cache: &PetCache{ExpireS: 60}}); ct != 0 || len(list) != 0 {
+			t.Fatalf("round %d got %d %+v", i, ct, list)
+		}
+	}
+	if n := m.pending(); n != 0 {
+		t.Fatalf("%d queries not executed", n)
+	}
+}
+
+// 提前刷新标记过期之后，命中缓存的请求仍然拿到旧数据，同时在后台刷新缓存
+func TestQueryPetCacheRefresh(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	mc := newTestMemCache(t)
+	conn.SetCache(mc)
+	m.expectQuery(`^SELECT \* FROM accounts WHERE id=\?`, 1).withRows(accountCls, []driver.Value{int64(1), "old", int64(1), time.Time{}})
+
+	query := func() (*SelectPet, []*bAccount) {
+		var list []*bAccount
+		pet := &SelectPet{Target: &list, Where: "id=?", Args: []any{1}, Cache: &PetCache{ExpireS: 60, RefreshS: 30}}
+		if ct := conn.QueryPet(pet); ct != 1 {
+			t.Fatalf("got %d", ct)
+		}
+		return pet, list
+	}
+
+	pet, list := query()
+	if list[0].Name != "old" {
+		t.Fatalf("got %+v", list[0])
+	}
+	// 刷新标记还在，直接用缓存
+	if _, list = query(); list[0].Name != "old" || m.pending() != 0 {
+		t.Fatalf("got %+v", list[0])
+	}
+
+	// 模拟刷新标记过期
+	m.expectQuery(`^SELECT \* FROM accounts WHERE id=\?`, 1).withRows(accountCls, []driver.Value{int64(1), "new", int64(1), time.Time{}})
+	_ = mc.Del(pet.Cache.sqlHash + cacheRefreshEx)
+	if _, list = query(); list[0].Name != "old" {
+		t.Fatalf("stale read got %+v", list[0])
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, list = query(); list[0].Name == "new" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("cache not refreshed in background")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if m.pending() != 0 {
+		t.Fatal("refresh query not executed")
+	}
+}
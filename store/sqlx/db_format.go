@@ -15,7 +15,12 @@ func formatArgs(args []any) []any {
 		case time.Time:
 			args[idx] = item.(time.Time).Format(timeFormat)
 		case *time.Time:
-			args[idx] = item.(*time.Time).Format(timeFormat)
+			// 软删除字段这类可为空的时间，nil 对应数据库的 NULL
+			if tm := item.(*time.Time); tm == nil {
+				args[idx] = nil
+			} else {
+				args[idx] = tm.Format(timeFormat)
+			}
 		}
 	}
 	return args
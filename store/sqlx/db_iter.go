@@ -0,0 +1,165 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/qinchende/gofast/store/orm"
+	"reflect"
+)
+
+// 逐行读取的游标，所有记录都解析到同一个 dest 对象中，不会把整个结果集放进内存
+// 用法：it := conn.QueryIter(&user, "status=?", 1); defer it.Close(); for it.Next() { ... }; err := it.Err()
+// 每次 Next 都会覆盖 dest，需要保存记录的话自己拷贝
+type RowsIter struct {
+	ctx   context.Context
+	rows  *sql.Rows
+	addrs []any
+	ct    int64
+	err   error
+}
+
+// dest 必须是结构体指针，查询条件的写法和 QueryRows 一样，但是没有条数限制
+func (conn *OrmDB) QueryIter(dest any, where string, args ...any) *RowsIter {
+	return conn.QueryIter2(dest, "*", where, args...)
+}
+
+func (conn *OrmDB) QueryIter2(dest any, fields string, where string, args ...any) *RowsIter {
+	rVal := reflect.ValueOf(dest)
+	if rVal.Kind() != reflect.Ptr || rVal.Elem().Kind() != reflect.Struct {
+		panic("sqlx: QueryIter args [dest] must be pointer of struct")
+	}
+	sm := orm.Schema(dest)
+	if where == "" {
+		where = "1=1"
+	}
+	sqlStr := fmt.Sprintf("SELECT %s FROM %s WHERE %s;", fields, sm.TableName(), whereNotDeleted(sm, where))
+	ctx := conn.iterCtx()
+	sqlRows := conn.QuerySqlCtx(ctx, sqlStr, args...)
+
+	// 列和字段的对应关系只需要算一次，每行都扫描到相同的字段地址
+	dbColumns, err := sqlRows.Columns()
+	if err != nil {
+		CloseSqlRows(sqlRows)
+		ErrPanic(err)
+	}
+	rve := rVal.Elem()
+	smColumns := sm.ColumnsKV()
+	addrs := make([]any, len(dbColumns))
+	for cIdx, column := range dbColumns {
+		if idx, ok := smColumns[column]; ok {
+			addrs[cIdx] = sm.AddrByIndex(&rve, idx)
+		} else {
+			addrs[cIdx] = new(any) // 这个值会被丢弃
+		}
+	}
+	return &RowsIter{ctx: ctx, rows: sqlRows, addrs: addrs}
+}
+
+// 自己构造的 OrmDB 可能没有设置 Ctx，遍历的时间可能很长，这里不能直接用 nil
+func (conn *OrmDB) iterCtx() context.Context {
+	if conn.Ctx == nil {
+		return context.Background()
+	}
+	return conn.Ctx
+}
+
+// 读取下一行到 dest 中，没有更多记录、出错或者 ctx 被取消时返回 false
+func (it *RowsIter) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.err = it.ctx.Err(); it.err != nil {
+		return false
+	}
+	if !it.rows.Next() {
+		it.err = it.rows.Err()
+		return false
+	}
+	if it.err = it.rows.Scan(it.addrs...); it.err != nil {
+		return false
+	}
+	it.ct++
+	return true
+}
+
+// 迭代过程中的错误，已经分类成 *DBError
+func (it *RowsIter) Err() error {
+	return classifyErr(it.err)
+}
+
+// 已经读取的记录条数
+func (it *RowsIter) Count() int64 {
+	return it.ct
+}
+
+func (it *RowsIter) Close() error {
+	return it.rows.Close()
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 逐行处理查询结果，每读到一行就调用一次 fn，fn 返回 false 停止迭代，返回处理的记录条数
+// dest 是复用的结构体指针，fn 中如果要保存记录需要自己拷贝
+func (conn *OrmDB) QueryEach(dest any, fn func() bool, where string, args ...any) int64 {
+	it := conn.QueryIter(dest, where, args...)
+	defer func() { ErrLog(it.Close()) }()
+
+	for it.Next() {
+		if !fn() {
+			break
+		}
+	}
+	ErrPanic(it.err)
+	return it.ct
+}
+
+// 按主键分批遍历整张表（keyset 分页）：WHERE pk>上一批最后的主键 ORDER BY pk LIMIT size
+// 每批数据解析到 dest 切片中（*[]T 或者 *[]*T）然后调用 fn，fn 返回 false 停止遍历。返回遍历的记录总数
+// 不像 OFFSET 分页那样越往后越慢，适合大表导出。主键需要是可比较大小的单调值，比如自增ID
+func (conn *OrmDB) QueryChunks(dest any, size int, fn func() bool, where string, args ...any) int64 {
+	if size <= 0 {
+		panic("sqlx: QueryChunks args [size] must be positive")
+	}
+	sm, _, _, _, isKV := checkDestType(dest)
+	if isKV {
+		panic("sqlx: QueryChunks not support KV records")
+	}
+	if where == "" {
+		where = "1=1"
+	}
+	pkCol := sm.Columns()[sm.PrimaryIndex()]
+	first := fmt.Sprintf("SELECT * FROM %s WHERE %s ORDER BY %s LIMIT %d;", sm.TableName(),
+		whereNotDeleted(sm, where), pkCol, size)
+	next := fmt.Sprintf("SELECT * FROM %s WHERE %s ORDER BY %s LIMIT %d;", sm.TableName(),
+		whereNotDeleted(sm, "("+where+") AND "+pkCol+">?"), pkCol, size)
+
+	var total int64
+	var lastPk any
+	ctx := conn.iterCtx()
+	records := reflect.ValueOf(dest).Elem()
+	for {
+		ErrPanic(ctx.Err())
+
+		var sqlRows *sql.Rows
+		if lastPk == nil {
+			sqlRows = conn.QuerySqlCtx(ctx, first, args...)
+		} else {
+			sqlRows = conn.QuerySqlCtx(ctx, next, append(args[:len(args):len(args)], lastPk)...)
+		}
+		ct := func() int64 {
+			defer CloseSqlRows(sqlRows)
+			return scanSqlRowsSlice(dest, sqlRows, nil)
+		}()
+		if ct == 0 {
+			break
+		}
+		total += ct
+		if !fn() || ct < int64(size) {
+			break
+		}
+		lastPk = sm.PrimaryValue(records.Index(records.Len() - 1).Interface())
+	}
+	return total
+}
@@ -0,0 +1,156 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+var qUserCls = []string{"id", "name", "status", "deleted_at", "extra"}
+
+func qUserRow(id int64, name string) []driver.Value {
+	return []driver.Value{id, name, int64(1), nil, "x"}
+}
+
+func TestRowsIter(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	m.expectQuery(exactSql("SELECT * FROM users WHERE (status=?) AND deleted_at IS NULL;"), 1).
+		withRows(qUserCls, qUserRow(1, "amy"), qUserRow(2, "bob"), qUserRow(3, "cat"))
+
+	var u qUser
+	it := conn.QueryIter(&u, "status=?", 1)
+	defer it.Close()
+	var names []string
+	for it.Next() {
+		names = append(names, u.Name)
+		if u.ID != it.Count() || u.Status != 1 {
+			t.Fatalf("row %d got %+v", it.Count(), u)
+		}
+	}
+	if it.Err() != nil || it.Count() != 3 || len(names) != 3 || names[2] != "cat" {
+		t.Fatalf("got %v %d %v", it.Err(), it.Count(), names)
+	}
+	if it.Next() {
+		t.Fatal("Next after end should be false")
+	}
+}
+
+// 迭代中途 ctx 被取消，Next 返回 false，Err 能拿到取消的原因
+func TestRowsIterCanceled(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	ctx, cancel := context.WithCancel(context.Background())
+	conn = conn.CloneWithCtx(ctx)
+	m.expectQuery(`^SELECT \* FROM users`).withRows(qUserCls, qUserRow(1, "amy"), qUserRow(2, "bob"))
+
+	var u qUser
+	it := conn.QueryIter(&u, "")
+	defer it.Close()
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+	cancel()
+	if it.Next() || !errors.Is(it.Err(), context.Canceled) || it.Count() != 1 {
+		t.Fatalf("got %v %d", it.Err(), it.Count())
+	}
+	var dbErr *DBError
+	if !errors.As(it.Err(), &dbErr) {
+		t.Fatalf("err not classified: %#v", it.Err())
+	}
+}
+
+func TestQueryEach(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	m.expectQuery(`^SELECT \* FROM users`).withRows(qUserCls, qUserRow(1, "amy"), qUserRow(2, "bob"), qUserRow(3, "cat"))
+
+	// fn 返回 false 提前结束
+	var u qUser
+	var ids []int64
+	ct := conn.QueryEach(&u, func() bool {
+		ids = append(ids, u.ID)
+		return len(ids) < 2
+	}, "")
+	if ct != 2 || len(ids) != 2 || ids[1] != 2 {
+		t.Fatalf("got %d %v", ct, ids)
+	}
+
+	m.expectQuery(`^SELECT \* FROM users`).withErr(errors.New("connection reset"))
+	if _, err := conn.QueryEachE(&u, func() bool { return true }, ""); err == nil {
+		t.Fatal("query error should be returned")
+	}
+}
+
+// 按主键分批：后一批从上一批最后的主键之后开始，最后一批不满说明已经没有数据了
+func TestQueryChunks(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	first := exactSql("SELECT * FROM users WHERE (status=?) AND deleted_at IS NULL ORDER BY id LIMIT 2;")
+	next := exactSql("SELECT * FROM users WHERE ((status=?) AND id>?) AND deleted_at IS NULL ORDER BY id LIMIT 2;")
+	m.expectQuery(first, 1).withRows(qUserCls, qUserRow(1, "a"), qUserRow(2, "b"))
+	m.expectQuery(next, 1, 2).withRows(qUserCls, qUserRow(3, "c"), qUserRow(5, "d"))
+	m.expectQuery(next, 1, 5).withRows(qUserCls, qUserRow(8, "e"))
+
+	var list []*qUser
+	var chunks [][]string
+	ct := conn.QueryChunks(&list, 2, func() bool {
+		var names []string
+		for _, u := range list {
+			names = append(names, u.Name)
+		}
+		chunks = append(chunks, names)
+		return true
+	}, "status=?", 1)
+	if ct != 5 || len(chunks) != 3 || len(chunks[1]) != 2 || chunks[1][1] != "d" || chunks[2][0] != "e" {
+		t.Fatalf("got %d %v", ct, chunks)
+	}
+
+	// 刚好整批结束时，多查一次空结果；fn 返回 false 不再继续
+	m.expectQuery(first, 1).withRows(qUserCls, qUserRow(1, "a"), qUserRow(2, "b"))
+	m.expectQuery(next, 1, 2).withRows(qUserCls)
+	if ct = conn.QueryChunks(&list, 2, func() bool { return true }, "status=?", 1); ct != 2 {
+		t.Fatalf("got %d", ct)
+	}
+	m.expectQuery(first, 1).withRows(qUserCls, qUserRow(1, "a"), qUserRow(2, "b"))
+	if ct = conn.QueryChunks(&list, 2, func() bool { return false }, "status=?", 1); ct != 2 {
+		t.Fatalf("got %d", ct)
+	}
+}
+
+func TestQueryChunksCanceled(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	ctx, cancel := context.WithCancel(context.Background())
+	conn = conn.CloneWithCtx(ctx)
+	m.expectQuery(`^SELECT \* FROM users`).withRows(qUserCls, qUserRow(1, "a"), qUserRow(2, "b"))
+
+	var list []qUser
+	batches := 0
+	ct, err := conn.QueryChunksE(&list, 2, func() bool {
+		batches++
+		cancel()
+		return true
+	}, "")
+	if !errors.Is(err, context.Canceled) || batches != 1 || ct != 0 {
+		t.Fatalf("got %d %v after %d batches", ct, err, batches)
+	}
+}
+
+// 没有设置 Ctx 的连接也能遍历
+func TestIterNilCtx(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	conn.Ctx = nil
+	m.expectQuery(`^SELECT \* FROM users`).withRows(qUserCls, qUserRow(1, "a"))
+	m.expectQuery(`^SELECT \* FROM users`).withRows(qUserCls, qUserRow(1, "a"))
+
+	var u qUser
+	it := conn.QueryIter(&u, "")
+	for it.Next() {
+	}
+	_ = it.Close()
+	if it.Err() != nil || it.Count() != 1 {
+		t.Fatalf("iter got %v %d", it.Err(), it.Count())
+	}
+
+	var list []qUser
+	if ct := conn.QueryChunks(&list, 2, func() bool { return true }, ""); ct != 1 || list[0].Name != "a" {
+		t.Fatalf("chunks got %d %+v", ct, list)
+	}
+}
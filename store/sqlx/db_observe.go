@@ -0,0 +1,260 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"github.com/qinchende/gofast/logx"
+	"github.com/qinchende/gofast/skill/collect"
+	"github.com/qinchende/gofast/skill/gmp"
+	"github.com/qinchende/gofast/skill/metric"
+	"github.com/qinchende/gofast/skill/timex"
+	"github.com/qinchende/gofast/skill/trace"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sqlNamespace      = "sql_client"
+	poolStatsInterval = 10 * time.Second // 连接池状态的刷新周期
+)
+
+var (
+	metricSqlDur = metric.NewHistogramVec(&metric.HistogramVecOpts{
+		Namespace: sqlNamespace,
+		Subsystem: "requests",
+		Name:      "duration_ms",
+		Help:      "sql client requests duration(ms).",
+		Labels:    []string{"kind", "table", "sql_hash"},
+		Buckets:   []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500},
+	})
+
+	metricSqlErrTotal = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: sqlNamespace,
+		Subsystem: "requests",
+		Name:      "error_total",
+		Help:      "sql client requests error count.",
+		Labels:    []string{"kind", "table"},
+	})
+
+	metricSqlPool = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: sqlNamespace,
+		Subsystem: "pool",
+		Name:      "stats",
+		Help:      "sql client connection pool stats.",
+		Labels:    []string{"db", "role", "stat"},
+	})
+
+	// 原始SQL -> 归一化之后的信息，限制条数，防止拼接参数的SQL撑爆内存
+	sqlInfos, _ = collect.NewCache(time.Hour, collect.WithLimit(10000), collect.WithName("sqlx-stmt"))
+
+	// IN (?,?,?) 和批量插入的多组 VALUES 长度不固定，合并成一个，否则 sql_hash 标签的取值没有上限
+	sqlArgsList  = regexp.MustCompile(`\?(?:\s*,\s*\?)+`)
+	sqlTupleList = regexp.MustCompile(`\(\?\)(?:\s*,\s*\(\?\))+`)
+)
+
+// 归一化的SQL：字符串和数字常量替换成 ?，多余的空白合并。同一类SQL得到相同的 hash
+type sqlInfo struct {
+	norm  string
+	hash  string
+	table string
+	op    string
+}
+
+// 慢日志阈值，没有设置时用默认值
+func (attrs *DBAttrs) slowThreshold() time.Duration {
+	if attrs == nil || attrs.SlowThreshold <= 0 {
+		return slowThreshold
+	}
+	return attrs.SlowThreshold
+}
+
+func (conn *OrmDB) SetSlowThreshold(dur time.Duration) {
+	conn.Attrs.SlowThreshold = dur
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 每条SQL一个客户端 span，记录的是归一化之后的SQL，不包含参数值
+func startSqlSpan(ctx context.Context, attrs *DBAttrs, sqlStr string) (context.Context, oteltrace.Span) {
+	info := sqlInfoOf(sqlStr)
+	kvs := []attribute.KeyValue{
+		semconv.DBStatementKey.String(info.norm),
+		semconv.DBOperationKey.String(info.op),
+		semconv.DBSQLTableKey.String(info.table),
+	}
+	if attrs != nil {
+		kvs = append(kvs, semconv.DBSystemKey.String(attrs.DriverName), semconv.DBNameKey.String(attrs.DbName))
+	}
+	tracer := otel.GetTracerProvider().Tracer(trace.TraceName)
+	return tracer.Start(ctx, "sql."+strings.ToLower(info.op), oteltrace.WithSpanKind(oteltrace.SpanKindClient), oteltrace.WithAttributes(kvs...))
+}
+
+// SQL执行结束：结束 span，统计耗时和错误，超过阈值打印慢日志
+func endSqlSpan(span oteltrace.Span, attrs *DBAttrs, kind, sqlStr string, args []any, startTime time.Duration, ret sql.Result, err error) {
+	dur := timex.NowDiff(startTime)
+	info := sqlInfoOf(sqlStr)
+
+	if ret != nil && err == nil {
+		if ct, e := ret.RowsAffected(); e == nil {
+			span.SetAttributes(attribute.Int64("db.rows_affected", ct))
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otcodes.Error, err.Error())
+		metricSqlErrTotal.Inc(kind, info.table)
+	}
+	span.End()
+
+	metricSqlDur.Observe(int64(dur/time.Millisecond), kind, info.table, info.hash)
+	if dur > attrs.slowThreshold() {
+		logx.SlowF("[SQL][%dms] %s: slow-call - %s", dur/time.Millisecond, kind, realSql(sqlStr, args...))
+	}
+}
+
+func sqlInfoOf(sqlStr string) *sqlInfo {
+	if val, ok := sqlInfos.Get(sqlStr); ok {
+		return val.(*sqlInfo)
+	}
+	norm := normalizeSql(sqlStr)
+	info := &sqlInfo{norm: norm, hash: sqlHash(norm)[:12], table: sqlTable(norm), op: sqlOperation(norm)}
+	sqlInfos.Set(sqlStr, info)
+	if logx.ShowDebug() {
+		logx.DebugF("[SQL] hash %s - %s", info.hash, norm)
+	}
+	return info
+}
+
+// 把SQL中的字符串和数字常量替换成 ?，连续的空白合并成一个空格，? 的列表合并成一个 ?
+func normalizeSql(sqlStr string) string {
+	var sb strings.Builder
+	sb.Grow(len(sqlStr))
+	space := false
+	for i := 0; i < len(sqlStr); i++ {
+		c := sqlStr[i]
+		switch {
+		case c == '\'' || c == '"':
+			// 字符串常量，跳到配对的引号（两个连续的引号是转义）
+			j := i + 1
+			for ; j < len(sqlStr); j++ {
+				if sqlStr[j] == c {
+					if j+1 < len(sqlStr) && sqlStr[j+1] == c {
+						j++
+						continue
+					}
+					break
+				}
+			}
+			sb.WriteByte('?')
+			i = j
+		case c >= '0' && c <= '9' && (i == 0 || !isWordByte(sqlStr[i-1])):
+			for i+1 < len(sqlStr) && (isWordByte(sqlStr[i+1]) || sqlStr[i+1] == '.') {
+				i++
+			}
+			sb.WriteByte('?')
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if !space && sb.Len() > 0 {
+				sb.WriteByte(' ')
+			}
+			space = true
+			continue
+		default:
+			sb.WriteByte(c)
+		}
+		space = false
+	}
+	norm := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(sb.String()), ";"))
+	norm = sqlArgsList.ReplaceAllLiteralString(norm, "?")
+	return sqlTupleList.ReplaceAllLiteralString(norm, "(?)")
+}
+
+// SQL的第一个关键字，比如 SELECT、INSERT
+func sqlOperation(norm string) string {
+	if idx := strings.IndexByte(norm, ' '); idx > 0 {
+		return strings.ToUpper(norm[:idx])
+	}
+	return strings.ToUpper(norm)
+}
+
+// 从 FROM、INTO、UPDATE 后面取出第一个表名，取不到返回空
+func sqlTable(norm string) string {
+	words := strings.Fields(norm)
+	for i := 0; i < len(words)-1; i++ {
+		switch strings.ToUpper(words[i]) {
+		case "FROM", "INTO", "UPDATE", "TABLE":
+			// INSERT INTO t(a,b) 表名后面直接跟着字段列表
+			name := words[i+1]
+			if idx := strings.IndexByte(name, '('); idx > 0 {
+				name = name[:idx]
+			}
+			name = strings.Trim(name, "`\"[]();,")
+			if name != "" && name != "?" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 连接池状态（sql.DBStats）定时导出成 Prometheus gauge，标签 db 是调用时传入的名称，role 是 writer/reader/从库名
+var (
+	poolStats     = make(map[string]*OrmDB)
+	poolStatsLock sync.Mutex
+	poolStatsOnce sync.Once
+)
+
+func (conn *OrmDB) StatPool(name string) {
+	poolStatsLock.Lock()
+	poolStats[name] = conn
+	poolStatsLock.Unlock()
+
+	poolStatsOnce.Do(func() {
+		gmp.GoSafe(func() {
+			ticker := time.NewTicker(poolStatsInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				refreshPoolStats()
+			}
+		})
+	})
+}
+
+func refreshPoolStats() {
+	poolStatsLock.Lock()
+	defer poolStatsLock.Unlock()
+
+	for name, conn := range poolStats {
+		setPoolStats(name, "writer", conn.Writer)
+		if conn.replicas != nil {
+			for _, nd := range conn.replicas.nodes {
+				setPoolStats(name, nd.Name, nd.DB)
+			}
+		} else if conn.Reader != nil && conn.Reader != conn.Writer {
+			setPoolStats(name, "reader", conn.Reader)
+		}
+	}
+}
+
+func setPoolStats(name, role string, db *sql.DB) {
+	if db == nil {
+		return
+	}
+	st := db.Stats()
+	metricSqlPool.Set(float64(st.MaxOpenConnections), name, role, "max_open")
+	metricSqlPool.Set(float64(st.OpenConnections), name, role, "open")
+	metricSqlPool.Set(float64(st.InUse), name, role, "in_use")
+	metricSqlPool.Set(float64(st.Idle), name, role, "idle")
+	metricSqlPool.Set(float64(st.WaitCount), name, role, "wait_count")
+	metricSqlPool.Set(float64(st.WaitDuration/time.Millisecond), name, role, "wait_ms")
+	metricSqlPool.Set(float64(st.MaxIdleClosed), name, role, "max_idle_closed")
+	metricSqlPool.Set(float64(st.MaxLifetimeClosed), name, role, "max_lifetime_closed")
+}
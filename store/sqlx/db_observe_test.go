@@ -0,0 +1,58 @@
+package sqlx
+
+import (
+	"testing"
+)
+
+func TestNormalizeSql(t *testing.T) {
+	cases := []struct {
+		sql, want string
+	}{
+		{"SELECT * FROM users WHERE id=?", "SELECT * FROM users WHERE id=?"},
+		{"  SELECT\n\t*  FROM users\r\n WHERE id=1 ;", "SELECT * FROM users WHERE id=?"},
+		// IN 列表不管多长都合并成一个 ?
+		{"SELECT * FROM users WHERE id IN (1, 2, 3)", "SELECT * FROM users WHERE id IN (?)"},
+		{"SELECT * FROM users WHERE id IN (?,?,?,?) AND s=?", "SELECT * FROM users WHERE id IN (?) AND s=?"},
+		{"SELECT * FROM users WHERE name IN ('a','b')", "SELECT * FROM users WHERE name IN (?)"},
+		// 多行 VALUES 合并成一行
+		{"INSERT INTO t (a,b) VALUES (1,'x'),(2,'y'),(3,'z');", "INSERT INTO t (a,b) VALUES (?)"},
+		{"INSERT INTO t (a,b) VALUES (?,?), (?,?)", "INSERT INTO t (a,b) VALUES (?)"},
+		// 字符串常量，两个连续的引号是转义
+		{"SELECT * FROM t WHERE name='it''s' AND memo=''", "SELECT * FROM t WHERE name=? AND memo=?"},
+		{`SELECT * FROM t WHERE a="say ""hi""" AND b='x y  z'`, "SELECT * FROM t WHERE a=? AND b=?"},
+		{"SELECT * FROM t WHERE a='1, 2'", "SELECT * FROM t WHERE a=?"},
+		// 标识符中的数字不替换
+		{"SELECT col1, t2.v3 FROM table2 WHERE id=10 AND x=1.5", "SELECT col1, t2.v3 FROM table2 WHERE id=? AND x=?"},
+		{"SELECT * FROM log_2023 WHERE a1b=0x1F LIMIT 10", "SELECT * FROM log_2023 WHERE a1b=? LIMIT ?"},
+	}
+	for _, c := range cases {
+		if got := normalizeSql(c.sql); got != c.want {
+			t.Errorf("normalizeSql(%q)\n got %q\nwant %q", c.sql, got, c.want)
+		}
+	}
+}
+
+func TestSqlTable(t *testing.T) {
+	cases := []struct {
+		sql, op, table string
+	}{
+		{"SELECT * FROM users WHERE id=?", "SELECT", "users"},
+		{"select * from `users` where id=?", "SELECT", "users"},
+		{"INSERT INTO orders(a,b) VALUES (?)", "INSERT", "orders"},
+		{"UPDATE items SET a=? WHERE id=?", "UPDATE", "items"},
+		{"INSERT INTO `orders`(a) VALUES (?)", "INSERT", "orders"},
+		{"DELETE FROM log_2023 WHERE id=?", "DELETE", "log_2023"},
+		{"TRUNCATE TABLE t1", "TRUNCATE", "t1"},
+		{"SELECT ?", "SELECT", ""},
+		{"BEGIN", "BEGIN", ""},
+	}
+	for _, c := range cases {
+		norm := normalizeSql(c.sql)
+		if got := sqlOperation(norm); got != c.op {
+			t.Errorf("sqlOperation(%q) got %q, want %q", c.sql, got, c.op)
+		}
+		if got := sqlTable(norm); got != c.table {
+			t.Errorf("sqlTable(%q) got %q, want %q", c.sql, got, c.table)
+		}
+	}
+}
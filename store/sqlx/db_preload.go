@@ -0,0 +1,200 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package sqlx
+
+import (
+	"fmt"
+	"github.com/qinchende/gofast/store/orm"
+	"reflect"
+	"strings"
+)
+
+// 返回带预加载的连接，之后的 QueryPrimary、QueryRow、QueryRows、QueryPet 等查询会同时加载这些关联
+// 多级关联用点号分隔，比如：conn.Preload("Items", "Items.Product").QueryRows(&orders, "user_id=?", uid)
+// 每个关联只会发起一次 IN (...) 查询，不会有 N+1 的问题
+func (conn *OrmDB) Preload(names ...string) *OrmDB {
+	nConn := *conn
+	nConn.preloads = append(append(make([]string, 0, len(conn.preloads)+len(names)), conn.preloads...), names...)
+	return &nConn
+}
+
+// 给已经查询出来的记录加载关联数据，dest 可以是 *T、*[]T 或者 *[]*T
+func (conn *OrmDB) LoadRelations(dest any, names ...string) {
+	records := collectRecords(dest)
+	if len(records) == 0 || len(names) == 0 {
+		return
+	}
+	sm := orm.SchemaOfType(reflect.PtrTo(records[0].Type()))
+
+	// 按第一级关联分组，剩下的路径交给下一级递归处理
+	order := make([]string, 0, len(names))
+	subs := make(map[string][]string, len(names))
+	for _, name := range names {
+		parts := strings.SplitN(name, ".", 2)
+		if _, ok := subs[parts[0]]; !ok {
+			order = append(order, parts[0])
+			subs[parts[0]] = nil
+		}
+		if len(parts) == 2 {
+			subs[parts[0]] = append(subs[parts[0]], parts[1])
+		}
+	}
+	for _, name := range order {
+		rel := sm.Relation(name)
+		if rel == nil {
+			panic(fmt.Errorf("sqlx: model %s has no relation %s", sm.TableName(), name))
+		}
+		conn.loadRelation(sm, records, rel, subs[name])
+	}
+}
+
+// 有预加载设置时，查询之后加载关联数据
+func (conn *OrmDB) loadPreloads(dest any, ct int64) {
+	if ct > 0 && len(conn.preloads) > 0 {
+		conn.LoadRelations(dest, conn.preloads...)
+	}
+}
+
+func (conn *OrmDB) loadRelation(sm *orm.ModelSchema, records []reflect.Value, rel *orm.Relation, subs []string) {
+	rsm := orm.SchemaOfType(reflect.PtrTo(rel.ElemType))
+
+	// 本表中用来匹配的列，关联表中用来匹配的列
+	var ownCol, relCol string
+	if rel.Kind == orm.RelBelongsTo {
+		ownCol, relCol = rel.FK, rel.Ref
+		if relCol == "" {
+			relCol = rsm.Columns()[rsm.PrimaryIndex()]
+		}
+	} else {
+		ownCol, relCol = rel.Ref, rel.FK
+		if ownCol == "" {
+			ownCol = sm.Columns()[sm.PrimaryIndex()]
+		}
+	}
+	ownIdx, ok := sm.ColumnsKV()[ownCol]
+	if !ok {
+		panic(fmt.Errorf("sqlx: relation %s column %s not in %s", rel.Name, ownCol, sm.TableName()))
+	}
+	relIdx, ok := rsm.ColumnsKV()[relCol]
+	if !ok {
+		panic(fmt.Errorf("sqlx: relation %s column %s not in %s", rel.Name, relCol, rsm.TableName()))
+	}
+
+	// 1. 收集去重之后的关联键值
+	keys := make([]any, 0, len(records))
+	seen := make(map[string]bool, len(records))
+	for i := range records {
+		val := relationKey(sm.ValueByIndex(&records[i], ownIdx))
+		if val == nil || reflect.ValueOf(val).IsZero() {
+			continue
+		}
+		if ks := fmt.Sprint(val); !seen[ks] {
+			seen[ks] = true
+			keys = append(keys, val)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	// 2. 分批查询关联记录
+	children := reflect.New(reflect.SliceOf(reflect.PtrTo(rel.ElemType)))
+	all := children.Elem()
+	step := conn.Dialect().MaxParams()
+	for start := 0; start < len(keys); start += step {
+		end := start + step
+		if end > len(keys) {
+			end = len(keys)
+		}
+		where := whereNotDeleted(rsm, fmt.Sprintf("%s IN (%s)", relCol, strings.TrimSuffix(strings.Repeat("?,", end-start), ",")))
+		sqlStr := fmt.Sprintf("SELECT * FROM %s WHERE %s ORDER BY %s;", rsm.TableName(), where, rsm.Columns()[rsm.PrimaryIndex()])
+		part := reflect.New(all.Type())
+		conn.scanRows(part.Interface(), sqlStr, keys[start:end]...)
+		all = reflect.AppendSlice(all, part.Elem())
+	}
+	children.Elem().Set(all)
+	if all.Len() == 0 {
+		return
+	}
+	if len(subs) > 0 {
+		conn.LoadRelations(children.Interface(), subs...)
+	}
+
+	// 3. 按键值分组之后回填到每条记录
+	groups := make(map[string][]reflect.Value, len(keys))
+	for i := 0; i < all.Len(); i++ {
+		child := all.Index(i)
+		childVal := child.Elem()
+		ks := fmt.Sprint(relationKey(rsm.ValueByIndex(&childVal, relIdx)))
+		groups[ks] = append(groups[ks], child)
+	}
+	for i := range records {
+		matched := groups[fmt.Sprint(relationKey(sm.ValueByIndex(&records[i], ownIdx)))]
+		if len(matched) == 0 {
+			continue
+		}
+
+		field := records[i].FieldByIndex(rel.Index)
+		if rel.Kind == orm.RelHasMany {
+			items := reflect.MakeSlice(field.Type(), 0, len(matched))
+			for _, child := range matched {
+				if rel.IsPtr {
+					items = reflect.Append(items, child)
+				} else {
+					items = reflect.Append(items, child.Elem())
+				}
+			}
+			field.Set(items)
+		} else if rel.IsPtr {
+			field.Set(matched[0])
+		} else {
+			field.Set(matched[0].Elem())
+		}
+	}
+}
+
+// 可以为空的外键字段是指针类型，取出指向的值
+func relationKey(val any) any {
+	rVal := reflect.ValueOf(val)
+	if rVal.Kind() != reflect.Ptr {
+		return val
+	}
+	if rVal.IsNil() {
+		return nil
+	}
+	return rVal.Elem().Interface()
+}
+
+func (conn *OrmDB) scanRows(dest any, sqlStr string, args ...any) int64 {
+	sqlRows := conn.QuerySql(sqlStr, args...)
+	defer CloseSqlRows(sqlRows)
+	return scanSqlRowsSlice(dest, sqlRows, nil)
+}
+
+// 取出所有记录的结构体值（可寻址）
+func collectRecords(dest any) []reflect.Value {
+	rVal := reflect.ValueOf(dest)
+	if rVal.Kind() != reflect.Ptr {
+		panic("sqlx: dest must be pointer.")
+	}
+	rVal = rVal.Elem()
+
+	if rVal.Kind() == reflect.Struct {
+		return []reflect.Value{rVal}
+	}
+	if rVal.Kind() != reflect.Slice {
+		panic("sqlx: dest must be struct or slice.")
+	}
+	records := make([]reflect.Value, 0, rVal.Len())
+	for i := 0; i < rVal.Len(); i++ {
+		item := rVal.Index(i)
+		if item.Kind() == reflect.Ptr {
+			if item.IsNil() {
+				continue
+			}
+			item = item.Elem()
+		}
+		records = append(records, item)
+	}
+	return records
+}
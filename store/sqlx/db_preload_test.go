@@ -0,0 +1,169 @@
+package sqlx
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"github.com/qinchende/gofast/store/orm"
+	"regexp"
+	"testing"
+)
+
+type pUser struct {
+	ID      int64 `dbc:"primary_field"`
+	Name    string
+	Orders  []*pOrder `rel:"has_many,fk=user_id"`
+	Profile *pProfile `rel:"has_one,fk=user_id"`
+}
+
+func (u *pUser) GfAttrs(orm.OrmStruct) *orm.ModelAttrs { return &orm.ModelAttrs{TableName: "users"} }
+func (u *pUser) BeforeSave()                           {}
+func (u *pUser) AfterInsert(sql.Result)                {}
+
+type pOrder struct {
+	ID     int64 `dbc:"primary_field"`
+	UserID int64
+	Amount float64
+	User   *pUser  `rel:"belongs_to,fk=user_id"`
+	Items  []pItem `rel:"has_many,fk=order_id"`
+}
+
+func (o *pOrder) GfAttrs(orm.OrmStruct) *orm.ModelAttrs { return &orm.ModelAttrs{TableName: "orders"} }
+func (o *pOrder) BeforeSave()                           {}
+func (o *pOrder) AfterInsert(sql.Result)                {}
+
+type pItem struct {
+	ID      int64 `dbc:"primary_field"`
+	OrderID int64
+	Title   string
+}
+
+func (i *pItem) GfAttrs(orm.OrmStruct) *orm.ModelAttrs { return &orm.ModelAttrs{TableName: "items"} }
+func (i *pItem) BeforeSave()                           {}
+func (i *pItem) AfterInsert(sql.Result)                {}
+
+type pProfile struct {
+	ID     int64 `dbc:"primary_field"`
+	UserID int64
+	Bio    string
+}
+
+func (p *pProfile) GfAttrs(orm.OrmStruct) *orm.ModelAttrs {
+	return &orm.ModelAttrs{TableName: "profiles"}
+}
+func (p *pProfile) BeforeSave()            {}
+func (p *pProfile) AfterInsert(sql.Result) {}
+
+var (
+	pUserCls    = []string{"id", "name"}
+	pOrderCls   = []string{"id", "user_id", "amount"}
+	pItemCls    = []string{"id", "order_id", "title"}
+	pProfileCls = []string{"id", "user_id", "bio"}
+)
+
+func exactSql(sqlStr string) string {
+	return "^" + regexp.QuoteMeta(sqlStr) + "$"
+}
+
+// 每个关联只查一次，多级关联用上一级的结果继续查
+func TestPreloadHasMany(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	m.expectQuery(`^SELECT \* FROM users WHERE`, 0).withRows(pUserCls,
+		[]driver.Value{int64(1), "amy"}, []driver.Value{int64(2), "bob"}, []driver.Value{int64(3), "cat"})
+	m.expectQuery(exactSql("SELECT * FROM orders WHERE user_id IN (?,?,?) ORDER BY id;"), 1, 2, 3).withRows(pOrderCls,
+		[]driver.Value{int64(10), int64(1), 1.5}, []driver.Value{int64(11), int64(1), 2.5}, []driver.Value{int64(12), int64(2), 3.5})
+	m.expectQuery(exactSql("SELECT * FROM items WHERE order_id IN (?,?,?) ORDER BY id;"), 10, 11, 12).withRows(pItemCls,
+		[]driver.Value{int64(100), int64(10), "pen"}, []driver.Value{int64(101), int64(12), "ink"}, []driver.Value{int64(102), int64(12), "cap"})
+
+	var users []*pUser
+	if ct := conn.Preload("Orders", "Orders.Items").QueryRows(&users, "id>?", 0); ct != 3 {
+		t.Fatalf("got %d", ct)
+	}
+	amy, bob, cat := users[0], users[1], users[2]
+	if len(amy.Orders) != 2 || amy.Orders[0].ID != 10 || amy.Orders[1].ID != 11 {
+		t.Fatalf("amy orders %+v", amy.Orders)
+	}
+	if len(amy.Orders[0].Items) != 1 || amy.Orders[0].Items[0].Title != "pen" || amy.Orders[1].Items != nil {
+		t.Fatalf("amy items %+v %+v", amy.Orders[0].Items, amy.Orders[1].Items)
+	}
+	if len(bob.Orders) != 1 || len(bob.Orders[0].Items) != 2 || bob.Orders[0].Items[1].Title != "cap" {
+		t.Fatalf("bob orders %+v", bob.Orders)
+	}
+	if cat.Orders != nil {
+		t.Fatalf("cat orders %+v", cat.Orders)
+	}
+}
+
+// 外键重复的只查一次，零值外键跳过，同一条关联记录回填到多条记录上
+func TestPreloadBelongsTo(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	m.expectQuery(exactSql("SELECT * FROM users WHERE id IN (?,?) ORDER BY id;"), 1, 2).withRows(pUserCls,
+		[]driver.Value{int64(1), "amy"}, []driver.Value{int64(2), "bob"})
+
+	orders := []pOrder{{ID: 10, UserID: 1}, {ID: 11, UserID: 2}, {ID: 12, UserID: 1}, {ID: 13}}
+	conn.LoadRelations(&orders, "User")
+	if orders[0].User == nil || orders[0].User.Name != "amy" || orders[1].User.Name != "bob" {
+		t.Fatalf("got %+v %+v", orders[0].User, orders[1].User)
+	}
+	if orders[2].User != orders[0].User || orders[3].User != nil {
+		t.Fatalf("got %+v %+v", orders[2].User, orders[3].User)
+	}
+
+	// 没有关联键值时不查询
+	none := []pOrder{{ID: 13}}
+	conn.LoadRelations(&none, "User")
+}
+
+func TestPreloadHasOne(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	m.expectQuery(`^SELECT \* FROM users WHERE`, 1).withRows(pUserCls, []driver.Value{int64(1), "amy"})
+	m.expectQuery(exactSql("SELECT * FROM profiles WHERE user_id IN (?) ORDER BY id;"), 1).withRows(pProfileCls,
+		[]driver.Value{int64(5), int64(1), "hello"})
+
+	var u pUser
+	if ct := conn.Preload("Profile").QueryPrimary(&u, 1); ct != 1 {
+		t.Fatalf("got %d", ct)
+	}
+	if u.Profile == nil || u.Profile.Bio != "hello" || u.Orders != nil {
+		t.Fatalf("got %+v", u)
+	}
+
+	// 查不到记录时不加载关联
+	m.expectQuery(`^SELECT \* FROM users WHERE`, 9).withRows(pUserCls)
+	var none pUser
+	if ct := conn.Preload("Profile").QueryPrimary(&none, 9); ct != 0 || none.Profile != nil {
+		t.Fatalf("got %d %+v", ct, none)
+	}
+}
+
+// 关联键值超过方言的参数上限时分批查询，结果合并之后回填
+func TestPreloadBatched(t *testing.T) {
+	m, conn := newMockDB(t, "tiny")
+	users := make([]pUser, 7)
+	for i := range users {
+		users[i].ID = int64(i + 1)
+	}
+	m.expectQuery(exactSql("SELECT * FROM orders WHERE user_id IN (?,?,?,?,?) ORDER BY id;"), 1, 2, 3, 4, 5).withRows(pOrderCls,
+		[]driver.Value{int64(10), int64(1), 1.0}, []driver.Value{int64(11), int64(5), 1.0})
+	m.expectQuery(exactSql("SELECT * FROM orders WHERE user_id IN (?,?) ORDER BY id;"), 6, 7).withRows(pOrderCls,
+		[]driver.Value{int64(12), int64(7), 1.0})
+
+	conn.LoadRelations(&users, "Orders")
+	for i, want := range map[int]int64{0: 10, 4: 11, 6: 12} {
+		if len(users[i].Orders) != 1 || users[i].Orders[0].ID != want {
+			t.Fatalf("user %d orders %+v", users[i].ID, users[i].Orders)
+		}
+	}
+	if users[1].Orders != nil {
+		t.Fatalf("user 2 orders %+v", users[1].Orders)
+	}
+}
+
+func TestPreloadUnknownRelation(t *testing.T) {
+	_, conn := newMockDB(t, DriverMysql)
+	defer func() {
+		if pic := recover(); pic == nil {
+			t.Fatal("unknown relation should panic")
+		}
+	}()
+	conn.LoadRelations(&[]pUser{{ID: 1}}, "Nope")
+}
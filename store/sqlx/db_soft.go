@@ -0,0 +1,94 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package sqlx
+
+import (
+	"fmt"
+	"github.com/qinchende/gofast/store/orm"
+	"reflect"
+	"strings"
+)
+
+// 物理删除记录，不管 Model 有没有软删除字段
+func (conn *OrmDB) ForceDelete(obj any) int64 {
+	sm := orm.Schema(obj)
+	val := sm.PrimaryValue(obj)
+	ret := conn.ExecSql(forceDeleteSql(sm), val)
+	return parseSqlResult(ret, val, conn, sm)
+}
+
+// 有版本号字段时，更新不到记录说明版本号已经变了，抛出 ErrVersionConflict
+// 更新成功之后把新的版本号写回对象
+func (conn *OrmDB) checkVersion(sm *orm.ModelSchema, rVal *reflect.Value, ct int64, pk, nextVer any) {
+	if sm.VersionIndex() < 0 {
+		return
+	}
+	if ct == 0 {
+		panic(&DBError{Kind: ErrVersionConflict, Err: fmt.Errorf("table %s id=%v", sm.TableName(), pk)})
+	}
+	rVal.FieldByIndex(sm.FieldIndex(sm.VersionIndex())).Set(reflect.ValueOf(nextVer))
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 当前版本号和加1之后的版本号，类型和字段保持一致
+func versionValues(ms *orm.ModelSchema, rVal *reflect.Value) (any, any) {
+	cur := rVal.FieldByIndex(ms.FieldIndex(ms.VersionIndex()))
+	next := reflect.New(cur.Type()).Elem()
+	if cur.CanInt() {
+		next.SetInt(cur.Int() + 1)
+	} else {
+		next.SetUint(cur.Uint() + 1)
+	}
+	return cur.Interface(), next.Interface()
+}
+
+// 在自定义的查询条件上加上未删除的过滤，where 后面可能跟着 GROUP BY、ORDER BY、LIMIT 等子句
+func whereNotDeleted(ms *orm.ModelSchema, where string) string {
+	if !ms.SoftDelete() {
+		return where
+	}
+	pos := tailClauseIndex(where)
+	cond, tail := strings.TrimSpace(where[:pos]), where[pos:]
+	if tail != "" {
+		tail = " " + tail
+	}
+	if cond == "" {
+		return ms.NotDeletedCond() + tail
+	}
+	return "(" + cond + ") AND " + ms.NotDeletedCond() + tail
+}
+
+var tailClauses = []string{"group by", "order by", "having", "limit", "for update"}
+
+// 找到第一个不在括号和引号中的尾部子句的位置，没有就返回字符串长度
+func tailClauseIndex(where string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(where); i++ {
+		c := where[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && (i == 0 || where[i-1] == ' ' || where[i-1] == '\t' || where[i-1] == '\n'):
+			for _, kw := range tailClauses {
+				end := i + len(kw)
+				if end <= len(where) && strings.EqualFold(where[i:end], kw) && (end == len(where) || !isWordByte(where[end])) {
+					return i
+				}
+			}
+		}
+	}
+	return len(where)
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
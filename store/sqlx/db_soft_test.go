@@ -0,0 +1,108 @@
+package sqlx
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/qinchende/gofast/store/orm"
+	"testing"
+)
+
+func TestTailClauseIndex(t *testing.T) {
+	cases := []struct {
+		where string
+		tail  string
+	}{
+		{"id=1", ""},
+		{"", ""},
+		{"id=1 ORDER BY id", "ORDER BY id"},
+		{"id=1 order by id desc limit 10", "order by id desc limit 10"},
+		{"status=1 GROUP BY user_id HAVING COUNT(*)>1", "GROUP BY user_id HAVING COUNT(*)>1"},
+		{"id=? FOR UPDATE", "FOR UPDATE"},
+		{"LIMIT 5", "LIMIT 5"},
+		{"id=1\nORDER BY id", "ORDER BY id"},
+		{"id=1\tlimit 1", "limit 1"},
+		// 引号和括号中的关键字不算
+		{"name='x ORDER BY y'", ""},
+		{"name='x ORDER BY y' ORDER BY id", "ORDER BY id"},
+		{`name="group by" AND memo='it''s limit 1' LIMIT 2`, "LIMIT 2"},
+		{"id IN (SELECT user_id FROM orders ORDER BY id LIMIT 5)", ""},
+		{"(a=1 OR b=2) ORDER BY (CASE WHEN a=1 THEN 0 ELSE 1 END)", "ORDER BY (CASE WHEN a=1 THEN 0 ELSE 1 END)"},
+		{"`limit`=1 AND `order by`=2", ""},
+		// 关键字必须是完整的单词
+		{"limited=1 AND order_by=2 AND x.limit=3", ""},
+		{"id=1 ORDER BYX", ""},
+	}
+	for _, c := range cases {
+		pos := tailClauseIndex(c.where)
+		if got := c.where[pos:]; got != c.tail {
+			t.Errorf("tailClauseIndex(%q) tail %q, want %q", c.where, got, c.tail)
+		}
+	}
+}
+
+func TestWhereNotDeleted(t *testing.T) {
+	users, orders, items := orm.Schema(&qUser{}), orm.Schema(&qOrder{}), orm.Schema(&qItem{})
+	cases := []struct {
+		ms    *orm.ModelSchema
+		where string
+		want  string
+	}{
+		{users, "id=?", "(id=?) AND deleted_at IS NULL"},
+		{users, "", "deleted_at IS NULL"},
+		{users, "ORDER BY id", "deleted_at IS NULL ORDER BY id"},
+		{users, "a=1 OR b=2 ORDER BY id LIMIT 1", "(a=1 OR b=2) AND deleted_at IS NULL ORDER BY id LIMIT 1"},
+		{users, "name='a ORDER BY b' LIMIT 1", "(name='a ORDER BY b') AND deleted_at IS NULL LIMIT 1"},
+		{users, "id IN (SELECT id FROM t ORDER BY id LIMIT 3)", "(id IN (SELECT id FROM t ORDER BY id LIMIT 3)) AND deleted_at IS NULL"},
+		{orders, "user_id=? GROUP BY status", "(user_id=?) AND deleted=0 GROUP BY status"},
+		// 没有软删除字段的原样返回
+		{items, "id=? ORDER BY id", "id=? ORDER BY id"},
+	}
+	for _, c := range cases {
+		if got := whereNotDeleted(c.ms, c.where); got != c.want {
+			t.Errorf("whereNotDeleted(%q)\n got %q\nwant %q", c.where, got, c.want)
+		}
+	}
+}
+
+type vAccount struct {
+	ID      int64 `dbc:"primary_field"`
+	Name    string
+	Version uint32 `dbc:"version_field"`
+}
+
+func (a *vAccount) GfAttrs(orm.OrmStruct) *orm.ModelAttrs {
+	return &orm.ModelAttrs{TableName: "accounts"}
+}
+func (a *vAccount) BeforeSave()            {}
+func (a *vAccount) AfterInsert(sql.Result) {}
+
+// 版本号作为更新条件，更新成功之后加1写回对象
+func TestUpdateVersion(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	m.expectExec(exactSql("UPDATE accounts SET name=?,version=? WHERE id=? AND version=?;"), "b", 4, 1, 3).withResult(0, 1)
+	m.expectExec(exactSql("UPDATE accounts SET version=?,name=? WHERE id=? AND version=?;"), 5, "c", 1, 4).withResult(0, 1)
+
+	acc := &vAccount{ID: 1, Name: "b", Version: 3}
+	if ct := conn.UpdateFields(acc, "Name"); ct != 1 || acc.Version != 4 {
+		t.Fatalf("got %d version %d", ct, acc.Version)
+	}
+	acc.Name = "c"
+	if ct := conn.Update(acc); ct != 1 || acc.Version != 5 {
+		t.Fatalf("got %d version %d", ct, acc.Version)
+	}
+}
+
+// 更新不到记录说明版本号已经被别人改了，对象上的版本号保持不变
+func TestUpdateVersionConflict(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	m.expectExec(`^UPDATE accounts SET`, 4, "b", 1, 3).withResult(0, 0)
+	m.expectExec(`^UPDATE accounts SET`, "b", 4, 1, 3).withResult(0, 0)
+
+	acc := &vAccount{ID: 1, Name: "b", Version: 3}
+	if _, err := conn.UpdateE(acc); !errors.Is(err, ErrVersionConflict) || acc.Version != 3 {
+		t.Fatalf("got %v version %d", err, acc.Version)
+	}
+	if _, err := conn.UpdateFieldsE(acc, "Name"); !errors.Is(err, ErrVersionConflict) || acc.Version != 3 {
+		t.Fatalf("got %v version %d", err, acc.Version)
+	}
+}
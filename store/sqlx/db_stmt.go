@@ -6,7 +6,6 @@ import (
 	"github.com/qinchende/gofast/logx"
 	"github.com/qinchende/gofast/skill/timex"
 	"github.com/qinchende/gofast/store/orm"
-	"time"
 )
 
 func (conn *OrmDB) Prepare(sqlStr string, readonly bool) *StmtConn {
@@ -17,18 +16,19 @@ func (conn *OrmDB) PrepareCtx(ctx context.Context, sqlStr string, readonly bool)
 	var stmt *sql.Stmt
 	var err error
 
+	dbSql := rebind(conn.Dialect(), sqlStr)
 	if conn.tx == nil {
 		if readonly == true {
-			stmt, err = conn.Reader.PrepareContext(ctx, sqlStr)
+			stmt, err = conn.readerDB(ctx).PrepareContext(ctx, dbSql)
 		} else {
-			stmt, err = conn.Writer.PrepareContext(ctx, sqlStr)
+			stmt, err = conn.Writer.PrepareContext(ctx, dbSql)
 		}
 	} else {
-		stmt, err = conn.tx.PrepareContext(ctx, sqlStr)
+		stmt, err = conn.tx.PrepareContext(ctx, dbSql)
 	}
 
 	ErrPanic(err)
-	return &StmtConn{ctx: ctx, stmt: stmt, sqlStr: sqlStr, readonly: readonly}
+	return &StmtConn{ctx: ctx, attrs: conn.Attrs, stmt: stmt, sqlStr: sqlStr, readonly: readonly}
 }
 
 func (conn *StmtConn) Close() {
@@ -50,12 +50,11 @@ func (conn *StmtConn) ExecCtx(ctx context.Context, args ...any) int64 {
 	if logx.ShowDebug() {
 		logx.Debug(realSql(conn.sqlStr, args...))
 	}
+	sCtx, span := startSqlSpan(ctx, conn.attrs, conn.sqlStr)
 	startTime := timex.Now()
-	ret, err := conn.stmt.ExecContext(ctx, args...)
-	dur := timex.NowDiff(startTime)
-	if dur > slowThreshold {
-		logx.SlowF("[SQL][%dms] slow-call - %s", dur/time.Millisecond, realSql(conn.sqlStr, args...))
-	}
+	ret, err := conn.stmt.ExecContext(sCtx, args...)
+	markWritten(ctx)
+	endSqlSpan(span, conn.attrs, "exec", conn.sqlStr, args, startTime, ret, err)
 
 	if err != nil {
 		ErrLog(err)
@@ -104,11 +103,9 @@ func (conn *StmtConn) queryContext(ctx context.Context, args ...any) (sqlRows *s
 	if logx.ShowDebug() {
 		logx.Debug(realSql(conn.sqlStr, args...))
 	}
+	sCtx, span := startSqlSpan(ctx, conn.attrs, conn.sqlStr)
 	startTime := timex.Now()
-	sqlRows, err = conn.stmt.QueryContext(ctx, args...)
-	dur := timex.NowDiff(startTime)
-	if dur > slowThreshold {
-		logx.SlowF("[SQL][%dms] slow-call - %s", dur/time.Millisecond, realSql(conn.sqlStr, args...))
-	}
+	sqlRows, err = conn.stmt.QueryContext(sCtx, args...)
+	endSqlSpan(span, conn.attrs, "query", conn.sqlStr, args, startTime, nil, err)
 	return
 }
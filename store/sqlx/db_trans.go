@@ -0,0 +1,105 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/qinchende/gofast/logx"
+	"github.com/qinchende/gofast/skill/mathx"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	transRetryBase = 20 * time.Millisecond // 第一次重试前等待的时间，之后每次翻倍
+	transRetryMax  = time.Second           // 最长等待时间
+)
+
+var transRetryDev = mathx.NewUnstable(0.5)
+
+// 在当前事务中建立保存点，返回的连接共享同一个事务
+func (conn *OrmDB) savepoint(ctx context.Context) *OrmDB {
+	name := fmt.Sprintf("gf_sp_%d", atomic.AddInt32(conn.spSeq, 1))
+	_, err := conn.tx.ExecContext(ctx, "SAVEPOINT "+name)
+	ErrPanic(err)
+
+	nConn := *conn
+	nConn.Ctx = ctx
+	nConn.spName = name
+	return &nConn
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 遇到死锁或者锁等待超时，回滚之后自动重试整个事务，retries 是最多重试的次数
+// 和 TransFunc 不同，fn 中的异常不会被吞掉，最终失败的原因通过返回值返回
+// 注意：fn 可能被执行多次，不要在里面做非数据库的副作用操作
+func (conn *OrmDB) TransFuncRetry(retries int, fn func(newConn *OrmDB)) error {
+	return conn.TransFuncRetryCtx(conn.Ctx, nil, retries, fn)
+}
+
+// 已经在事务中时不会重试，因为死锁之后外层事务已经被数据库回滚了
+func (conn *OrmDB) TransFuncRetryCtx(ctx context.Context, opts *sql.TxOptions, retries int, fn func(newConn *OrmDB)) error {
+	for i := 0; ; i++ {
+		err := conn.transOnce(ctx, opts, fn)
+		if err == nil || i >= retries || conn.tx != nil || !canRetryTrans(ctx, err) {
+			return err
+		}
+
+		wait := transRetryBase << i
+		if wait > transRetryMax || wait <= 0 {
+			wait = transRetryMax
+		}
+		wait = transRetryDev.AroundDuration(wait)
+		logx.InfoF("[SQL] transaction retry %d after %dms: %s", i+1, wait/time.Millisecond, err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (conn *OrmDB) transOnce(ctx context.Context, opts *sql.TxOptions, fn func(newConn *OrmDB)) (err error) {
+	defer catchErr(&err)
+
+	nConn := conn.TransCtxOpts(ctx, opts)
+	defer func() {
+		if pic := recover(); pic != nil {
+			ErrLog(nConn.Rollback())
+			panic(pic)
+		}
+	}()
+	fn(nConn)
+	ErrPanic(nConn.Commit())
+	return nil
+}
+
+// 上下文已经超时就没必要重试了；超时类错误只有锁等待超时可以重试，查询超时重试也还是超时
+func canRetryTrans(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return errors.Is(err, ErrDeadlock) || isLockWaitErr(err)
+}
+
+// 锁等待超时：MySQL 1205，PostgreSQL 55P03，SQLite database is locked
+func isLockWaitErr(err error) bool {
+	if !errors.Is(err, ErrTimeout) {
+		return false
+	}
+	var myErr *mysql.MySQLError
+	var pgErr sqlStateErr
+	switch {
+	case errors.As(err, &myErr):
+		return myErr.Number == 1205
+	case errors.As(err, &pgErr):
+		return pgErr.SQLState() == "55P03"
+	}
+	return strings.Contains(err.Error(), "database is locked")
+}
@@ -0,0 +1,161 @@
+package sqlx
+
+import (
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"testing"
+)
+
+var errMysqlDeadlock = &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+
+// 嵌套的 TransFunc 用保存点，提交只是释放保存点，最外层才真正提交
+func TestTransFuncNested(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	m.expectExec("^BEGIN$")
+	m.expectExec(`^UPDATE a`)
+	m.expectExec("^SAVEPOINT gf_sp_1$")
+	m.expectExec(`^UPDATE b`)
+	m.expectExec("^RELEASE SAVEPOINT gf_sp_1$")
+	m.expectExec("^SAVEPOINT gf_sp_2$")
+	m.expectExec(`^UPDATE c`)
+	m.expectExec("^RELEASE SAVEPOINT gf_sp_2$")
+	m.expectExec("^COMMIT$")
+
+	conn.TransFunc(func(tx *OrmDB) {
+		tx.ExecSql("UPDATE a SET v=1")
+		tx.TransFunc(func(sp *OrmDB) {
+			sp.ExecSql("UPDATE b SET v=1")
+		})
+		tx.TransFunc(func(sp *OrmDB) {
+			sp.ExecSql("UPDATE c SET v=1")
+		})
+	})
+}
+
+// 内层异常只回滚到保存点，外层截获之后可以继续并提交
+func TestTransFuncInnerPanic(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	m.expectExec("^BEGIN$")
+	m.expectExec("^SAVEPOINT gf_sp_1$")
+	m.expectExec(`^UPDATE b`)
+	m.expectExec("^ROLLBACK TO SAVEPOINT gf_sp_1$")
+	m.expectExec(`^UPDATE c`)
+	m.expectExec("^COMMIT$")
+
+	var inner any
+	conn.TransFunc(func(tx *OrmDB) {
+		func() {
+			defer func() { inner = recover() }()
+			tx.TransFunc(func(sp *OrmDB) {
+				sp.ExecSql("UPDATE b SET v=1")
+				panic("inner failed")
+			})
+		}()
+		tx.ExecSql("UPDATE c SET v=1")
+	})
+	if inner != "inner failed" {
+		t.Fatalf("inner panic should reach the outer transaction, got %v", inner)
+	}
+}
+
+// 外层不处理内层的异常，整个事务回滚
+func TestTransFuncInnerPanicRollbackAll(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	m.expectExec("^BEGIN$")
+	m.expectExec(`^UPDATE a`)
+	m.expectExec("^SAVEPOINT gf_sp_1$")
+	m.expectExec("^ROLLBACK TO SAVEPOINT gf_sp_1$")
+	m.expectExec("^ROLLBACK$")
+
+	conn.TransFunc(func(tx *OrmDB) {
+		tx.ExecSql("UPDATE a SET v=1")
+		tx.TransFunc(func(sp *OrmDB) {
+			panic("inner failed")
+		})
+		t.Error("outer transaction should stop after inner panic")
+	})
+}
+
+func TestTransFuncRetryDeadlock(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	m.expectExec("^BEGIN$")
+	m.expectExec(`^UPDATE a`).withErr(errMysqlDeadlock)
+	m.expectExec("^ROLLBACK$")
+	m.expectExec("^BEGIN$")
+	m.expectExec(`^UPDATE a`).withResult(0, 1)
+	m.expectExec("^COMMIT$")
+
+	runs := 0
+	err := conn.TransFuncRetry(2, func(tx *OrmDB) {
+		runs++
+		tx.ExecSql("UPDATE a SET v=1")
+	})
+	if err != nil || runs != 2 {
+		t.Fatalf("got %v after %d runs", err, runs)
+	}
+}
+
+func TestTransFuncRetryGiveUp(t *testing.T) {
+	// 重试次数用完，返回最后一次的错误
+	m, conn := newMockDB(t, DriverMysql)
+	for i := 0; i < 2; i++ {
+		m.expectExec("^BEGIN$")
+		m.expectExec(`^UPDATE a`).withErr(errMysqlDeadlock)
+		m.expectExec("^ROLLBACK$")
+	}
+	err := conn.TransFuncRetry(1, func(tx *OrmDB) {
+		tx.ExecSql("UPDATE a SET v=1")
+	})
+	if !errors.Is(err, ErrDeadlock) {
+		t.Fatalf("got %v, want ErrDeadlock", err)
+	}
+
+	// 不是死锁和锁等待超时的错误不重试
+	m.expectExec("^BEGIN$")
+	m.expectExec(`^INSERT INTO a`).withErr(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+	m.expectExec("^ROLLBACK$")
+	runs := 0
+	err = conn.TransFuncRetry(3, func(tx *OrmDB) {
+		runs++
+		tx.ExecSql("INSERT INTO a (v) VALUES (1)")
+	})
+	if !errors.Is(err, ErrDuplicate) || runs != 1 {
+		t.Fatalf("got %v after %d runs", err, runs)
+	}
+
+	// 不是数据库错误的异常继续抛出
+	m.expectExec("^BEGIN$")
+	m.expectExec("^ROLLBACK$")
+	func() {
+		defer func() {
+			if pic := recover(); pic != "bug" {
+				t.Fatalf("got panic %v", pic)
+			}
+		}()
+		_ = conn.TransFuncRetry(3, func(tx *OrmDB) {
+			panic("bug")
+		})
+	}()
+}
+
+// 已经在事务中时死锁不重试，外层事务已经被数据库回滚了
+func TestTransFuncRetryNested(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	m.expectExec("^BEGIN$")
+	m.expectExec("^SAVEPOINT gf_sp_1$")
+	m.expectExec(`^UPDATE a`).withErr(errMysqlDeadlock)
+	m.expectExec("^ROLLBACK TO SAVEPOINT gf_sp_1$")
+	m.expectExec("^COMMIT$")
+
+	runs := 0
+	var err error
+	conn.TransFunc(func(tx *OrmDB) {
+		err = tx.TransFuncRetry(3, func(sp *OrmDB) {
+			runs++
+			sp.ExecSql("UPDATE a SET v=1")
+		})
+	})
+	if !errors.Is(err, ErrDeadlock) || runs != 1 {
+		t.Fatalf("got %v after %d runs", err, runs)
+	}
+}
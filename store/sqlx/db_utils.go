@@ -16,7 +16,17 @@ var (
 	ErrUnsupportedValueType = errors.New("unsupported unmarshal type")
 )
 
+// err 来自数据库驱动、database/sql 或 context 时使用
+// 抛出的是分类之后的 *DBError，recover 之后可以用 errors.Is 判断错误类型
 func ErrPanic(err error) {
+	if err != nil {
+		logx.Stack(err.Error())
+		panic(classifyErr(err))
+	}
+}
+
+// 参数错误、缓存数据解析错误等不是数据库错误，原样抛出，E 系列方法不会把它们当作数据库错误返回
+func panicIfErr(err error) {
 	if err != nil {
 		logx.Stack(err.Error())
 		panic(err)
@@ -50,55 +60,108 @@ func parseSqlResult(ret sql.Result, keyVal any, conn *OrmDB, sm *orm.ModelSchema
 
 	// 判断是否要删除缓存，删除缓存的逻辑要特殊处理，
 	// TODO：删除Key要有策略，比如删除之后加一个删除标记，后面设置缓存策略先查询这个标记，如果有标记就删除标记但本次不设置缓存
-	if ct > 0 && sm.CacheAll() {
-		// 目前只支持第一个redis实例作缓存
-		if conn.rdsNodes != nil {
-			key := sm.CacheLineKey(conn.Attrs.DbName, keyVal)
-			rds := (*conn.rdsNodes)[0]
-			_, _ = rds.Del(key)
-			_, _ = rds.SetEX(key+"_del", "1", sm.ExpireDuration())
-		}
+	if ct > 0 {
+		conn.delLineCache(sm, keyVal)
 	}
-
 	return ct
 }
 
+// 删除行记录缓存，同时加上删除标记
+func (conn *OrmDB) delLineCache(sm *orm.ModelSchema, ids ...any) {
+	if !sm.CacheAll() || conn.cache == nil {
+		return
+	}
+	for _, id := range ids {
+		key := sm.CacheLineKey(conn.Attrs.DbName, id)
+		_ = conn.cache.Del(key)
+		_ = conn.cache.SetExpire(key+"_del", "1", sm.ExpireDuration())
+	}
+}
+
+// 新插入的记录之前可能缓存了空值，需要清除
+func (conn *OrmDB) delNullCache(sm *orm.ModelSchema, ids ...any) {
+	if !sm.CacheAll() || conn.cache == nil {
+		return
+	}
+	for _, id := range ids {
+		_ = conn.cache.Del(sm.CacheLineKey(conn.Attrs.DbName, id))
+	}
+}
+
 func queryByPrimaryWithCache(conn *OrmDB, dest any, id any) int64 {
 	sm := orm.Schema(dest)
+	// 事务中读到的可能是还没提交的数据，不能走缓存
+	if conn.cache == nil || conn.tx != nil {
+		return conn.queryPrimary(dest, id)
+	}
 
 	key := sm.CacheLineKey(conn.Attrs.DbName, id)
-	rds := (*conn.rdsNodes)[0]
-	cacheStr, err := rds.Get(key)
+	var cacheStr string
+	err := conn.cache.Get(key, &cacheStr)
+	if err == nil && cacheStr == cacheNullVal {
+		return 0
+	}
 	if err == nil && cacheStr != "" {
 		if err = loadRecordFromGsonString(dest, cacheStr, sm); err == nil {
 			return 1
 		}
 	}
 
+	// 相同Key的并发请求只有一个去查数据库，其它请求共享查询结果
+	val, fresh, _ := cacheBarrier.DoExt(key, func() (any, error) {
+		return queryPrimaryAndCache(conn, dest, id, sm, key), nil
+	})
+	cacheStr, _ = val.(string)
+	if fresh {
+		if cacheStr == cacheNullVal {
+			return 0
+		}
+		return 1
+	}
+	// 共享的查询发生了异常，自己再查一次
+	if cacheStr == "" {
+		return conn.queryPrimary(dest, id)
+	}
+	if cacheStr == cacheNullVal {
+		return 0
+	}
+	panicIfErr(loadRecordFromGsonString(dest, cacheStr, sm))
+	return 1
+}
+
+// 查询数据库并设置缓存，返回记录的gson字符串，没有记录时返回 cacheNullVal
+func queryPrimaryAndCache(conn *OrmDB, dest any, id any, sm *orm.ModelSchema, key string) string {
 	sqlRows := conn.QuerySql(selectSqlForPrimary(sm), id)
 	defer CloseSqlRows(sqlRows)
 
 	var gro gsonResultOne
+	cacheStr := cacheNullVal
 	ct := scanSqlRowsOne(dest, sqlRows, sm, &gro)
 	if ct > 0 {
-		keyDel := key + "_del"
-		if cacheStr, _ := rds.Get(keyDel); cacheStr == "1" {
-			_, _ = rds.Del(keyDel)
-		} else if jsonValBytes, err := jsonx.Marshal(gro.Row); err == nil {
-			_, _ = rds.Set(key, jsonValBytes, sm.ExpireDuration())
-		}
+		jsonValBytes, err := jsonx.Marshal(gro.Row)
+		panicIfErr(err)
+		cacheStr = string(jsonValBytes)
 	}
-	return ct
+
+	// 刚刚删除或更新过的记录，本次不设置缓存，防止从库延迟导致缓存了旧数据
+	keyDel := key + "_del"
+	var delFlag string
+	if _ = conn.cache.Get(keyDel, &delFlag); delFlag == "1" {
+		_ = conn.cache.Del(keyDel)
+	} else if ct > 0 {
+		_ = conn.cache.SetExpire(key, cacheStr, sm.ExpireDuration())
+	} else {
+		_ = conn.cache.SetExpire(key, cacheStr, nullExpire(sm.ExpireDuration()))
+	}
+	return cacheStr
 }
 
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 func scanSqlRowsOne(dest any, sqlRows *sql.Rows, sm *orm.ModelSchema, gro *gsonResultOne) int64 {
+	// 没有记录不是错误，返回0即可
+	// 注意：Next 返回 false 也可能是读取结果时出错（比如连接中断），这时 panic 出来，不能当作没有记录
 	if !sqlRows.Next() {
-		if err := sqlRows.Err(); err != nil {
-			ErrLog(err)
-		} else {
-			ErrLog(sql.ErrNoRows)
-		}
+		ErrPanic(sqlRows.Err())
 		return 0
 	}
 
@@ -113,7 +176,7 @@ func scanSqlRowsOne(dest any, sqlRows *sql.Rows, sm *orm.ModelSchema, gro *gsonR
 		if rve.CanSet() {
 			ErrPanic(sqlRows.Scan(rve.Addr().Interface()))
 		} else {
-			ErrPanic(ErrNotSettable)
+			panicIfErr(ErrNotSettable)
 		}
 	case reflect.Struct:
 		dbColumns, _ := sqlRows.Columns()
@@ -144,7 +207,7 @@ func scanSqlRowsOne(dest any, sqlRows *sql.Rows, sm *orm.ModelSchema, gro *gsonR
 			}
 		}
 	default:
-		ErrPanic(ErrUnsupportedValueType)
+		panicIfErr(ErrUnsupportedValueType)
 	}
 	return 1
 }
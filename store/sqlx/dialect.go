@@ -0,0 +1,189 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package sqlx
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 不同数据库的SQL差异。sqlx内部统一用 ? 作占位符生成SQL，执行前再按方言转换
+type Dialect interface {
+	Name() string
+	// 第n个参数的占位符，n从1开始
+	Placeholder(n int) string
+	// insert 语句返回自增主键的写法，支持 LastInsertId 的数据库返回空字符串
+	Returning(pk string) string
+	// conflict 列冲突时改为更新 cls 字段
+	Upsert(conflict []string, cls []string) string
+	// 一条语句最多能带多少个参数
+	MaxParams() int
+}
+
+const (
+	DriverMysql    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSqlite   = "sqlite3"
+)
+
+var dialects sync.Map // driverName -> Dialect
+
+func init() {
+	RegisterDialect(DriverMysql, mysqlDialect{})
+	RegisterDialect(DriverPostgres, postgresDialect{})
+	RegisterDialect("pgx", postgresDialect{})
+	RegisterDialect(DriverSqlite, sqliteDialect{})
+	RegisterDialect("sqlite", sqliteDialect{})
+}
+
+// 可以注册新的方言，或者给其它驱动名指定已有的方言
+func RegisterDialect(driverName string, d Dialect) {
+	dialects.Store(driverName, d)
+}
+
+// 没有注册过的驱动按 MySQL 处理
+func DialectOf(driverName string) Dialect {
+	if d, ok := dialects.Load(driverName); ok {
+		return d.(Dialect)
+	}
+	return mysqlDialect{}
+}
+
+func (conn *OrmDB) Dialect() Dialect {
+	return DialectOf(conn.Attrs.DriverName)
+}
+
+// 把SQL中的 ? 转换成方言的占位符，引号中的 ? 不转换
+func rebind(d Dialect, sqlStr string) string {
+	if d.Placeholder(1) == "?" || strings.IndexByte(sqlStr, '?') < 0 {
+		return sqlStr
+	}
+
+	sBuf := strings.Builder{}
+	sBuf.Grow(len(sqlStr) + 16)
+	var quote byte
+	n := 0
+	for i := 0; i < len(sqlStr); i++ {
+		ch := sqlStr[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '?':
+			n++
+			sBuf.WriteString(d.Placeholder(n))
+			continue
+		}
+		sBuf.WriteByte(ch)
+	}
+	return sBuf.String()
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string {
+	return DriverMysql
+}
+
+func (mysqlDialect) Placeholder(int) string {
+	return "?"
+}
+
+func (mysqlDialect) Returning(string) string {
+	return ""
+}
+
+func (mysqlDialect) Upsert(_ []string, cls []string) string {
+	sBuf := strings.Builder{}
+	sBuf.WriteString(" ON DUPLICATE KEY UPDATE ")
+	for i, c := range cls {
+		if i > 0 {
+			sBuf.WriteByte(',')
+		}
+		sBuf.WriteString(c + "=VALUES(" + c + ")")
+	}
+	return sBuf.String()
+}
+
+func (mysqlDialect) MaxParams() int {
+	return 65535
+}
+
+// PostgreSQL 用 $n 作占位符，驱动不支持 LastInsertId，需要 RETURNING 主键
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return DriverPostgres
+}
+
+func (postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgresDialect) Returning(pk string) string {
+	return " RETURNING " + pk
+}
+
+func (postgresDialect) Upsert(conflict []string, cls []string) string {
+	return onConflictUpdate(conflict, cls)
+}
+
+func (postgresDialect) MaxParams() int {
+	return 65535
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return DriverSqlite
+}
+
+func (sqliteDialect) Placeholder(int) string {
+	return "?"
+}
+
+func (sqliteDialect) Returning(string) string {
+	return ""
+}
+
+// SQLite 3.24 之后支持
+func (sqliteDialect) Upsert(conflict []string, cls []string) string {
+	return onConflictUpdate(conflict, cls)
+}
+
+// 老版本 SQLite 默认只支持999个参数
+func (sqliteDialect) MaxParams() int {
+	return 999
+}
+
+func onConflictUpdate(conflict []string, cls []string) string {
+	sBuf := strings.Builder{}
+	sBuf.WriteString(" ON CONFLICT (" + strings.Join(conflict, ",") + ") DO UPDATE SET ")
+	for i, c := range cls {
+		if i > 0 {
+			sBuf.WriteByte(',')
+		}
+		sBuf.WriteString(c + "=EXCLUDED." + c)
+	}
+	return sBuf.String()
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// RETURNING 方式拿到的主键，包装成 sql.Result 交给 AfterInsert
+type returningResult struct {
+	id int64
+	ct int64
+}
+
+func (r returningResult) LastInsertId() (int64, error) {
+	return r.id, nil
+}
+
+func (r returningResult) RowsAffected() (int64, error) {
+	return r.ct, nil
+}
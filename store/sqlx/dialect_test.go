@@ -0,0 +1,74 @@
+package sqlx
+
+import (
+	"testing"
+)
+
+func TestRebind(t *testing.T) {
+	pg := DialectOf(DriverPostgres)
+	cases := []struct {
+		sql, want string
+	}{
+		{"SELECT * FROM users WHERE id=? AND age>?", "SELECT * FROM users WHERE id=$1 AND age>$2"},
+		{"SELECT * FROM users", "SELECT * FROM users"},
+		{"INSERT INTO t (a,b) VALUES (?,?),(?,?)", "INSERT INTO t (a,b) VALUES ($1,$2),($3,$4)"},
+		// 引号中的 ? 不是占位符
+		{"SELECT '?' AS q, name FROM t WHERE id=?", "SELECT '?' AS q, name FROM t WHERE id=$1"},
+		{`SELECT "col?" FROM t WHERE a=?`, `SELECT "col?" FROM t WHERE a=$1`},
+		{"SELECT `c?` FROM t WHERE a=?", "SELECT `c?` FROM t WHERE a=$1"},
+		// 字符串中用两个单引号转义单引号
+		{"SELECT * FROM t WHERE a='it''s ?' AND b=?", "SELECT * FROM t WHERE a='it''s ?' AND b=$1"},
+		{"SELECT * FROM t WHERE a='' AND b=?", "SELECT * FROM t WHERE a='' AND b=$1"},
+		{`SELECT * FROM t WHERE a='"?' AND b=? AND c="'?"`, `SELECT * FROM t WHERE a='"?' AND b=$1 AND c="'?"`},
+		{"SELECT * FROM t WHERE a=?;", "SELECT * FROM t WHERE a=$1;"},
+	}
+	for _, c := range cases {
+		if got := rebind(pg, c.sql); got != c.want {
+			t.Errorf("rebind(%q)\n got %q\nwant %q", c.sql, got, c.want)
+		}
+	}
+
+	// 占位符本身就是 ? 的方言原样返回
+	sqlStr := "SELECT * FROM t WHERE a=? AND b='?'"
+	for _, name := range []string{DriverMysql, DriverSqlite, "unknown"} {
+		if got := rebind(DialectOf(name), sqlStr); got != sqlStr {
+			t.Errorf("%s rebind got %q", name, got)
+		}
+	}
+}
+
+func TestDialects(t *testing.T) {
+	cases := []struct {
+		driver    string
+		name      string
+		returning string
+		upsert    string
+		maxParams int
+	}{
+		{DriverMysql, DriverMysql, "", " ON DUPLICATE KEY UPDATE name=VALUES(name),age=VALUES(age)", 65535},
+		{DriverPostgres, DriverPostgres, " RETURNING id", " ON CONFLICT (id,code) DO UPDATE SET name=EXCLUDED.name,age=EXCLUDED.age", 65535},
+		{"pgx", DriverPostgres, " RETURNING id", " ON CONFLICT (id,code) DO UPDATE SET name=EXCLUDED.name,age=EXCLUDED.age", 65535},
+		{DriverSqlite, DriverSqlite, "", " ON CONFLICT (id,code) DO UPDATE SET name=EXCLUDED.name,age=EXCLUDED.age", 999},
+		{"sqlite", DriverSqlite, "", " ON CONFLICT (id,code) DO UPDATE SET name=EXCLUDED.name,age=EXCLUDED.age", 999},
+		{"unknown", DriverMysql, "", " ON DUPLICATE KEY UPDATE name=VALUES(name),age=VALUES(age)", 65535},
+	}
+	for _, c := range cases {
+		d := DialectOf(c.driver)
+		if d.Name() != c.name {
+			t.Errorf("%s name got %s", c.driver, d.Name())
+		}
+		if got := d.Returning("id"); got != c.returning {
+			t.Errorf("%s returning got %q", c.driver, got)
+		}
+		if got := d.Upsert([]string{"id", "code"}, []string{"name", "age"}); got != c.upsert {
+			t.Errorf("%s upsert got %q", c.driver, got)
+		}
+		if d.MaxParams() != c.maxParams {
+			t.Errorf("%s max params got %d", c.driver, d.MaxParams())
+		}
+	}
+
+	if got := DialectOf(DriverPostgres).Placeholder(12); got != "$12" {
+		t.Errorf("postgres placeholder got %q", got)
+	}
+}
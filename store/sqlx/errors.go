@@ -0,0 +1,112 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"strings"
+)
+
+// 常见的数据库错误分类，可以用 errors.Is(err, sqlx.ErrDuplicate) 判断
+var (
+	ErrNotFound  = errors.New("sqlx: record not found")
+	ErrDuplicate = errors.New("sqlx: duplicate key")
+	ErrDeadlock  = errors.New("sqlx: deadlock")
+	ErrTimeout   = errors.New("sqlx: timeout")
+
+	ErrVersionConflict = errors.New("sqlx: version conflict") // 乐观锁版本号不匹配，记录已被别人修改
+)
+
+// 数据库错误：Kind 是上面的分类之一（无法分类时为nil），Err 是驱动返回的原始错误
+type DBError struct {
+	Kind error
+	Err  error
+}
+
+func (e *DBError) Error() string {
+	if e.Kind == nil {
+		return e.Err.Error()
+	}
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DBError) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
+}
+
+// PostgreSQL 驱动（pq、pgx）的错误都能拿到 SQLSTATE
+type sqlStateErr interface {
+	SQLState() string
+}
+
+// 按驱动的错误码给错误分类，MySQL 用错误号，PostgreSQL 用 SQLSTATE，SQLite 只能看错误信息
+func classifyErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	var dbErr *DBError
+	if errors.As(err, &dbErr) {
+		return err
+	}
+	// 参数错误是程序的 bug，不是数据库错误
+	if errors.Is(err, ErrNotSettable) || errors.Is(err, ErrUnsupportedValueType) {
+		return err
+	}
+
+	var kind error
+	var myErr *mysql.MySQLError
+	var pgErr sqlStateErr
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		kind = ErrNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		kind = ErrTimeout
+	case errors.As(err, &myErr):
+		switch myErr.Number {
+		case 1062, 1586: // ER_DUP_ENTRY, ER_DUP_ENTRY_WITH_KEY_NAME
+			kind = ErrDuplicate
+		case 1213: // ER_LOCK_DEADLOCK
+			kind = ErrDeadlock
+		case 1205, 3024: // ER_LOCK_WAIT_TIMEOUT, ER_QUERY_TIMEOUT
+			kind = ErrTimeout
+		}
+	case errors.As(err, &pgErr):
+		switch pgErr.SQLState() {
+		case "23505": // unique_violation
+			kind = ErrDuplicate
+		case "40P01", "40001": // deadlock_detected, serialization_failure（和死锁一样，整个事务重试）
+			kind = ErrDeadlock
+		case "57014", "55P03": // query_canceled, lock_not_available
+			kind = ErrTimeout
+		}
+	default:
+		msg := err.Error()
+		switch {
+		case strings.Contains(msg, "UNIQUE constraint failed"):
+			kind = ErrDuplicate
+		case strings.Contains(msg, "database is locked"):
+			kind = ErrTimeout
+		}
+	}
+	return &DBError{Kind: kind, Err: err}
+}
+
+// 把 sqlx 内部的数据库错误 panic 转换成 error 返回，供 xxxE 系列方法使用
+// runtime.Error、参数错误等其它 panic 是程序的 bug，继续抛出，不能当作数据库错误吞掉
+func catchErr(err *error) {
+	if pic := recover(); pic != nil {
+		var dbErr *DBError
+		if e, ok := pic.(error); ok && errors.As(e, &dbErr) {
+			*err = e
+			return
+		}
+		panic(pic)
+	}
+}
@@ -0,0 +1,95 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"testing"
+)
+
+func TestClassifyErr(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind error
+	}{
+		{sql.ErrNoRows, ErrNotFound},
+		{context.DeadlineExceeded, ErrTimeout},
+		{fmt.Errorf("wrap: %w", context.DeadlineExceeded), ErrTimeout},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, ErrDuplicate},
+		{&mysql.MySQLError{Number: 1213, Message: "Deadlock found"}, ErrDeadlock},
+		{&mysql.MySQLError{Number: 1205, Message: "Lock wait timeout"}, ErrTimeout},
+		{&mysql.MySQLError{Number: 1146, Message: "Table doesn't exist"}, nil},
+		{errors.New("UNIQUE constraint failed: users.name"), ErrDuplicate},
+		{errors.New("database is locked"), ErrTimeout},
+		{driver.ErrBadConn, nil},
+	}
+	for _, c := range cases {
+		err := classifyErr(c.err)
+		var dbErr *DBError
+		if !errors.As(err, &dbErr) || dbErr.Kind != c.kind || !errors.Is(err, c.err) {
+			t.Errorf("classifyErr(%v) = %#v, want kind %v", c.err, err, c.kind)
+		}
+	}
+
+	// 参数错误原样返回，不当作数据库错误
+	for _, e := range []error{ErrNotSettable, ErrUnsupportedValueType} {
+		var dbErr *DBError
+		if err := classifyErr(e); err != e || errors.As(err, &dbErr) {
+			t.Errorf("classifyErr(%v) = %#v, want the error itself", e, err)
+		}
+	}
+}
+
+func TestInsertEDuplicate(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	m.expectExec(`^INSERT INTO users`).withErr(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'amy'"})
+
+	_, err := conn.InsertE(&qUser{Name: "amy"})
+	var dbErr *DBError
+	if !errors.Is(err, ErrDuplicate) || !errors.As(err, &dbErr) {
+		t.Fatalf("got %#v, want ErrDuplicate", err)
+	}
+}
+
+func TestQueryRowENotFound(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	m.expectQuery(`^SELECT \* FROM users WHERE`, 1).withRows([]string{"id", "name"})
+
+	if err := conn.QueryRowE(&qUser{}, "id=?", 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+}
+
+// 目标对象不能接收结果是程序的 bug，E 系列方法不能把它当作数据库错误返回
+func TestQueryRowEBadDestRepanics(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	m.expectQuery(`^SELECT \* FROM users WHERE`).withRows([]string{"id", "name"}, []driver.Value{int64(1), "amy"})
+	m.expectQuery(`^SELECT id FROM users`).withRows([]string{"id"}, []driver.Value{int64(1)})
+
+	mustRepanic := func(want error, fn func()) {
+		t.Helper()
+		defer func() {
+			t.Helper()
+			pic := recover()
+			if err, ok := pic.(error); !ok || err != want {
+				t.Errorf("got panic %#v, want %v", pic, want)
+			}
+		}()
+		fn()
+	}
+
+	var pu *qUser
+	mustRepanic(ErrUnsupportedValueType, func() { _ = conn.QueryRowE(&pu, "id=?", 1) })
+
+	// 值类型的 map 元素不可寻址
+	mustRepanic(ErrNotSettable, func() {
+		_ = notFoundE(func() int64 {
+			rows := conn.QuerySql("SELECT id FROM users LIMIT 1")
+			defer CloseSqlRows(rows)
+			return scanSqlRowsOne(map[string]int64{}, rows, nil, nil)
+		})
+	})
+}
@@ -0,0 +1,284 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"github.com/qinchende/gofast/logx"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sync"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	logx.MustSetup(&logx.LogConfig{LogMedium: "console", LogLevel: "error", LogStyle: "sdx"})
+	os.Exit(m.Run())
+}
+
+// 测试用的假数据库驱动：按顺序预设期望的SQL和返回结果，不需要真实的数据库
+// BEGIN、COMMIT、ROLLBACK 也当作一条期望，用 expectExec 设置
+type mockExpect struct {
+	query    bool
+	sqlRe    *regexp.Regexp
+	args     []driver.Value // nil 代表不检查参数
+	cols     []string
+	rows     [][]driver.Value
+	lastId   int64
+	affected int64
+	err      error
+	hook     func() // 执行这条SQL时回调，可以用来模拟耗时或者取消上下文
+}
+
+func (e *mockExpect) withRows(cols []string, rows ...[]driver.Value) *mockExpect {
+	e.cols, e.rows = cols, rows
+	return e
+}
+
+func (e *mockExpect) withResult(lastId, affected int64) *mockExpect {
+	e.lastId, e.affected = lastId, affected
+	return e
+}
+
+func (e *mockExpect) withErr(err error) *mockExpect {
+	e.err = err
+	return e
+}
+
+func (e *mockExpect) withHook(fn func()) *mockExpect {
+	e.hook = fn
+	return e
+}
+
+type mockDB struct {
+	t       *testing.T
+	mu      sync.Mutex
+	expects []*mockExpect
+	pingErr error // 不为nil时 Ping 返回这个错误，模拟数据库连不上
+	db      *sql.DB
+}
+
+// 返回的 OrmDB 读写都用同一个假连接
+func newMockDB(t *testing.T, driverName string) (*mockDB, *OrmDB) {
+	m := &mockDB{t: t}
+	m.db = sql.OpenDB(m)
+	t.Cleanup(func() {
+		_ = m.db.Close()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for _, e := range m.expects {
+			t.Errorf("mockdb: expectation not met: %s", e.sqlRe)
+		}
+	})
+	conn := &OrmDB{
+		Attrs:  &DBAttrs{DriverName: driverName, DbName: "test"},
+		Ctx:    context.Background(),
+		Reader: m.db,
+		Writer: m.db,
+	}
+	return m, conn
+}
+
+func (m *mockDB) expectQuery(sqlRe string, args ...any) *mockExpect {
+	return m.expect(true, sqlRe, args)
+}
+
+func (m *mockDB) expectExec(sqlRe string, args ...any) *mockExpect {
+	return m.expect(false, sqlRe, args)
+}
+
+func (m *mockDB) expect(query bool, sqlRe string, args []any) *mockExpect {
+	e := &mockExpect{query: query, sqlRe: regexp.MustCompile(sqlRe)}
+	if args != nil {
+		e.args = make([]driver.Value, len(args))
+		for i, arg := range args {
+			v, err := driver.DefaultParameterConverter.ConvertValue(arg)
+			if err != nil {
+				m.t.Fatalf("mockdb: bad arg %v: %v", arg, err)
+			}
+			e.args[i] = v
+		}
+	}
+	m.mu.Lock()
+	m.expects = append(m.expects, e)
+	m.mu.Unlock()
+	return e
+}
+
+// 取出下一条期望，和实际执行的SQL不匹配就返回错误
+func (m *mockDB) next(query bool, sqlStr string, args []driver.NamedValue) (*mockExpect, error) {
+	m.mu.Lock()
+	if len(m.expects) == 0 {
+		m.mu.Unlock()
+		m.t.Errorf("mockdb: unexpected sql %q", sqlStr)
+		return nil, fmt.Errorf("mockdb: unexpected sql %q", sqlStr)
+	}
+	e := m.expects[0]
+	m.expects = m.expects[1:]
+	m.mu.Unlock()
+
+	if e.query != query || !e.sqlRe.MatchString(sqlStr) {
+		m.t.Errorf("mockdb: sql %q does not match %s (query=%v)", sqlStr, e.sqlRe, e.query)
+		return nil, fmt.Errorf("mockdb: sql %q does not match %s", sqlStr, e.sqlRe)
+	}
+	if e.args != nil {
+		got := make([]driver.Value, len(args))
+		for i := range args {
+			got[i] = args[i].Value
+		}
+		if !reflect.DeepEqual(got, e.args) {
+			m.t.Errorf("mockdb: sql %q args %v, want %v", sqlStr, got, e.args)
+			return nil, fmt.Errorf("mockdb: args mismatch")
+		}
+	}
+	if e.hook != nil {
+		e.hook()
+	}
+	return e, e.err
+}
+
+// 剩下还没有执行的期望数量
+func (m *mockDB) pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.expects)
+}
+
+func (m *mockDB) Connect(context.Context) (driver.Conn, error) {
+	return &mockConn{m: m}, nil
+}
+
+func (m *mockDB) Driver() driver.Driver {
+	return mockDriver{}
+}
+
+type mockDriver struct{}
+
+func (mockDriver) Open(string) (driver.Conn, error) {
+	return nil, fmt.Errorf("mockdb: use sql.OpenDB")
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+type mockConn struct {
+	m *mockDB
+}
+
+func (c *mockConn) Prepare(query string) (driver.Stmt, error) {
+	return &mockStmt{c: c, sqlStr: query}, nil
+}
+
+func (c *mockConn) Close() error { return nil }
+
+func (c *mockConn) Ping(context.Context) error {
+	c.m.mu.Lock()
+	defer c.m.mu.Unlock()
+	return c.m.pingErr
+}
+
+func (c *mockConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *mockConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	if _, err := c.m.next(false, "BEGIN", nil); err != nil {
+		return nil, err
+	}
+	return &mockTx{c: c}, nil
+}
+
+func (c *mockConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	e, err := c.m.next(true, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &mockRows{cols: e.cols, rows: e.rows}, nil
+}
+
+func (c *mockConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	e, err := c.m.next(false, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return mockResult{lastId: e.lastId, affected: e.affected}, nil
+}
+
+type mockTx struct {
+	c *mockConn
+}
+
+func (tx *mockTx) Commit() error {
+	_, err := tx.c.m.next(false, "COMMIT", nil)
+	return err
+}
+
+func (tx *mockTx) Rollback() error {
+	_, err := tx.c.m.next(false, "ROLLBACK", nil)
+	return err
+}
+
+type mockStmt struct {
+	c      *mockConn
+	sqlStr string
+}
+
+func (s *mockStmt) Close() error  { return nil }
+func (s *mockStmt) NumInput() int { return -1 }
+
+func (s *mockStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), toNamed(args))
+}
+
+func (s *mockStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), toNamed(args))
+}
+
+func (s *mockStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.c.ExecContext(ctx, s.sqlStr, args)
+}
+
+func (s *mockStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.c.QueryContext(ctx, s.sqlStr, args)
+}
+
+func toNamed(args []driver.Value) []driver.NamedValue {
+	nvs := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		nvs[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return nvs
+}
+
+type mockResult struct {
+	lastId   int64
+	affected int64
+}
+
+func (r mockResult) LastInsertId() (int64, error) { return r.lastId, nil }
+func (r mockResult) RowsAffected() (int64, error) { return r.affected, nil }
+
+type mockRows struct {
+	cols []string
+	rows [][]driver.Value
+	pos  int
+}
+
+func (r *mockRows) Columns() []string { return r.cols }
+func (r *mockRows) Close() error      { return nil }
+
+func (r *mockRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.pos])
+	r.pos++
+	return nil
+}
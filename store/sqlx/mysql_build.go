@@ -41,12 +41,29 @@ func insertSql(mss *orm.ModelSchema) string {
 	})
 }
 
+// insert 时不赋值的自增字段
+func autoColumn(ms *orm.ModelSchema) string {
+	if autoIdx := ms.AutoIndex(); autoIdx > 0 {
+		return ms.Columns()[autoIdx]
+	}
+	return ms.Columns()[0]
+}
+
+// 有软删除字段时，删除变成更新软删除字段
 func deleteSql(mss *orm.ModelSchema) string {
 	return mss.DeleteSQL(func(ms *orm.ModelSchema) string {
-		return fmt.Sprintf("DELETE FROM %s WHERE %s=?;", ms.TableName(), ms.Columns()[ms.PrimaryIndex()])
+		if ms.SoftDelete() {
+			return fmt.Sprintf("UPDATE %s SET %s=? WHERE %s=? AND %s;", ms.TableName(), ms.Columns()[ms.DeletedIndex()],
+				ms.Columns()[ms.PrimaryIndex()], ms.NotDeletedCond())
+		}
+		return forceDeleteSql(ms)
 	})
 }
 
+func forceDeleteSql(ms *orm.ModelSchema) string {
+	return fmt.Sprintf("DELETE FROM %s WHERE %s=?;", ms.TableName(), ms.Columns()[ms.PrimaryIndex()])
+}
+
 func updateSql(mss *orm.ModelSchema) string {
 	return mss.UpdateSQL(func(ms *orm.ModelSchema) string {
 		cls := ms.Columns()
@@ -67,6 +84,10 @@ func updateSql(mss *orm.ModelSchema) string {
 			}
 			sBuf.WriteString("=?")
 		}
+		// 有版本号字段时，版本号不匹配就更新不到记录
+		if verIdx := ms.VersionIndex(); verIdx >= 0 {
+			return fmt.Sprintf("UPDATE %s SET %s WHERE %s=? AND %s=?;", ms.TableName(), sBuf.String(), cls[priIdx], cls[verIdx])
+		}
 		return fmt.Sprintf("UPDATE %s SET %s WHERE %s=?;", ms.TableName(), sBuf.String(), cls[priIdx])
 	})
 }
@@ -85,40 +106,53 @@ func updateSqlByFields(ms *orm.ModelSchema, rVal *reflect.Value, fNames ...strin
 	flsKV := ms.FieldsKV()
 	cls := ms.Columns()
 	sBuf := strings.Builder{}
-	tValues := make([]any, tgLen+2)
+	tValues := make([]any, 0, tgLen+4)
+	verIdx := ms.VersionIndex()
 
 	for i := 0; i < tgLen; i++ {
 		idx, ok := flsKV[fNames[i]]
 		if !ok {
 			fst.GFPanicErr(fmt.Errorf("Field %s not exist.", fNames[i]))
 		}
+		// 版本号字段统一在后面处理
+		if idx == verIdx {
+			continue
+		}
 
 		// 更新字符串
-		if i > 0 {
+		if len(tValues) > 0 {
 			sBuf.WriteByte(',')
 		}
 		sBuf.WriteString(cls[idx])
 		sBuf.WriteString("=?")
 
 		// 值
-		tValues[i] = ms.ValueByIndex(rVal, idx)
+		tValues = append(tValues, ms.ValueByIndex(rVal, idx))
 	}
 
 	// 更新字段
-	upIdx := ms.UpdatedIndex()
-	priIdx := ms.PrimaryIndex()
-	if upIdx >= 0 {
+	if upIdx := ms.UpdatedIndex(); upIdx >= 0 {
 		sBuf.WriteByte(',')
 		sBuf.WriteString(cls[upIdx])
 		sBuf.WriteString("=?")
-		tValues[tgLen] = ms.ValueByIndex(rVal, upIdx)
-		tValues[tgLen+1] = ms.ValueByIndex(rVal, priIdx)
+		tValues = append(tValues, ms.ValueByIndex(rVal, upIdx))
+	}
+
+	// 版本号字段：SET version=新版本 WHERE version=老版本
+	priIdx := ms.PrimaryIndex()
+	where := cls[priIdx] + "=?"
+	if verIdx >= 0 {
+		curVer, nextVer := versionValues(ms, rVal)
+		sBuf.WriteByte(',')
+		sBuf.WriteString(cls[verIdx])
+		sBuf.WriteString("=?")
+		tValues = append(tValues, nextVer, ms.ValueByIndex(rVal, priIdx), curVer)
+		where += " AND " + cls[verIdx] + "=?"
 	} else {
-		tValues[tgLen] = ms.ValueByIndex(rVal, priIdx)
-		tValues = tValues[:tgLen+1]
+		tValues = append(tValues, ms.ValueByIndex(rVal, priIdx))
 	}
 
-	return fmt.Sprintf("UPDATE %s SET %s WHERE %s=?;", ms.TableName(), sBuf.String(), cls[priIdx]), tValues
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s;", ms.TableName(), sBuf.String(), where), tValues
 }
 
 // +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
@@ -126,6 +160,9 @@ func updateSqlByFields(ms *orm.ModelSchema, rVal *reflect.Value, fNames ...strin
 
 func selectSqlForPrimary(mss *orm.ModelSchema) string {
 	return mss.SelectSQL(func(ms *orm.ModelSchema) string {
+		if ms.SoftDelete() {
+			return fmt.Sprintf("SELECT * FROM %s WHERE %s=? AND %s LIMIT 1;", ms.TableName(), ms.Columns()[ms.PrimaryIndex()], ms.NotDeletedCond())
+		}
 		return fmt.Sprintf("SELECT * FROM %s WHERE %s=? LIMIT 1;", ms.TableName(), ms.Columns()[ms.PrimaryIndex()])
 	})
 }
@@ -137,6 +174,7 @@ func selectSqlForOne(mss *orm.ModelSchema, fields string, where string) string {
 	if where == "" {
 		where = "1=1"
 	}
+	where = whereNotDeleted(mss, where)
 	return fmt.Sprintf("SELECT %s FROM %s WHERE %s LIMIT 1;", fields, mss.TableName(), where)
 }
 
@@ -150,6 +188,7 @@ func selectSqlForSome(mss *orm.ModelSchema, fields string, where string) string
 	if strings.Index(where, "limit") < 0 {
 		where += " LIMIT 10000" // 最多1万条记录
 	}
+	where = whereNotDeleted(mss, where)
 	return fmt.Sprintf("SELECT %s FROM %s WHERE %s;", fields, mss.TableName(), where)
 }
 
@@ -159,6 +198,14 @@ func checkPet(mss *orm.ModelSchema, pet *SelectPet) *SelectPet {
 		return pet
 	}
 
+	if pet.Query != nil {
+		pet.Table, pet.Where, pet.GroupBy, pet.OrderBy, pet.Args = pet.Query.build(mss)
+		// 多表查询默认只取主表的字段
+		if pet.Columns == "" && len(pet.Query.joins) > 0 {
+			pet.Columns = mss.TableName() + ".*"
+		}
+	}
+
 	if pet.Table == "" {
 		pet.Table = mss.TableName()
 	}
@@ -194,16 +241,24 @@ func checkPet(mss *orm.ModelSchema, pet *SelectPet) *SelectPet {
 }
 
 func selectSqlForPet(mss *orm.ModelSchema, pet *SelectPet) string {
-	return fmt.Sprintf("SELECT %s FROM %s WHERE %s%s%s LIMIT %d OFFSET %d;", pet.Columns, pet.Table, pet.Where, pet.groupByT, pet.orderByT, pet.Limit, pet.Offset)
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s%s%s LIMIT %d OFFSET %d;", pet.Columns, pet.Table, petWhere(mss, pet), pet.groupByT, pet.orderByT, pet.Limit, pet.Offset)
 }
 
 func selectCountSqlForPet(mss *orm.ModelSchema, pet *SelectPet) string {
 	if pet.GroupBy == "" {
-		return fmt.Sprintf("SELECT COUNT(*) AS COUNT FROM %s WHERE %s;", pet.Table, pet.Where)
+		return fmt.Sprintf("SELECT COUNT(*) AS COUNT FROM %s WHERE %s;", pet.Table, petWhere(mss, pet))
 	}
-	return fmt.Sprintf("SELECT COUNT(DISTINCT(%s)) AS COUNT FROM %s WHERE %s;", pet.GroupBy, pet.Table, pet.Where)
+	return fmt.Sprintf("SELECT COUNT(DISTINCT(%s)) AS COUNT FROM %s WHERE %s;", pet.GroupBy, pet.Table, petWhere(mss, pet))
 }
 
 func selectPagingSqlForPet(mss *orm.ModelSchema, pet *SelectPet) string {
-	return fmt.Sprintf("SELECT %s FROM %s WHERE %s%s%s LIMIT %d OFFSET %d;", pet.Columns, pet.Table, pet.Where, pet.groupByT, pet.orderByT, pet.PageSize, (pet.Page-1)*pet.PageSize)
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s%s%s LIMIT %d OFFSET %d;", pet.Columns, pet.Table, petWhere(mss, pet), pet.groupByT, pet.orderByT, pet.PageSize, (pet.Page-1)*pet.PageSize)
+}
+
+// 只有查询的是 Model 自己的表才自动过滤软删除的记录，自定义的多表查询需要自己加条件
+func petWhere(mss *orm.ModelSchema, pet *SelectPet) string {
+	if !mss.SoftDelete() || pet.Table != mss.TableName() {
+		return pet.Where
+	}
+	return "(" + pet.Where + ") AND " + mss.NotDeletedCond()
 }
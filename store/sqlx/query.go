@@ -0,0 +1,325 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package sqlx
+
+import (
+	"fmt"
+	"github.com/qinchende/gofast/store/orm"
+	"reflect"
+	"strings"
+)
+
+// 类型安全的查询条件，代替 SelectPet 中手写的 Where、OrderBy、GroupBy 和 Args，比如：
+// pet := &sqlx.SelectPet{Target: &users, Query: sqlx.Where(sqlx.Eq("status", 1), sqlx.Or(sqlx.Like("name", key+"%"), sqlx.In("id", ids...))).Desc("id")}
+// 列名可以是数据库列名也可以是结构体字段名，都会用 ModelSchema 校验，不存在的列直接 panic
+// 所有的值都作为参数传递，占位符由 rebind 转换成对应方言的写法
+type Cond interface {
+	render(r *queryRender)
+}
+
+type Query struct {
+	conds  []Cond
+	joins  []queryJoin
+	groups []string
+	orders []string
+}
+
+type queryJoin struct {
+	kind  string
+	model any
+	left  string
+	right string
+}
+
+func Where(conds ...Cond) *Query {
+	return &Query{conds: conds}
+}
+
+// 追加条件，和之前的条件是 AND 关系
+func (q *Query) And(conds ...Cond) *Query {
+	q.conds = append(q.conds, conds...)
+	return q
+}
+
+// INNER JOIN model 对应的表，ON 本表（或者之前 Join 的表，用 表名.列名）left = 关联表 right
+// 关联表有软删除字段时，ON 条件中自动加上过滤已删除记录的条件
+func (q *Query) Join(model any, left, right string) *Query {
+	q.joins = append(q.joins, queryJoin{kind: "INNER", model: model, left: left, right: right})
+	return q
+}
+
+func (q *Query) LeftJoin(model any, left, right string) *Query {
+	q.joins = append(q.joins, queryJoin{kind: "LEFT", model: model, left: left, right: right})
+	return q
+}
+
+func (q *Query) GroupBy(cols ...string) *Query {
+	q.groups = append(q.groups, cols...)
+	return q
+}
+
+func (q *Query) Asc(cols ...string) *Query {
+	for _, col := range cols {
+		q.orders = append(q.orders, col+" ASC")
+	}
+	return q
+}
+
+func (q *Query) Desc(cols ...string) *Query {
+	for _, col := range cols {
+		q.orders = append(q.orders, col+" DESC")
+	}
+	return q
+}
+
+// 生成 SelectPet 需要的各个部分
+func (q *Query) build(ms *orm.ModelSchema) (table, where, groupBy, orderBy string, args []any) {
+	r := &queryRender{ms: ms, tables: map[string]*orm.ModelSchema{ms.TableName(): ms}, joined: len(q.joins) > 0}
+
+	// 1. JOIN
+	tb := strings.Builder{}
+	tb.WriteString(ms.TableName())
+	for _, jn := range q.joins {
+		jms := orm.Schema(jn.model)
+		r.tables[jms.TableName()] = jms
+		tb.WriteString(fmt.Sprintf(" %s JOIN %s ON %s=%s", jn.kind, jms.TableName(), r.column(jn.left),
+			r.column(jms.TableName()+"."+jn.right)))
+		// 放在 ON 中而不是 WHERE 中，LEFT JOIN 时主表的记录不会因为关联记录已删除而被过滤掉
+		if jms.SoftDelete() {
+			tb.WriteString(" AND " + jms.TableName() + "." + jms.NotDeletedCond())
+		}
+	}
+
+	// 2. WHERE，多表查询时自动过滤的软删除条件需要加上表名
+	conds := q.conds
+	if r.joined && ms.SoftDelete() {
+		conds = append(conds[:len(conds):len(conds)], Raw(ms.TableName()+"."+ms.NotDeletedCond()))
+	}
+	And(conds...).render(r)
+
+	// 3. GROUP BY、ORDER BY
+	groups := make([]string, len(q.groups))
+	for i, col := range q.groups {
+		groups[i] = r.column(col)
+	}
+	orders := make([]string, len(q.orders))
+	for i, item := range q.orders {
+		sp := strings.LastIndexByte(item, ' ')
+		orders[i] = r.column(item[:sp]) + item[sp:]
+	}
+	return tb.String(), r.sb.String(), strings.Join(groups, ","), strings.Join(orders, ","), r.args
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+type queryRender struct {
+	ms     *orm.ModelSchema
+	tables map[string]*orm.ModelSchema
+	joined bool
+	sb     strings.Builder
+	args   []any
+}
+
+// 校验并转换列名，多表查询时统一加上表名，防止列名冲突
+func (r *queryRender) column(name string) string {
+	tbl, col := "", name
+	if idx := strings.IndexByte(name, '.'); idx > 0 {
+		tbl, col = name[:idx], name[idx+1:]
+	}
+	ms := r.ms
+	if tbl != "" {
+		if ms = r.tables[tbl]; ms == nil {
+			panic(fmt.Errorf("sqlx: query table %s not exist", tbl))
+		}
+	}
+
+	if _, ok := ms.ColumnsKV()[col]; !ok {
+		idx, ok := ms.FieldsKV()[col]
+		if !ok {
+			panic(fmt.Errorf("sqlx: query column %s not exist in %s", col, ms.TableName()))
+		}
+		col = ms.Columns()[idx]
+	}
+	if tbl == "" && r.joined {
+		tbl = ms.TableName()
+	}
+	if tbl != "" {
+		return tbl + "." + col
+	}
+	return col
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+type opCond struct {
+	col string
+	op  string
+	val any
+}
+
+func Eq(col string, val any) Cond   { return &opCond{col: col, op: "=", val: val} }
+func Ne(col string, val any) Cond   { return &opCond{col: col, op: "<>", val: val} }
+func Gt(col string, val any) Cond   { return &opCond{col: col, op: ">", val: val} }
+func Gte(col string, val any) Cond  { return &opCond{col: col, op: ">=", val: val} }
+func Lt(col string, val any) Cond   { return &opCond{col: col, op: "<", val: val} }
+func Lte(col string, val any) Cond  { return &opCond{col: col, op: "<=", val: val} }
+func Like(col string, val any) Cond { return &opCond{col: col, op: " LIKE ", val: val} }
+
+// Eq 和 Ne 的值是 nil 时，转换成 IS NULL 和 IS NOT NULL（col = NULL 永远不成立）
+func (c *opCond) render(r *queryRender) {
+	if (c.op == "=" || c.op == "<>") && isNilValue(c.val) {
+		(&nullCond{col: c.col, not: c.op == "<>"}).render(r)
+		return
+	}
+	r.sb.WriteString(r.column(c.col))
+	r.sb.WriteString(c.op)
+	r.sb.WriteByte('?')
+	r.args = append(r.args, c.val)
+}
+
+type inCond struct {
+	col  string
+	not  bool
+	vals []any
+}
+
+// 只传一个切片参数时会展开，比如 In("id", ids) 和 In("id", ids...) 效果一样
+func In(col string, vals ...any) Cond { return &inCond{col: col, vals: flattenArgs(vals)} }
+func NotIn(col string, vals ...any) Cond {
+	return &inCond{col: col, not: true, vals: flattenArgs(vals)}
+}
+
+func (c *inCond) render(r *queryRender) {
+	column := r.column(c.col)
+	// 空集合：IN 永远不成立，NOT IN 永远成立
+	if len(c.vals) == 0 {
+		if c.not {
+			r.sb.WriteString("1=1")
+		} else {
+			r.sb.WriteString("1=0")
+		}
+		return
+	}
+	r.sb.WriteString(column)
+	if c.not {
+		r.sb.WriteString(" NOT")
+	}
+	r.sb.WriteString(" IN (")
+	r.sb.WriteString(strings.TrimSuffix(strings.Repeat("?,", len(c.vals)), ","))
+	r.sb.WriteByte(')')
+	r.args = append(r.args, c.vals...)
+}
+
+type betweenCond struct {
+	col      string
+	min, max any
+}
+
+func Between(col string, min, max any) Cond { return &betweenCond{col: col, min: min, max: max} }
+
+func (c *betweenCond) render(r *queryRender) {
+	r.sb.WriteString(r.column(c.col))
+	r.sb.WriteString(" BETWEEN ? AND ?")
+	r.args = append(r.args, c.min, c.max)
+}
+
+type nullCond struct {
+	col string
+	not bool
+}
+
+func IsNull(col string) Cond  { return &nullCond{col: col} }
+func NotNull(col string) Cond { return &nullCond{col: col, not: true} }
+
+func (c *nullCond) render(r *queryRender) {
+	r.sb.WriteString(r.column(c.col))
+	if c.not {
+		r.sb.WriteString(" IS NOT NULL")
+	} else {
+		r.sb.WriteString(" IS NULL")
+	}
+}
+
+type groupCond struct {
+	op    string
+	conds []Cond
+}
+
+func And(conds ...Cond) Cond { return &groupCond{op: " AND ", conds: conds} }
+func Or(conds ...Cond) Cond  { return &groupCond{op: " OR ", conds: conds} }
+
+func (c *groupCond) render(r *queryRender) {
+	ct := 0
+	for _, cd := range c.conds {
+		if emptyCond(cd) {
+			continue
+		}
+		if ct > 0 {
+			r.sb.WriteString(c.op)
+		}
+		// 嵌套的分组加括号，保证优先级
+		if _, ok := cd.(*groupCond); ok {
+			r.sb.WriteByte('(')
+			cd.render(r)
+			r.sb.WriteByte(')')
+		} else {
+			cd.render(r)
+		}
+		ct++
+	}
+}
+
+func emptyCond(cd Cond) bool {
+	if cd == nil {
+		return true
+	}
+	if gc, ok := cd.(*groupCond); ok {
+		for _, sub := range gc.conds {
+			if !emptyCond(sub) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+type rawCond struct {
+	sql  string
+	args []any
+}
+
+// 原样输出的条件，不做列名校验，只在构造器表达不了的时候使用，值一定要用参数传递
+func Raw(sql string, args ...any) Cond { return &rawCond{sql: sql, args: args} }
+
+func (c *rawCond) render(r *queryRender) {
+	r.sb.WriteByte('(')
+	r.sb.WriteString(c.sql)
+	r.sb.WriteByte(')')
+	r.args = append(r.args, c.args...)
+}
+
+func isNilValue(val any) bool {
+	if val == nil {
+		return true
+	}
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func flattenArgs(vals []any) []any {
+	if len(vals) != 1 {
+		return vals
+	}
+	rv := reflect.ValueOf(vals[0])
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return vals
+	}
+	ret := make([]any, rv.Len())
+	for i := range ret {
+		ret[i] = rv.Index(i).Interface()
+	}
+	return ret
+}
@@ -0,0 +1,133 @@
+package sqlx
+
+import (
+	"database/sql"
+	"github.com/qinchende/gofast/store/orm"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type qUser struct {
+	ID        int64 `dbc:"primary_field"`
+	Name      string
+	Status    int8
+	DeletedAt *time.Time `dbc:"deleted_field"`
+}
+
+func (u *qUser) GfAttrs(orm.OrmStruct) *orm.ModelAttrs { return &orm.ModelAttrs{TableName: "users"} }
+func (u *qUser) BeforeSave()                           {}
+func (u *qUser) AfterInsert(sql.Result)                {}
+
+type qOrder struct {
+	ID      int64 `dbc:"primary_field"`
+	UserID  int64
+	Amount  float64
+	Deleted int64 `dbc:"deleted_field"`
+}
+
+func (o *qOrder) GfAttrs(orm.OrmStruct) *orm.ModelAttrs { return &orm.ModelAttrs{TableName: "orders"} }
+func (o *qOrder) BeforeSave()                           {}
+func (o *qOrder) AfterInsert(sql.Result)                {}
+
+type qItem struct {
+	ID      int64 `dbc:"primary_field"`
+	OrderID int64
+	Title   string
+}
+
+func (i *qItem) GfAttrs(orm.OrmStruct) *orm.ModelAttrs { return &orm.ModelAttrs{TableName: "items"} }
+func (i *qItem) BeforeSave()                           {}
+func (i *qItem) AfterInsert(sql.Result)                {}
+
+func TestQueryOperators(t *testing.T) {
+	var nilPtr *int
+	cases := []struct {
+		cond  Cond
+		where string
+		args  []any
+	}{
+		{Eq("status", 1), "status=?", []any{1}},
+		{Ne("status", 1), "status<>?", []any{1}},
+		{Gt("id", 1), "id>?", []any{1}},
+		{Gte("id", 1), "id>=?", []any{1}},
+		{Lt("id", 1), "id<?", []any{1}},
+		{Lte("id", 1), "id<=?", []any{1}},
+		{Like("Name", "a%"), "name LIKE ?", []any{"a%"}},
+		{Eq("name", nil), "name IS NULL", nil},
+		{Ne("name", nil), "name IS NOT NULL", nil},
+		{Eq("name", nilPtr), "name IS NULL", nil},
+		{In("id", 1, 2), "id IN (?,?)", []any{1, 2}},
+		{In("id", []int64{1, 2, 3}), "id IN (?,?,?)", []any{int64(1), int64(2), int64(3)}},
+		{In("id"), "1=0", nil},
+		{NotIn("id", 1), "id NOT IN (?)", []any{1}},
+		{NotIn("id"), "1=1", nil},
+		{Between("id", 1, 9), "id BETWEEN ? AND ?", []any{1, 9}},
+		{IsNull("name"), "name IS NULL", nil},
+		{NotNull("name"), "name IS NOT NULL", nil},
+		{Raw("LENGTH(name)>?", 3), "(LENGTH(name)>?)", []any{3}},
+		{Or(Eq("id", 1), Eq("id", 2)), "(id=? OR id=?)", []any{1, 2}},
+		{And(Eq("id", 1), Or(Eq("status", 1), Eq("status", 2))), "(id=? AND (status=? OR status=?))", []any{1, 1, 2}},
+		{And(Or(), nil, Eq("id", 1)), "(id=?)", []any{1}},
+	}
+	ms := orm.Schema(&qUser{})
+	for _, c := range cases {
+		_, where, _, _, args := Where(c.cond).build(ms)
+		if where != c.where || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("got %q %v, want %q %v", where, args, c.where, c.args)
+		}
+	}
+}
+
+func TestQueryJoins(t *testing.T) {
+	ms := orm.Schema(&qUser{})
+	cases := []struct {
+		query *Query
+		table string
+		where string
+	}{
+		{
+			Where(Eq("status", 1)).Join(&qOrder{}, "id", "user_id"),
+			"users INNER JOIN orders ON users.id=orders.user_id AND orders.deleted=0",
+			"users.status=? AND (users.deleted_at IS NULL)",
+		},
+		{
+			Where(Eq("orders.amount", 1)).LeftJoin(&qOrder{}, "id", "UserID"),
+			"users LEFT JOIN orders ON users.id=orders.user_id AND orders.deleted=0",
+			"orders.amount=? AND (users.deleted_at IS NULL)",
+		},
+		{
+			Where().Join(&qOrder{}, "id", "user_id").LeftJoin(&qItem{}, "orders.id", "order_id"),
+			"users INNER JOIN orders ON users.id=orders.user_id AND orders.deleted=0 LEFT JOIN items ON orders.id=items.order_id",
+			"(users.deleted_at IS NULL)",
+		},
+	}
+	for _, c := range cases {
+		table, where, _, _, _ := c.query.build(ms)
+		if table != c.table || where != c.where {
+			t.Errorf("got\n  %q\n  %q\nwant\n  %q\n  %q", table, where, c.table, c.where)
+		}
+	}
+}
+
+func TestQueryGroupAndOrder(t *testing.T) {
+	ms := orm.Schema(&qUser{})
+	_, _, groupBy, orderBy, _ := Where().GroupBy("Status").Desc("id").Asc("name").build(ms)
+	if groupBy != "status" || orderBy != "id DESC,name ASC" {
+		t.Errorf("got %q %q", groupBy, orderBy)
+	}
+
+	_, _, groupBy, orderBy, _ = Where().LeftJoin(&qItem{}, "id", "order_id").GroupBy("items.title").Desc("id").build(ms)
+	if groupBy != "items.title" || orderBy != "users.id DESC" {
+		t.Errorf("joined got %q %q", groupBy, orderBy)
+	}
+}
+
+func TestQueryUnknownColumn(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("unknown column should panic")
+		}
+	}()
+	Where(Eq("nope", 1)).build(orm.Schema(&qUser{}))
+}
@@ -0,0 +1,276 @@
+// Copyright 2022 GoFast Author(http://chende.ren). All rights reserved.
+// Use of this source code is governed by a MIT license
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/qinchende/gofast/logx"
+	"github.com/qinchende/gofast/skill/gmp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	replicaCheckInterval = 3 * time.Second // 从库健康检查周期
+	replicaCheckTimeout  = 2 * time.Second // 单次检查的超时时间
+	replicaMaxFails      = 3               // 连续失败这么多次就把从库摘掉
+)
+
+var errReplicaStopped = errors.New("replication is not running")
+
+// 一个从库连接，Weight 是负载均衡的权重，小于等于0时按1算
+type Replica struct {
+	Name   string
+	DB     *sql.DB
+	Weight int
+}
+
+// 多个从库组成的读连接池，按权重平滑轮询（nginx 的算法）选择从库
+// 后台定时检查所有从库，连续 Ping 失败或者复制延迟超过 maxLag 的从库暂时摘掉，恢复之后再加回来
+// 所有从库都不可用时返回nil，由调用方改走主库
+type ReplicaPool struct {
+	driver string
+	maxLag time.Duration
+	lock   sync.Mutex
+	nodes  []*replicaNode
+	quit   chan struct{}
+	once   sync.Once
+}
+
+type replicaNode struct {
+	Replica
+	current int
+	fails   int // 只在健康检查协程中读写
+	online  bool
+}
+
+// maxLag 为0代表不检查复制延迟
+func NewReplicaPool(driverName string, replicas []Replica, maxLag time.Duration) *ReplicaPool {
+	rp := &ReplicaPool{
+		driver: driverName,
+		maxLag: maxLag,
+		nodes:  make([]*replicaNode, len(replicas)),
+		quit:   make(chan struct{}),
+	}
+	for i, rep := range replicas {
+		if rep.Weight <= 0 {
+			rep.Weight = 1
+		}
+		if rep.Name == "" {
+			rep.Name = "replica#" + strconv.Itoa(i)
+		}
+		rp.nodes[i] = &replicaNode{Replica: rep, online: true}
+	}
+	gmp.GoSafe(rp.healthLoop)
+	return rp
+}
+
+// 停止健康检查，不会关闭从库连接
+func (rp *ReplicaPool) Close() {
+	rp.once.Do(func() {
+		close(rp.quit)
+	})
+}
+
+// 当前可用的从库名称
+func (rp *ReplicaPool) Online() []string {
+	rp.lock.Lock()
+	defer rp.lock.Unlock()
+
+	names := make([]string, 0, len(rp.nodes))
+	for _, nd := range rp.nodes {
+		if nd.online {
+			names = append(names, nd.Name)
+		}
+	}
+	return names
+}
+
+func (rp *ReplicaPool) pick() *sql.DB {
+	rp.lock.Lock()
+	defer rp.lock.Unlock()
+
+	var best *replicaNode
+	total := 0
+	for _, nd := range rp.nodes {
+		if !nd.online {
+			continue
+		}
+		nd.current += nd.Weight
+		total += nd.Weight
+		if best == nil || nd.current > best.current {
+			best = nd
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	best.current -= total
+	return best.DB
+}
+
+func (rp *ReplicaPool) setOnline(nd *replicaNode, online bool) {
+	rp.lock.Lock()
+	nd.online = online
+	nd.current = 0
+	rp.lock.Unlock()
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+func (rp *ReplicaPool) healthLoop() {
+	ticker := time.NewTicker(replicaCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rp.quit:
+			return
+		case <-ticker.C:
+			rp.checkNodes()
+		}
+	}
+}
+
+func (rp *ReplicaPool) checkNodes() {
+	// 所有从库并行探测，一个从库卡住不会拖慢其它从库的检查
+	lags := make([]time.Duration, len(rp.nodes))
+	errs := make([]error, len(rp.nodes))
+	var wg sync.WaitGroup
+	for i := range rp.nodes {
+		idx := i
+		wg.Add(1)
+		gmp.GoSafe(func() {
+			defer wg.Done()
+			lags[idx], errs[idx] = rp.probe(rp.nodes[idx].DB)
+		})
+	}
+	wg.Wait()
+
+	for i, nd := range rp.nodes {
+		lag, err := lags[i], errs[i]
+		rp.lock.Lock()
+		online := nd.online
+		rp.lock.Unlock()
+
+		if err == nil && (rp.maxLag <= 0 || lag <= rp.maxLag) {
+			nd.fails = 0
+			if !online {
+				rp.setOnline(nd, true)
+				logx.InfoF("DB replica %s recovered, added back to the pool.", nd.Name)
+			}
+			continue
+		}
+
+		// 连不上要连续失败几次才摘掉，复制延迟太大马上摘掉，防止读到旧数据
+		if err == nil {
+			err = fmt.Errorf("replication lag %s exceeds %s", lag, rp.maxLag)
+			nd.fails = replicaMaxFails
+		} else {
+			nd.fails++
+		}
+		if online && nd.fails >= replicaMaxFails {
+			rp.setOnline(nd, false)
+			logx.ErrorF("DB replica %s removed from the pool: %s", nd.Name, err)
+		}
+	}
+}
+
+// Ping 从库，需要检查延迟时顺便查出复制延迟
+func (rp *ReplicaPool) probe(db *sql.DB) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), replicaCheckTimeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		return 0, err
+	}
+	if rp.maxLag <= 0 {
+		return 0, nil
+	}
+
+	switch rp.driver {
+	case DriverMysql:
+		return mysqlReplicaLag(ctx, db)
+	case DriverPostgres:
+		var secs float64
+		err := db.QueryRowContext(ctx, pgReplicaLagSql).Scan(&secs)
+		return time.Duration(secs * float64(time.Second)), err
+	}
+	return 0, nil
+}
+
+// 主库没有写入时 pg_last_xact_replay_timestamp 不会变，直接用 now() 相减会算出很大的延迟
+// 已经接收到的 WAL 全部回放完了，说明没有延迟
+const pgReplicaLagSql = `SELECT CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0) END;`
+
+// SHOW SLAVE STATUS 的列很多，只取 Seconds_Behind_Master，值为NULL代表复制已经停止
+// 查不到记录说明这个库不是从库，当作没有延迟
+func mysqlReplicaLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, "SHOW SLAVE STATUS;")
+	if err != nil {
+		return 0, err
+	}
+	defer CloseSqlRows(rows)
+
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+	cls, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	values := make([]sql.RawBytes, len(cls))
+	addrs := make([]any, len(cls))
+	for i := range values {
+		addrs[i] = &values[i]
+	}
+	if err = rows.Scan(addrs...); err != nil {
+		return 0, err
+	}
+	for i, c := range cls {
+		if c != "Seconds_Behind_Master" && c != "Seconds_Behind_Source" {
+			continue
+		}
+		if values[i] == nil {
+			return 0, errReplicaStopped
+		}
+		secs, err := strconv.ParseInt(string(values[i]), 10, 64)
+		return time.Duration(secs) * time.Second, err
+	}
+	return 0, nil
+}
+
+// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+// 读己之写：从库有复制延迟，刚写完马上去从库读可能读不到。用下面的 ctx 执行SQL可以避免这个问题
+type rywCtxKey struct{}
+
+type rywState struct {
+	written int32
+}
+
+// 返回带读己之写标记的 ctx，用它执行过写操作之后，后面的查询都走主库
+// 一般在一次请求开始的时候调用，比如：conn.CloneWithCtx(sqlx.WithReadYourWrites(ctx))
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rywCtxKey{}, &rywState{})
+}
+
+// 返回所有查询都走主库的 ctx
+func WithWriter(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rywCtxKey{}, &rywState{written: 1})
+}
+
+func markWritten(ctx context.Context) {
+	if st, ok := ctx.Value(rywCtxKey{}).(*rywState); ok && atomic.LoadInt32(&st.written) == 0 {
+		atomic.StoreInt32(&st.written, 1)
+	}
+}
+
+func mustWriter(ctx context.Context) bool {
+	st, ok := ctx.Value(rywCtxKey{}).(*rywState)
+	return ok && atomic.LoadInt32(&st.written) == 1
+}
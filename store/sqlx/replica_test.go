@@ -0,0 +1,186 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 每个从库一个假数据库，测试中手动调用 checkNodes
+func newTestReplicas(t *testing.T, driverName string, maxLag time.Duration, weights ...int) (*ReplicaPool, []*mockDB) {
+	mocks := make([]*mockDB, len(weights))
+	reps := make([]Replica, len(weights))
+	for i, w := range weights {
+		mocks[i], _ = newMockDB(t, driverName)
+		reps[i] = Replica{DB: mocks[i].db, Weight: w}
+	}
+	rp := NewReplicaPool(driverName, reps, maxLag)
+	rp.Close()
+	return rp, mocks
+}
+
+func setPingErr(m *mockDB, err error) {
+	m.mu.Lock()
+	m.pingErr = err
+	m.mu.Unlock()
+}
+
+func pickName(t *testing.T, rp *ReplicaPool) string {
+	db := rp.pick()
+	if db == nil {
+		return ""
+	}
+	for _, nd := range rp.nodes {
+		if nd.DB == db {
+			return nd.Name
+		}
+	}
+	t.Fatal("picked an unknown replica")
+	return ""
+}
+
+func TestReplicaPickWeighted(t *testing.T) {
+	rp, _ := newTestReplicas(t, DriverMysql, 0, 5, 1, 0)
+	if rp.nodes[2].Weight != 1 || rp.nodes[2].Name != "replica#2" {
+		t.Fatalf("default weight/name got %+v", rp.nodes[2].Replica)
+	}
+
+	// 平滑加权轮询：权重大的不会连续被选中太多次
+	want := []string{"replica#0", "replica#0", "replica#1", "replica#0", "replica#2", "replica#0", "replica#0"}
+	for round := 0; round < 3; round++ {
+		for i, name := range want {
+			if got := pickName(t, rp); got != name {
+				t.Fatalf("round %d pick %d got %s, want %s", round, i, got, name)
+			}
+		}
+	}
+
+	// 摘掉的从库不参与选择，全部摘掉时返回nil
+	rp.setOnline(rp.nodes[0], false)
+	for i := 0; i < 4; i++ {
+		if got := pickName(t, rp); got == "replica#0" {
+			t.Fatal("offline replica picked")
+		}
+	}
+	rp.setOnline(rp.nodes[1], false)
+	rp.setOnline(rp.nodes[2], false)
+	if db := rp.pick(); db != nil {
+		t.Fatal("all offline should pick nil")
+	}
+	if len(rp.Online()) != 0 {
+		t.Fatalf("online got %v", rp.Online())
+	}
+}
+
+func TestReplicaPingFails(t *testing.T) {
+	rp, mocks := newTestReplicas(t, DriverMysql, 0, 1, 1)
+	setPingErr(mocks[1], errors.New("connection refused"))
+
+	// 连续失败 replicaMaxFails 次才摘掉
+	for i := 0; i < replicaMaxFails; i++ {
+		if !rp.nodes[1].online {
+			t.Fatalf("replica removed after %d fails", i)
+		}
+		rp.checkNodes()
+	}
+	if names := rp.Online(); len(names) != 1 || names[0] != "replica#0" {
+		t.Fatalf("online got %v", names)
+	}
+	for i := 0; i < 4; i++ {
+		if db := rp.pick(); db != mocks[0].db {
+			t.Fatal("removed replica picked")
+		}
+	}
+
+	// 中间成功一次，失败次数重新计算
+	setPingErr(mocks[1], nil)
+	rp.checkNodes()
+	if !rp.nodes[1].online {
+		t.Fatal("replica not recovered")
+	}
+	setPingErr(mocks[1], errors.New("connection refused"))
+	rp.checkNodes()
+	setPingErr(mocks[1], nil)
+	rp.checkNodes()
+	setPingErr(mocks[1], errors.New("connection refused"))
+	for i := 0; i < replicaMaxFails-1; i++ {
+		rp.checkNodes()
+	}
+	if !rp.nodes[1].online {
+		t.Fatal("fails should reset after a successful check")
+	}
+}
+
+func TestReplicaLag(t *testing.T) {
+	rp, mocks := newTestReplicas(t, DriverMysql, 5*time.Second, 1)
+	slaveCls := []string{"Slave_IO_State", "Seconds_Behind_Master"}
+	expectLag := func(lag driver.Value) {
+		mocks[0].expectQuery(`^SHOW SLAVE STATUS`).withRows(slaveCls, []driver.Value{"Waiting for master", lag})
+	}
+
+	expectLag("1")
+	rp.checkNodes()
+	if !rp.nodes[0].online {
+		t.Fatal("replica within max lag removed")
+	}
+
+	// 延迟太大马上摘掉
+	expectLag("10")
+	rp.checkNodes()
+	if rp.nodes[0].online {
+		t.Fatal("lagging replica not removed")
+	}
+
+	conn := &OrmDB{Attrs: &DBAttrs{DriverName: DriverMysql}, Writer: &sql.DB{}, replicas: rp}
+	if conn.readerDB(context.Background()) != conn.Writer {
+		t.Fatal("no replica online should read from writer")
+	}
+
+	// 追上之后加回来
+	expectLag("0")
+	rp.checkNodes()
+	if !rp.nodes[0].online || conn.readerDB(context.Background()) != mocks[0].db {
+		t.Fatal("caught up replica not recovered")
+	}
+
+	// 复制停止按连接失败计算，连续几次才摘掉；不是从库的当作没有延迟
+	for i := 0; i < replicaMaxFails; i++ {
+		if !rp.nodes[0].online {
+			t.Fatalf("stopped replica removed after %d checks", i)
+		}
+		expectLag(nil)
+		rp.checkNodes()
+	}
+	if rp.nodes[0].online {
+		t.Fatal("stopped replica not removed")
+	}
+	mocks[0].expectQuery(`^SHOW SLAVE STATUS`).withRows(slaveCls)
+	rp.checkNodes()
+	if !rp.nodes[0].online {
+		t.Fatal("non replica should count as no lag")
+	}
+}
+
+// 预编译语句执行写操作之后，读己之写的 ctx 也要改走主库
+func TestStmtExecMarksWritten(t *testing.T) {
+	m, conn := newMockDB(t, DriverMysql)
+	reader, _ := newMockDB(t, DriverMysql)
+	conn.Reader = reader.db
+
+	ctx := WithReadYourWrites(context.Background())
+	if conn.readerDB(ctx) != reader.db {
+		t.Fatal("read before write should use reader")
+	}
+	m.expectExec(`^UPDATE accounts SET age=\? WHERE id=\?`, 3, 1).withResult(0, 1)
+	stmt := conn.PrepareCtx(ctx, "UPDATE accounts SET age=? WHERE id=?", false)
+	defer stmt.Close()
+	if ct := stmt.ExecCtx(ctx, 3, 1); ct != 1 {
+		t.Fatalf("affected got %d", ct)
+	}
+	if conn.readerDB(ctx) != conn.Writer {
+		t.Fatal("read after stmt exec should use writer")
+	}
+}